@@ -96,32 +96,117 @@ func ActionSwapMaster(wm *WindowManager) {
 	wm.tile()
 }
 
-// ActionShrink shrinks the master area
-func ActionShrink(wm *WindowManager) {
+// sendLayoutMessage passes a message to the layout of the current
+// workspace and retiles
+func (wm *WindowManager) sendLayoutMessage(msg LayoutMessage) {
 	ws := wm.currentWorkspace()
-	ws.Layout.HandleMessage(LayoutMsgShrink)
+	ws.focusLayout()
+	ws.Layout.HandleMessage(msg)
 	wm.tile()
 }
 
+// ActionShrink shrinks the master area
+func ActionShrink(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgShrink)
+}
+
 // ActionExpand expands the master area
 func ActionExpand(wm *WindowManager) {
-	ws := wm.currentWorkspace()
-	ws.Layout.HandleMessage(LayoutMsgExpand)
-	wm.tile()
+	wm.sendLayoutMessage(LayoutMsgExpand)
+}
+
+// ActionShrinkWindow makes the focused window shorter than the others in
+// its column
+func ActionShrinkWindow(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgMirrorShrink)
+}
+
+// ActionExpandWindow makes the focused window taller than the others in
+// its column
+func ActionExpandWindow(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgMirrorExpand)
+}
+
+// ActionResetWeights gives every window in the layout the same size again
+func ActionResetWeights(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgResetWeights)
+}
+
+// ActionMagnifyMore enlarges the focused window further in a magnify layout
+func ActionMagnifyMore(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgMagnifyMore)
+}
+
+// ActionMagnifyLess enlarges the focused window less in a magnify layout
+func ActionMagnifyLess(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgMagnifyLess)
 }
 
 // ActionIncMaster increases the number of master windows
 func ActionIncMaster(wm *WindowManager) {
-	ws := wm.currentWorkspace()
-	ws.Layout.HandleMessage(LayoutMsgIncMaster)
-	wm.tile()
+	wm.sendLayoutMessage(LayoutMsgIncMaster)
 }
 
 // ActionDecMaster decreases the number of master windows
 func ActionDecMaster(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgDecMaster)
+}
+
+// ActionRotate rotates the split holding the focused window 90° clockwise
+func ActionRotate(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgRotate)
+}
+
+// ActionFlipHorizontal mirrors the split holding the focused window left
+// to right
+func ActionFlipHorizontal(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgFlipHorizontal)
+}
+
+// ActionFlipVertical mirrors the split holding the focused window top to
+// bottom
+func ActionFlipVertical(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgFlipVertical)
+}
+
+// currentBSP returns the BSP layout of the current workspace, or nil if
+// it uses another layout
+func (wm *WindowManager) currentBSP() *BSPLayout {
 	ws := wm.currentWorkspace()
-	ws.Layout.HandleMessage(LayoutMsgDecMaster)
-	wm.tile()
+	l, ok := baseLayout(ws.Layout).(*BSPLayout)
+	if !ok {
+		log.Printf("Preselection needs the bsp layout, not %s", ws.Layout.Name())
+		return nil
+	}
+	ws.focusLayout()
+	return l
+}
+
+// ActionPreselect returns an action that makes the next window split the
+// focused one on the given side
+func ActionPreselect(dir Direction) Action {
+	return func(wm *WindowManager) {
+		if l := wm.currentBSP(); l != nil {
+			l.Preselect(dir, 1-l.Ratio)
+		}
+	}
+}
+
+// ActionPreselectRatio returns an action that sets the share of the space
+// the preselected window takes, in percent
+func ActionPreselectRatio(percent int) Action {
+	return func(wm *WindowManager) {
+		if l := wm.currentBSP(); l != nil && l.Preselected() != DirNone {
+			l.Preselect(l.Preselected(), float64(percent)/100)
+		}
+	}
+}
+
+// ActionPreselectCancel drops the pending preselection
+func ActionPreselectCancel(wm *WindowManager) {
+	if l := wm.currentBSP(); l != nil {
+		l.Preselect(DirNone, 0)
+	}
 }
 
 // ActionNextLayout cycles to the next layout
@@ -135,11 +220,47 @@ func ActionNextLayout(wm *WindowManager) {
 	wm.mapAllTiledWindows()
 	ws.NextLayout(wm.layouts)
 	wm.tile()
+	wm.emitLayout(ws)
 	log.Printf("Layout: %s", ws.Layout.Name())
 	// Notify via dunst
 	spawn("notify-send -t 1000 'Layout' '%s'", ws.Layout.Name())
 }
 
+// ActionSetLayout returns an action that switches to a layout by name, such
+// as "grid" or "split(full,grid)"
+func ActionSetLayout(name string) Action {
+	return func(wm *WindowManager) {
+		ws := wm.currentWorkspace()
+		if newLayout(name) == nil {
+			log.Printf("Unknown layout %q", name)
+			return
+		}
+		// Re-map all windows (in case coming from monocle layout)
+		wm.mapAllTiledWindows()
+		ws.SelectLayout(name)
+		wm.tile()
+		wm.emitLayout(ws)
+		log.Printf("Layout: %s", ws.Layout.Name())
+		spawn("notify-send -t 1000 'Layout' '%s'", ws.Layout.Name())
+	}
+}
+
+// ActionSplitShrink narrows the first half of a split layout
+func ActionSplitShrink(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgSplitShrink)
+}
+
+// ActionSplitExpand widens the first half of a split layout
+func ActionSplitExpand(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgSplitExpand)
+}
+
+// ActionSplitMove moves the focused window to the other half of a split
+// layout
+func ActionSplitMove(wm *WindowManager) {
+	wm.sendLayoutMessage(LayoutMsgSplitMove)
+}
+
 // ActionResetLayout resets to the default layout
 func ActionResetLayout(wm *WindowManager) {
 	ws := wm.currentWorkspace()
@@ -149,13 +270,31 @@ func ActionResetLayout(wm *WindowManager) {
 	}
 	// Re-map all windows (in case coming from monocle layout)
 	wm.mapAllTiledWindows()
-	ws.Layout = NewTallLayout()
+	ws.ResetLayout()
 	wm.tile()
+	wm.emitLayout(ws)
 	log.Printf("Layout reset: %s", ws.Layout.Name())
 	// Notify via dunst
 	spawn("notify-send -t 1000 'Layout' '%s'", ws.Layout.Name())
 }
 
+// ActionToggleModifier returns an action that puts the named modifier,
+// such as "mirror", on the current layout or takes it off again
+func ActionToggleModifier(mod string) Action {
+	return func(wm *WindowManager) {
+		ws := wm.currentWorkspace()
+		if !ws.ToggleModifier(mod) {
+			log.Printf("Cannot %s %s", mod, ws.Layout.Name())
+			return
+		}
+		wm.tile()
+		wm.emitLayout(ws)
+		log.Printf("Layout: %s", ws.Layout.Name())
+		// Notify via dunst
+		spawn("notify-send -t 1000 'Layout' '%s'", ws.Layout.Name())
+	}
+}
+
 // ActionSink sinks a floating window back to tiled
 func ActionSink(wm *WindowManager) {
 	if wm.focused != nil && wm.focused.Floating {
@@ -164,10 +303,22 @@ func ActionSink(wm *WindowManager) {
 	}
 }
 
+// ActionFloat makes the focused window floating
+func ActionFloat(wm *WindowManager) {
+	if wm.focused != nil && !wm.focused.Floating {
+		wm.focused.Floating = true
+		wm.restoreBorder(wm.focused)
+		wm.tile()
+	}
+}
+
 // ActionToggleFloat toggles the focused window between floating and tiled
 func ActionToggleFloat(wm *WindowManager) {
 	if wm.focused != nil {
 		wm.focused.Floating = !wm.focused.Floating
+		if wm.focused.Floating {
+			wm.restoreBorder(wm.focused)
+		}
 		wm.tile()
 	}
 }
@@ -198,6 +349,54 @@ func ActionToggleStruts(wm *WindowManager) {
 	spawn("notify-send -t 1000 'Struts' '%s'", status)
 }
 
+// ActionAdjustSpacing returns an action that changes a gap or the border
+// width, on the current workspace only or everywhere
+func ActionAdjustSpacing(s Spacing, workspace bool, adj Adjustment) Action {
+	return func(wm *WindowManager) {
+		var ws *Workspace
+		where := ""
+		if workspace {
+			ws = wm.currentWorkspace()
+			where = " on workspace " + ws.Name
+		}
+		v := wm.adjustSpacing(ws, s, adj)
+		title := "Gaps"
+		if s == SpacingBorder {
+			title = "Border"
+		}
+		log.Printf("%s%s: %d", s, where, v)
+		spawn("notify-send -t 1000 '%s' '%s%s: %d'", title, s, where, v)
+	}
+}
+
+// ActionToggleGaps returns an action that turns both gaps off, or back on
+// if they are off, on the current workspace only or everywhere
+func ActionToggleGaps(workspace bool) Action {
+	return func(wm *WindowManager) {
+		var ws *Workspace
+		if workspace {
+			ws = wm.currentWorkspace()
+		}
+		on := wm.spacing(ws, SpacingOuterGap) != 0 || wm.spacing(ws, SpacingInnerGap) != 0
+		for _, s := range []Spacing{SpacingOuterGap, SpacingInnerGap} {
+			// A gap that is already off stays off when toggled back on
+			if on && wm.spacing(ws, s) == 0 {
+				wm.toggledSpacing[newSpacingKey(ws, s)] = 0
+				continue
+			}
+			wm.setSpacing(ws, s, Adjustment{Toggle: true})
+		}
+		wm.tile()
+
+		status := "on"
+		if on {
+			status = "off"
+		}
+		log.Printf("Gaps: %s", status)
+		spawn("notify-send -t 1000 'Gaps' '%s'", status)
+	}
+}
+
 // ActionSwitchWorkspace returns an action that switches to workspace n
 func ActionSwitchWorkspace(n int) Action {
 	return func(wm *WindowManager) {
@@ -214,6 +413,45 @@ func ActionMoveToWorkspace(n int) Action {
 	}
 }
 
+// ActionNextWorkspace switches to the next workspace, wrapping around
+func ActionNextWorkspace(wm *WindowManager) {
+	wm.switchToWorkspace((wm.current + 1) % len(wm.workspaces))
+}
+
+// ActionPrevWorkspace switches to the previous workspace, wrapping around
+func ActionPrevWorkspace(wm *WindowManager) {
+	wm.switchToWorkspace((wm.current + len(wm.workspaces) - 1) % len(wm.workspaces))
+}
+
+// ActionFocusNextMonitor focuses the workspace on the next monitor
+func ActionFocusNextMonitor(wm *WindowManager) {
+	wm.focusMonitor(wm.relativeMonitor(1))
+}
+
+// ActionFocusPrevMonitor focuses the workspace on the previous monitor
+func ActionFocusPrevMonitor(wm *WindowManager) {
+	wm.focusMonitor(wm.relativeMonitor(-1))
+}
+
+// ActionMoveToNextMonitor moves the focused window to the next monitor
+func ActionMoveToNextMonitor(wm *WindowManager) {
+	if wm.focused != nil {
+		wm.moveToMonitor(wm.focused, wm.relativeMonitor(1))
+	}
+}
+
+// ActionMoveToPrevMonitor moves the focused window to the previous monitor
+func ActionMoveToPrevMonitor(wm *WindowManager) {
+	if wm.focused != nil {
+		wm.moveToMonitor(wm.focused, wm.relativeMonitor(-1))
+	}
+}
+
+// ActionSwapMonitors swaps the workspaces of the current and next monitor
+func ActionSwapMonitors(wm *WindowManager) {
+	wm.swapMonitors(wm.currentMonitor(), wm.relativeMonitor(1))
+}
+
 // ActionRestart restarts the window manager
 func ActionRestart(wm *WindowManager) {
 	log.Println("Restarting...")
@@ -230,6 +468,13 @@ func ActionRestart(wm *WindowManager) {
 	}
 }
 
+// ActionReloadConfig reloads the config file without restarting
+func ActionReloadConfig(wm *WindowManager) {
+	if err := wm.reloadConfig(); err == nil {
+		spawn("notify-send -t 1000 'gowm' 'Config reloaded'")
+	}
+}
+
 // ActionQuit exits the window manager
 func ActionQuit(wm *WindowManager) {
 	log.Println("Quitting...")
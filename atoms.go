@@ -120,11 +120,11 @@ func (wm *WindowManager) initAtoms() {
 	}
 
 	for name, atom := range atomNames {
-		reply, err := xproto.InternAtom(wm.conn, false, uint16(len(name)), name).Reply()
+		value, err := wm.x.InternAtom(name)
 		if err != nil {
 			log.Printf("Failed to intern atom %s: %v", name, err)
 			continue
 		}
-		*atom = reply.Atom
+		*atom = value
 	}
 }
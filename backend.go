@@ -0,0 +1,477 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/randr"
+	"github.com/jezek/xgb/xproto"
+)
+
+// Backend is the set of X server operations the window manager core uses.
+// The real implementation talks to an xgb connection; tests use an
+// in-memory fake so the core model can run without an X server.
+type Backend interface {
+	// Window attributes and geometry
+	ChangeWindowAttributes(win xproto.Window, mask uint32, values []uint32)
+	ChangeWindowAttributesChecked(win xproto.Window, mask uint32, values []uint32) error
+	ConfigureWindow(win xproto.Window, mask uint16, values []uint32)
+	GetGeometry(win xproto.Window) (*xproto.GetGeometryReply, error)
+	GetWindowAttributes(win xproto.Window) (*xproto.GetWindowAttributesReply, error)
+	QueryTree(win xproto.Window) ([]xproto.Window, error)
+	CreateWindow(parent xproto.Window, r Rect, borderWidth uint16, class uint16,
+		mask uint32, values []uint32) (xproto.Window, error)
+
+	// Mapping and focus
+	MapWindow(win xproto.Window)
+	UnmapWindow(win xproto.Window)
+	SetInputFocus(win xproto.Window)
+
+	// Properties
+	InternAtom(name string) (xproto.Atom, error)
+	ChangeProperty(win xproto.Window, prop, typ xproto.Atom, format byte, data []byte)
+	DeleteProperty(win xproto.Window, prop xproto.Atom)
+	GetProperty(win xproto.Window, prop, typ xproto.Atom, offset, length uint32) (*xproto.GetPropertyReply, error)
+
+	// Client communication
+	SendEvent(win xproto.Window, mask uint32, event []byte)
+	KillClient(win xproto.Window)
+
+	// Input
+	GetKeyboardMapping(first xproto.Keycode, count byte) (*xproto.GetKeyboardMappingReply, error)
+	GrabKey(win xproto.Window, mod uint16, key xproto.Keycode)
+	UngrabAllKeys(win xproto.Window)
+	GrabKeyboard(win xproto.Window) error
+	UngrabKeyboard()
+	GrabButton(win xproto.Window, mod uint16, button byte)
+	UngrabAllButtons(win xproto.Window)
+
+	// Outputs
+	Monitors() ([]*Monitor, error)
+	SelectMonitorEvents() error
+	Outputs() ([]*Output, error)
+	ApplyOutputs(configs []OutputConfig) error
+}
+
+// xBackend implements Backend on top of an xgb connection
+type xBackend struct {
+	conn *xgb.Conn
+	root xproto.Window
+
+	randrErr error // Why RandR is unavailable, if it is
+}
+
+// NewXBackend creates a backend for the given connection and root window
+func NewXBackend(conn *xgb.Conn, root xproto.Window) Backend {
+	x := &xBackend{conn: conn, root: root}
+	if err := randr.Init(conn); err != nil {
+		x.randrErr = err
+	} else if _, err := randr.QueryVersion(conn, 1, 5).Reply(); err != nil {
+		x.randrErr = err
+	}
+	return x
+}
+
+func (x *xBackend) ChangeWindowAttributes(win xproto.Window, mask uint32, values []uint32) {
+	xproto.ChangeWindowAttributes(x.conn, win, mask, values)
+}
+
+func (x *xBackend) ChangeWindowAttributesChecked(win xproto.Window, mask uint32, values []uint32) error {
+	return xproto.ChangeWindowAttributesChecked(x.conn, win, mask, values).Check()
+}
+
+func (x *xBackend) ConfigureWindow(win xproto.Window, mask uint16, values []uint32) {
+	xproto.ConfigureWindow(x.conn, win, mask, values)
+}
+
+func (x *xBackend) GetGeometry(win xproto.Window) (*xproto.GetGeometryReply, error) {
+	return xproto.GetGeometry(x.conn, xproto.Drawable(win)).Reply()
+}
+
+func (x *xBackend) GetWindowAttributes(win xproto.Window) (*xproto.GetWindowAttributesReply, error) {
+	return xproto.GetWindowAttributes(x.conn, win).Reply()
+}
+
+func (x *xBackend) QueryTree(win xproto.Window) ([]xproto.Window, error) {
+	tree, err := xproto.QueryTree(x.conn, win).Reply()
+	if err != nil {
+		return nil, err
+	}
+	return tree.Children, nil
+}
+
+func (x *xBackend) CreateWindow(parent xproto.Window, r Rect, borderWidth uint16, class uint16,
+	mask uint32, values []uint32) (xproto.Window, error) {
+	win, err := xproto.NewWindowId(x.conn)
+	if err != nil {
+		return 0, err
+	}
+
+	// Depth and visual are copied from the parent
+	err = xproto.CreateWindowChecked(x.conn, 0, win, parent,
+		r.X, r.Y, r.Width, r.Height, borderWidth,
+		class, 0, mask, values).Check()
+	if err != nil {
+		return 0, err
+	}
+	return win, nil
+}
+
+func (x *xBackend) MapWindow(win xproto.Window) {
+	xproto.MapWindow(x.conn, win)
+}
+
+func (x *xBackend) UnmapWindow(win xproto.Window) {
+	xproto.UnmapWindow(x.conn, win)
+}
+
+func (x *xBackend) SetInputFocus(win xproto.Window) {
+	// RevertToParent is safer than PointerRoot if the window goes away
+	xproto.SetInputFocus(x.conn, xproto.InputFocusParent, win, xproto.TimeCurrentTime)
+}
+
+func (x *xBackend) InternAtom(name string) (xproto.Atom, error) {
+	reply, err := xproto.InternAtom(x.conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, err
+	}
+	return reply.Atom, nil
+}
+
+func (x *xBackend) ChangeProperty(win xproto.Window, prop, typ xproto.Atom, format byte, data []byte) {
+	xproto.ChangeProperty(x.conn, xproto.PropModeReplace, win, prop, typ, format,
+		uint32(len(data)*8/int(format)), data)
+}
+
+func (x *xBackend) DeleteProperty(win xproto.Window, prop xproto.Atom) {
+	xproto.DeleteProperty(x.conn, win, prop)
+}
+
+func (x *xBackend) GetProperty(win xproto.Window, prop, typ xproto.Atom, offset, length uint32) (*xproto.GetPropertyReply, error) {
+	return xproto.GetProperty(x.conn, false, win, prop, typ, offset, length).Reply()
+}
+
+func (x *xBackend) SendEvent(win xproto.Window, mask uint32, event []byte) {
+	xproto.SendEvent(x.conn, false, win, mask, string(event))
+}
+
+func (x *xBackend) KillClient(win xproto.Window) {
+	xproto.KillClient(x.conn, uint32(win))
+}
+
+func (x *xBackend) GetKeyboardMapping(first xproto.Keycode, count byte) (*xproto.GetKeyboardMappingReply, error) {
+	return xproto.GetKeyboardMapping(x.conn, first, count).Reply()
+}
+
+func (x *xBackend) GrabKey(win xproto.Window, mod uint16, key xproto.Keycode) {
+	xproto.GrabKey(x.conn, true, win, mod, key, xproto.GrabModeAsync, xproto.GrabModeAsync)
+}
+
+func (x *xBackend) UngrabAllKeys(win xproto.Window) {
+	xproto.UngrabKey(x.conn, xproto.GrabAny, win, xproto.ModMaskAny)
+}
+
+func (x *xBackend) GrabKeyboard(win xproto.Window) error {
+	reply, err := xproto.GrabKeyboard(x.conn, false, win, xproto.TimeCurrentTime,
+		xproto.GrabModeAsync, xproto.GrabModeAsync).Reply()
+	if err != nil {
+		return err
+	}
+	if reply.Status != xproto.GrabStatusSuccess {
+		return fmt.Errorf("keyboard grab failed (status %d)", reply.Status)
+	}
+	return nil
+}
+
+func (x *xBackend) UngrabKeyboard() {
+	xproto.UngrabKeyboard(x.conn, xproto.TimeCurrentTime)
+}
+
+func (x *xBackend) GrabButton(win xproto.Window, mod uint16, button byte) {
+	xproto.GrabButton(x.conn, true, win,
+		xproto.EventMaskButtonPress|xproto.EventMaskButtonRelease|xproto.EventMaskPointerMotion,
+		xproto.GrabModeAsync, xproto.GrabModeAsync,
+		x.root, 0, button, mod)
+}
+
+func (x *xBackend) UngrabAllButtons(win xproto.Window) {
+	xproto.UngrabButton(x.conn, xproto.ButtonIndexAny, win, xproto.ModMaskAny)
+}
+
+// Monitors lists the active RandR monitors (RandR 1.5), which already
+// merge mirrored outputs and include monitors set up with xrandr
+// --setmonitor
+func (x *xBackend) Monitors() ([]*Monitor, error) {
+	if x.randrErr != nil {
+		return nil, fmt.Errorf("RandR unavailable: %v", x.randrErr)
+	}
+
+	reply, err := randr.GetMonitors(x.conn, x.root, true).Reply()
+	if err != nil {
+		return nil, err
+	}
+
+	var monitors []*Monitor
+	for i, m := range reply.Monitors {
+		name := fmt.Sprintf("monitor-%d", i)
+		if atom, err := xproto.GetAtomName(x.conn, m.Name).Reply(); err == nil {
+			name = atom.Name
+		}
+		monitors = append(monitors, &Monitor{
+			Name:     name,
+			Primary:  m.Primary,
+			Geometry: Rect{X: m.X, Y: m.Y, Width: m.Width, Height: m.Height},
+		})
+	}
+	return monitors, nil
+}
+
+// SelectMonitorEvents asks for RandR notifications when outputs are
+// connected, disconnected or reconfigured
+func (x *xBackend) SelectMonitorEvents() error {
+	if x.randrErr != nil {
+		return fmt.Errorf("RandR unavailable: %v", x.randrErr)
+	}
+	return randr.SelectInputChecked(x.conn, x.root,
+		randr.NotifyMaskScreenChange|randr.NotifyMaskCrtcChange|
+			randr.NotifyMaskOutputChange).Check()
+}
+
+// randrState is a snapshot of the RandR configuration
+type randrState struct {
+	res     *randr.GetScreenResourcesCurrentReply
+	outputs map[randr.Output]*randr.GetOutputInfoReply
+	crtcs   map[randr.Crtc]*randr.GetCrtcInfoReply
+	modes   map[randr.Mode]randr.ModeInfo
+}
+
+// randrState reads the current outputs, CRTCs and modes without probing
+// the hardware
+func (x *xBackend) randrState() (*randrState, error) {
+	if x.randrErr != nil {
+		return nil, fmt.Errorf("RandR unavailable: %v", x.randrErr)
+	}
+	res, err := randr.GetScreenResourcesCurrent(x.conn, x.root).Reply()
+	if err != nil {
+		return nil, err
+	}
+
+	st := &randrState{
+		res:     res,
+		outputs: make(map[randr.Output]*randr.GetOutputInfoReply),
+		crtcs:   make(map[randr.Crtc]*randr.GetCrtcInfoReply),
+		modes:   make(map[randr.Mode]randr.ModeInfo),
+	}
+	for _, m := range res.Modes {
+		st.modes[randr.Mode(m.Id)] = m
+	}
+	for _, out := range res.Outputs {
+		info, err := randr.GetOutputInfo(x.conn, out, res.ConfigTimestamp).Reply()
+		if err != nil {
+			return nil, err
+		}
+		st.outputs[out] = info
+	}
+	for _, crtc := range res.Crtcs {
+		info, err := randr.GetCrtcInfo(x.conn, crtc, res.ConfigTimestamp).Reply()
+		if err != nil {
+			return nil, err
+		}
+		st.crtcs[crtc] = info
+	}
+	return st, nil
+}
+
+// modeRate returns the refresh rate of a mode in Hz
+func modeRate(m randr.ModeInfo) float64 {
+	vtotal := float64(m.Vtotal)
+	if m.ModeFlags&randr.ModeFlagDoubleScan != 0 {
+		vtotal *= 2
+	}
+	if m.ModeFlags&randr.ModeFlagInterlace != 0 {
+		vtotal /= 2
+	}
+	if m.Htotal == 0 || vtotal == 0 {
+		return 0
+	}
+	return float64(m.DotClock) / (float64(m.Htotal) * vtotal)
+}
+
+// Outputs lists the RandR outputs with their modes and EDID
+func (x *xBackend) Outputs() ([]*Output, error) {
+	st, err := x.randrState()
+	if err != nil {
+		return nil, err
+	}
+	edidAtom, _ := x.InternAtom("EDID")
+
+	var outputs []*Output
+	for _, out := range st.res.Outputs {
+		info := st.outputs[out]
+		o := &Output{
+			Name:      string(info.Name),
+			Connected: info.Connection == randr.ConnectionConnected,
+		}
+		// RandR lists the preferred modes first
+		for _, id := range info.Modes {
+			if m, ok := st.modes[id]; ok {
+				o.Modes = append(o.Modes, OutputMode{Width: m.Width, Height: m.Height, Rate: modeRate(m)})
+			}
+		}
+		if o.Connected && edidAtom != 0 {
+			prop, err := randr.GetOutputProperty(x.conn, out, edidAtom,
+				xproto.GetPropertyTypeAny, 0, 128, false, false).Reply()
+			if err == nil {
+				o.EDID = describeEDID(prop.Data)
+			}
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs, nil
+}
+
+// crtcPlan is the CRTC an enabled output will be driven by
+type crtcPlan struct {
+	cfg  OutputConfig
+	out  randr.Output
+	crtc randr.Crtc
+	mode randr.Mode
+	keep bool // The CRTC already shows this output this way
+}
+
+// ApplyOutputs reconfigures the outputs the way xrandr does: CRTCs that
+// change are turned off, the screen is resized to fit the new layout, and
+// the CRTCs are turned back on. Outputs already set up as asked are left
+// alone so they don't flicker.
+func (x *xBackend) ApplyOutputs(configs []OutputConfig) error {
+	st, err := x.randrState()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]randr.Output)
+	for out, info := range st.outputs {
+		byName[string(info.Name)] = out
+	}
+
+	// Pick the mode of each enabled output, keeping its current CRTC
+	var plans []*crtcPlan
+	used := make(map[randr.Crtc]bool)
+	var width, height int
+	for _, cfg := range configs {
+		if !cfg.Enabled {
+			continue
+		}
+		out, ok := byName[cfg.Name]
+		if !ok {
+			return fmt.Errorf("no output named %s", cfg.Name)
+		}
+		info := st.outputs[out]
+		p := &crtcPlan{cfg: cfg, out: out}
+		best := math.Inf(1)
+		for _, id := range info.Modes {
+			m := st.modes[id]
+			if m.Width == cfg.Mode.Width && m.Height == cfg.Mode.Height &&
+				math.Abs(modeRate(m)-cfg.Mode.Rate) < best {
+				p.mode, best = id, math.Abs(modeRate(m)-cfg.Mode.Rate)
+			}
+		}
+		if p.mode == 0 {
+			return fmt.Errorf("%s does not support %s", cfg.Name, cfg.Mode)
+		}
+		if info.Crtc != 0 && !used[info.Crtc] {
+			p.crtc = info.Crtc
+			used[p.crtc] = true
+		}
+		plans = append(plans, p)
+
+		w, h := cfg.size()
+		width = max(width, int(cfg.X)+int(w))
+		height = max(height, int(cfg.Y)+int(h))
+	}
+	if len(plans) == 0 {
+		return fmt.Errorf("refusing to turn every output off")
+	}
+
+	// Give the others a free CRTC they can use
+	for _, p := range plans {
+		if p.crtc != 0 {
+			continue
+		}
+		for _, crtc := range st.outputs[p.out].Crtcs {
+			if !used[crtc] {
+				p.crtc = crtc
+				used[crtc] = true
+				break
+			}
+		}
+		if p.crtc == 0 {
+			return fmt.Errorf("no free CRTC for %s", p.cfg.Name)
+		}
+	}
+
+	xproto.GrabServer(x.conn)
+	defer xproto.UngrabServer(x.conn)
+
+	// Turn off CRTCs that change or would not fit on the new screen
+	target := make(map[randr.Crtc]*crtcPlan)
+	for _, p := range plans {
+		target[p.crtc] = p
+	}
+	for _, crtc := range st.res.Crtcs {
+		info := st.crtcs[crtc]
+		if p := target[crtc]; p != nil {
+			p.keep = info.Mode == p.mode && info.X == p.cfg.X && info.Y == p.cfg.Y &&
+				info.Rotation == p.cfg.Rotation && len(info.Outputs) == 1 && info.Outputs[0] == p.out &&
+				int(info.X)+int(info.Width) <= width && int(info.Y)+int(info.Height) <= height
+			if p.keep {
+				continue
+			}
+		}
+		if info.Mode == 0 {
+			continue
+		}
+		_, err := randr.SetCrtcConfig(x.conn, crtc, xproto.TimeCurrentTime, st.res.ConfigTimestamp,
+			0, 0, 0, randr.RotationRotate0, nil).Reply()
+		if err != nil {
+			return fmt.Errorf("turning off CRTC %d: %v", crtc, err)
+		}
+	}
+
+	// Resize the screen, keeping its DPI
+	root, err := x.GetGeometry(x.root)
+	if err != nil {
+		return err
+	}
+	if int(root.Width) != width || int(root.Height) != height {
+		screen := xproto.Setup(x.conn).DefaultScreen(x.conn)
+		mmWidth := uint32(width * int(screen.WidthInMillimeters) / int(screen.WidthInPixels))
+		mmHeight := uint32(height * int(screen.HeightInMillimeters) / int(screen.HeightInPixels))
+		err := randr.SetScreenSizeChecked(x.conn, x.root, uint16(width), uint16(height),
+			mmWidth, mmHeight).Check()
+		if err != nil {
+			return fmt.Errorf("resizing the screen to %dx%d: %v", width, height, err)
+		}
+	}
+
+	for _, p := range plans {
+		if p.keep {
+			continue
+		}
+		reply, err := randr.SetCrtcConfig(x.conn, p.crtc, xproto.TimeCurrentTime, st.res.ConfigTimestamp,
+			p.cfg.X, p.cfg.Y, p.mode, p.cfg.Rotation, []randr.Output{p.out}).Reply()
+		if err == nil && reply.Status != randr.SetConfigSuccess {
+			err = fmt.Errorf("status %d", reply.Status)
+		}
+		if err != nil {
+			return fmt.Errorf("setting up %s: %v", p.cfg.Name, err)
+		}
+	}
+
+	for _, p := range plans {
+		if p.cfg.Primary {
+			return randr.SetOutputPrimaryChecked(x.conn, x.root, p.out).Check()
+		}
+	}
+	return nil
+}
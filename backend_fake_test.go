@@ -0,0 +1,522 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// fakeProperty is a window property stored by the fake backend
+type fakeProperty struct {
+	typ    xproto.Atom
+	format byte
+	data   []byte
+}
+
+// fakeWindow is the in-memory state of a window
+type fakeWindow struct {
+	parent           xproto.Window
+	geom             Rect
+	borderWidth      uint16
+	borderPixel      uint32
+	eventMask        uint32
+	mapped           bool
+	overrideRedirect bool
+	props            map[xproto.Atom]fakeProperty
+}
+
+// fakeGrab records a key or button grab
+type fakeGrab struct {
+	win    xproto.Window
+	mod    uint16
+	detail byte
+}
+
+// fakeEvent records an event sent with SendEvent
+type fakeEvent struct {
+	win  xproto.Window
+	mask uint32
+	data []byte
+}
+
+// fakeBackend is an in-memory Backend used by tests
+type fakeBackend struct {
+	root    xproto.Window
+	windows map[xproto.Window]*fakeWindow
+	nextID  xproto.Window
+	atoms   map[string]xproto.Atom
+
+	focus  xproto.Window
+	stack  []xproto.Window // bottom to top
+	killed []xproto.Window
+	sent   []fakeEvent
+
+	monitors         []Monitor // RandR monitors; none means RandR is unavailable
+	monitorEvents    bool      // SelectMonitorEvents was called
+	outputs          []*Output
+	applied          [][]OutputConfig // Every ApplyOutputs call
+	keyGrabs         []fakeGrab
+	buttonGrabs      []fakeGrab
+	keyboardGrabbed  bool
+	keyboardGrabFail bool // make GrabKeyboard fail, as when another client holds it
+
+	minKeycode     xproto.Keycode
+	maxKeycode     xproto.Keycode
+	keysymsPerCode int
+	keysyms        []xproto.Keysym
+}
+
+// fakeKeymap is the keyboard layout of the fake backend: each entry is one
+// keycode (starting at 8) with its unshifted and shifted keysyms
+var fakeKeymap = [][2]xproto.Keysym{
+	{XK_Escape, 0}, {XK_1, XK_exclam}, {XK_2, XK_at}, {XK_3, XK_numbersign},
+	{XK_4, XK_dollar}, {XK_5, XK_percent}, {XK_6, XK_asciicircum}, {XK_7, XK_ampersand},
+	{XK_8, XK_asterisk}, {XK_9, XK_parenleft}, {XK_0, XK_parenright}, {XK_minus, XK_underscore},
+	{XK_equal, XK_plus}, {XK_BackSpace, 0}, {XK_Tab, 0}, {XK_Return, 0}, {XK_space, 0},
+	{XK_grave, XK_asciitilde}, {XK_comma, XK_less}, {XK_period, XK_greater},
+	{XK_bracketleft, XK_braceleft}, {XK_bracketright, XK_braceright}, {XK_Print, 0},
+	{XK_Shift_L, 0}, {XK_F1, 0}, {XK_F2, 0}, {XK_F3, 0}, {XK_F5, 0}, {XK_F6, 0}, {XK_F7, 0}, {XK_F8, 0}, {XK_F9, 0},
+	{XF86XK_AudioMute, 0}, {XF86XK_AudioLowerVolume, 0}, {XF86XK_AudioRaiseVolume, 0},
+}
+
+// newFakeBackend creates a fake X server with a root window of the given size
+func newFakeBackend(width, height uint16) *fakeBackend {
+	f := &fakeBackend{
+		root:           1,
+		windows:        make(map[xproto.Window]*fakeWindow),
+		nextID:         0x100,
+		atoms:          make(map[string]xproto.Atom),
+		minKeycode:     8,
+		keysymsPerCode: 2,
+	}
+	f.windows[f.root] = &fakeWindow{
+		geom:   Rect{Width: width, Height: height},
+		mapped: true,
+		props:  make(map[xproto.Atom]fakeProperty),
+	}
+
+	for _, syms := range fakeKeymap {
+		f.keysyms = append(f.keysyms, syms[0], syms[1])
+	}
+	for c := XK_a; c <= XK_z; c++ {
+		f.keysyms = append(f.keysyms, xproto.Keysym(c), xproto.Keysym(c-XK_a+XK_A))
+	}
+	f.maxKeycode = f.minKeycode + xproto.Keycode(len(f.keysyms)/f.keysymsPerCode) - 1
+
+	return f
+}
+
+// addWindow creates an unmapped top-level window, as a client would
+func (f *fakeBackend) addWindow(geom Rect) xproto.Window {
+	win, _ := f.CreateWindow(f.root, geom, 0, xproto.WindowClassInputOutput, 0, nil)
+	return win
+}
+
+// setClass sets WM_CLASS on a window
+func (f *fakeBackend) setClass(win xproto.Window, instance, class string) {
+	f.ChangeProperty(win, xproto.AtomWmClass, xproto.AtomString, 8,
+		[]byte(instance+"\x00"+class+"\x00"))
+}
+
+// setCardinals sets a 32-bit property on a window
+func (f *fakeBackend) setCardinals(win xproto.Window, prop, typ xproto.Atom, values ...uint32) {
+	f.ChangeProperty(win, prop, typ, 32, encodeCardinals(values...))
+}
+
+// atom returns the atom interned for name
+func (f *fakeBackend) atom(name string) xproto.Atom {
+	a, _ := f.InternAtom(name)
+	return a
+}
+
+// window returns the state of a window, failing loudly if it doesn't exist
+func (f *fakeBackend) window(win xproto.Window) *fakeWindow {
+	w, ok := f.windows[win]
+	if !ok {
+		panic(fmt.Sprintf("fake backend: no window %d", win))
+	}
+	return w
+}
+
+// cardinals reads a 32-bit property back as a list of values
+func (f *fakeBackend) cardinals(win xproto.Window, prop xproto.Atom) []uint32 {
+	p, ok := f.window(win).props[prop]
+	if !ok {
+		return nil
+	}
+	return decodeCardinals(p.data)
+}
+
+// top returns the topmost window in the stacking order
+func (f *fakeBackend) top() xproto.Window {
+	if len(f.stack) == 0 {
+		return 0
+	}
+	return f.stack[len(f.stack)-1]
+}
+
+func (f *fakeBackend) raise(win xproto.Window) {
+	for i, w := range f.stack {
+		if w == win {
+			f.stack = append(f.stack[:i], f.stack[i+1:]...)
+			break
+		}
+	}
+	f.stack = append(f.stack, win)
+}
+
+func (f *fakeBackend) lookup(win xproto.Window) (*fakeWindow, error) {
+	w, ok := f.windows[win]
+	if !ok {
+		return nil, errors.New("BadWindow")
+	}
+	return w, nil
+}
+
+func (f *fakeBackend) ChangeWindowAttributes(win xproto.Window, mask uint32, values []uint32) {
+	f.ChangeWindowAttributesChecked(win, mask, values)
+}
+
+func (f *fakeBackend) ChangeWindowAttributesChecked(win xproto.Window, mask uint32, values []uint32) error {
+	w, err := f.lookup(win)
+	if err != nil {
+		return err
+	}
+	i := 0
+	for bit := uint32(1); bit <= xproto.CwCursor; bit <<= 1 {
+		if mask&bit == 0 {
+			continue
+		}
+		v := values[i]
+		i++
+		switch bit {
+		case xproto.CwBorderPixel:
+			w.borderPixel = v
+		case xproto.CwOverrideRedirect:
+			w.overrideRedirect = v != 0
+		case xproto.CwEventMask:
+			w.eventMask = v
+		}
+	}
+	return nil
+}
+
+func (f *fakeBackend) ConfigureWindow(win xproto.Window, mask uint16, values []uint32) {
+	w, err := f.lookup(win)
+	if err != nil {
+		return
+	}
+	i := 0
+	for bit := uint16(1); bit <= xproto.ConfigWindowStackMode; bit <<= 1 {
+		if mask&bit == 0 {
+			continue
+		}
+		v := values[i]
+		i++
+		switch bit {
+		case xproto.ConfigWindowX:
+			w.geom.X = int16(v)
+		case xproto.ConfigWindowY:
+			w.geom.Y = int16(v)
+		case xproto.ConfigWindowWidth:
+			w.geom.Width = uint16(v)
+		case xproto.ConfigWindowHeight:
+			w.geom.Height = uint16(v)
+		case xproto.ConfigWindowBorderWidth:
+			w.borderWidth = uint16(v)
+		case xproto.ConfigWindowStackMode:
+			if v == xproto.StackModeAbove {
+				f.raise(win)
+			}
+		}
+	}
+}
+
+func (f *fakeBackend) GetGeometry(win xproto.Window) (*xproto.GetGeometryReply, error) {
+	w, err := f.lookup(win)
+	if err != nil {
+		return nil, err
+	}
+	return &xproto.GetGeometryReply{
+		Root:        f.root,
+		X:           w.geom.X,
+		Y:           w.geom.Y,
+		Width:       w.geom.Width,
+		Height:      w.geom.Height,
+		BorderWidth: w.borderWidth,
+	}, nil
+}
+
+func (f *fakeBackend) GetWindowAttributes(win xproto.Window) (*xproto.GetWindowAttributesReply, error) {
+	w, err := f.lookup(win)
+	if err != nil {
+		return nil, err
+	}
+	state := byte(xproto.MapStateUnmapped)
+	if w.mapped {
+		state = xproto.MapStateViewable
+	}
+	return &xproto.GetWindowAttributesReply{
+		MapState:         state,
+		OverrideRedirect: w.overrideRedirect,
+		YourEventMask:    w.eventMask,
+	}, nil
+}
+
+func (f *fakeBackend) QueryTree(win xproto.Window) ([]xproto.Window, error) {
+	if _, err := f.lookup(win); err != nil {
+		return nil, err
+	}
+	var children []xproto.Window
+	for _, w := range f.stack {
+		if f.windows[w].parent == win {
+			children = append(children, w)
+		}
+	}
+	return children, nil
+}
+
+func (f *fakeBackend) CreateWindow(parent xproto.Window, r Rect, borderWidth uint16, class uint16,
+	mask uint32, values []uint32) (xproto.Window, error) {
+	if _, err := f.lookup(parent); err != nil {
+		return 0, err
+	}
+	win := f.nextID
+	f.nextID++
+	f.windows[win] = &fakeWindow{
+		parent:      parent,
+		geom:        r,
+		borderWidth: borderWidth,
+		props:       make(map[xproto.Atom]fakeProperty),
+	}
+	f.stack = append(f.stack, win)
+	f.ChangeWindowAttributes(win, mask, values)
+	return win, nil
+}
+
+// destroyWindow removes a window, as if its client had exited
+func (f *fakeBackend) destroyWindow(win xproto.Window) {
+	delete(f.windows, win)
+	for i, w := range f.stack {
+		if w == win {
+			f.stack = append(f.stack[:i], f.stack[i+1:]...)
+			break
+		}
+	}
+}
+
+func (f *fakeBackend) MapWindow(win xproto.Window) {
+	if w, err := f.lookup(win); err == nil {
+		w.mapped = true
+	}
+}
+
+func (f *fakeBackend) UnmapWindow(win xproto.Window) {
+	if w, err := f.lookup(win); err == nil {
+		w.mapped = false
+	}
+}
+
+func (f *fakeBackend) SetInputFocus(win xproto.Window) {
+	f.focus = win
+}
+
+func (f *fakeBackend) InternAtom(name string) (xproto.Atom, error) {
+	if a, ok := f.atoms[name]; ok {
+		return a, nil
+	}
+	// Stay clear of the predefined atoms
+	a := xproto.Atom(1000 + len(f.atoms))
+	f.atoms[name] = a
+	return a, nil
+}
+
+func (f *fakeBackend) ChangeProperty(win xproto.Window, prop, typ xproto.Atom, format byte, data []byte) {
+	w, err := f.lookup(win)
+	if err != nil {
+		return
+	}
+	w.props[prop] = fakeProperty{typ: typ, format: format, data: append([]byte(nil), data...)}
+}
+
+func (f *fakeBackend) DeleteProperty(win xproto.Window, prop xproto.Atom) {
+	if w, err := f.lookup(win); err == nil {
+		delete(w.props, prop)
+	}
+}
+
+func (f *fakeBackend) GetProperty(win xproto.Window, prop, typ xproto.Atom, offset, length uint32) (*xproto.GetPropertyReply, error) {
+	w, err := f.lookup(win)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := w.props[prop]
+	if !ok {
+		return &xproto.GetPropertyReply{}, nil
+	}
+	if typ != xproto.GetPropertyTypeAny && typ != p.typ {
+		return &xproto.GetPropertyReply{Type: p.typ, Format: p.format}, nil
+	}
+
+	// Offset and length are in 32-bit units
+	data := p.data
+	start := int(offset) * 4
+	if start > len(data) {
+		start = len(data)
+	}
+	end := start + int(length)*4
+	if end > len(data) {
+		end = len(data)
+	}
+	data = data[start:end]
+
+	return &xproto.GetPropertyReply{
+		Format:   p.format,
+		Type:     p.typ,
+		ValueLen: uint32(len(data) * 8 / int(p.format)),
+		Value:    data,
+	}, nil
+}
+
+func (f *fakeBackend) SendEvent(win xproto.Window, mask uint32, event []byte) {
+	f.sent = append(f.sent, fakeEvent{win: win, mask: mask, data: event})
+}
+
+func (f *fakeBackend) KillClient(win xproto.Window) {
+	f.killed = append(f.killed, win)
+}
+
+func (f *fakeBackend) GetKeyboardMapping(first xproto.Keycode, count byte) (*xproto.GetKeyboardMappingReply, error) {
+	start := int(first-f.minKeycode) * f.keysymsPerCode
+	end := start + int(count)*f.keysymsPerCode
+	if end > len(f.keysyms) {
+		end = len(f.keysyms)
+	}
+	return &xproto.GetKeyboardMappingReply{
+		KeysymsPerKeycode: byte(f.keysymsPerCode),
+		Keysyms:           append([]xproto.Keysym(nil), f.keysyms[start:end]...),
+	}, nil
+}
+
+// keycode returns the first keycode producing keysym in the fake keymap
+func (f *fakeBackend) keycode(keysym xproto.Keysym) xproto.Keycode {
+	for i, sym := range f.keysyms {
+		if sym == keysym {
+			return f.minKeycode + xproto.Keycode(i/f.keysymsPerCode)
+		}
+	}
+	return 0
+}
+
+func (f *fakeBackend) GrabKey(win xproto.Window, mod uint16, key xproto.Keycode) {
+	f.keyGrabs = append(f.keyGrabs, fakeGrab{win: win, mod: mod, detail: byte(key)})
+}
+
+func (f *fakeBackend) UngrabAllKeys(win xproto.Window) {
+	var kept []fakeGrab
+	for _, g := range f.keyGrabs {
+		if g.win != win {
+			kept = append(kept, g)
+		}
+	}
+	f.keyGrabs = kept
+}
+
+func (f *fakeBackend) GrabKeyboard(win xproto.Window) error {
+	if f.keyboardGrabFail {
+		return errors.New("keyboard already grabbed")
+	}
+	f.keyboardGrabbed = true
+	return nil
+}
+
+func (f *fakeBackend) UngrabKeyboard() {
+	f.keyboardGrabbed = false
+}
+
+func (f *fakeBackend) GrabButton(win xproto.Window, mod uint16, button byte) {
+	f.buttonGrabs = append(f.buttonGrabs, fakeGrab{win: win, mod: mod, detail: button})
+}
+
+func (f *fakeBackend) UngrabAllButtons(win xproto.Window) {
+	var kept []fakeGrab
+	for _, g := range f.buttonGrabs {
+		if g.win != win {
+			kept = append(kept, g)
+		}
+	}
+	f.buttonGrabs = kept
+}
+
+func (f *fakeBackend) Monitors() ([]*Monitor, error) {
+	if len(f.monitors) == 0 {
+		return nil, errors.New("RandR unavailable")
+	}
+	monitors := make([]*Monitor, len(f.monitors))
+	for i := range f.monitors {
+		m := f.monitors[i]
+		monitors[i] = &m
+	}
+	return monitors, nil
+}
+
+func (f *fakeBackend) SelectMonitorEvents() error {
+	f.monitorEvents = true
+	return nil
+}
+
+func (f *fakeBackend) Outputs() ([]*Output, error) {
+	if len(f.outputs) == 0 {
+		return nil, errors.New("RandR unavailable")
+	}
+	outputs := make([]*Output, len(f.outputs))
+	for i, o := range f.outputs {
+		copied := *o
+		outputs[i] = &copied
+	}
+	return outputs, nil
+}
+
+// ApplyOutputs records the configuration and turns the enabled outputs
+// into monitors, resizing the root window to fit them
+func (f *fakeBackend) ApplyOutputs(configs []OutputConfig) error {
+	f.applied = append(f.applied, configs)
+
+	f.monitors = nil
+	root := &f.windows[f.root].geom
+	root.Width, root.Height = 0, 0
+	for _, c := range configs {
+		if !c.Enabled {
+			continue
+		}
+		w, h := c.size()
+		f.monitors = append(f.monitors, Monitor{
+			Name:     c.Name,
+			Primary:  c.Primary,
+			Geometry: Rect{X: c.X, Y: c.Y, Width: w, Height: h},
+		})
+		root.Width = max(root.Width, uint16(c.X)+w)
+		root.Height = max(root.Height, uint16(c.Y)+h)
+	}
+	return nil
+}
+
+// encodeCardinals packs values the way 32-bit properties are sent to X
+func encodeCardinals(values ...uint32) []byte {
+	data := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], v)
+	}
+	return data
+}
+
+// decodeCardinals unpacks a 32-bit property value
+func decodeCardinals(data []byte) []uint32 {
+	values := make([]uint32, len(data)/4)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return values
+}
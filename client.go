@@ -14,6 +14,13 @@ type Client struct {
 	Floating  bool
 	Workspace int
 	Urgent    bool // Window requests attention
+
+	BorderWidth uint16 // Border width last set on the window
+
+	// Title and WM_CLASS instance, kept up to date from property changes
+	// so events don't query X, even about destroyed windows
+	Title string
+	Class string
 }
 
 // Geometry returns the client's current geometry as a Rect
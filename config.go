@@ -1,6 +1,8 @@
 package main
 
 import (
+	"log"
+
 	"github.com/jezek/xgb/xproto"
 )
 
@@ -53,6 +55,9 @@ type Config struct {
 	FocusedBorderColor   uint32
 	UnfocusedBorderColor uint32
 	UrgentBorderColor    uint32
+	SmartGaps            SmartMode // When to leave gaps out
+	SmartBorders         SmartMode // When to leave borders out
+	MagnifyFactor        float64   // Growth of the focused window in magnify layouts
 
 	// Behavior
 	FocusFollowsMouse bool
@@ -64,8 +69,28 @@ type Config struct {
 	Terminal string
 	Launcher string
 
-	// Keybindings
+	// Keybindings by keysym, resolved to keycodes by SetupKeybindings
+	Bindings    []KeyBinding
 	Keybindings map[KeyCombo]Action
+	Submaps     map[string]*Submap
+
+	// Mouse bindings on client windows and the desktop
+	MouseBindings []MouseBinding
+
+	// Window rules, scratchpad, startup and GridSelect launcher items
+	Rules      []WindowRule
+	Scratchpad *Scratchpad
+	Startup    *StartupConfig
+	SpawnItems []SpawnItem
+
+	// Output layouts, tried in order against the connected monitors
+	DisplayProfiles []*DisplayProfile
+
+	// Monitors divided into virtual monitors
+	MonitorSplits []MonitorSplit
+
+	// Appearance overrides by workspace ID (0 is workspace 1)
+	WorkspaceSettings map[int]WorkspaceSettings
 }
 
 // KeyCombo represents a key combination (modifier + keycode)
@@ -74,13 +99,22 @@ type KeyCombo struct {
 	Keycode xproto.Keycode
 }
 
+// KeyBinding binds a modifier mask and keysym to an action. A nil Action
+// removes an earlier binding for the same keys.
+type KeyBinding struct {
+	Mod    uint16
+	Keysym xproto.Keysym
+	Action Action
+}
+
 // Action is a function that performs a window manager action
 type Action func(*WindowManager)
 
 // DefaultConfig returns the default configuration matching your xmonad setup
 func DefaultConfig() *Config {
-	return &Config{
+	cfg := &Config{
 		BorderWidth:          2,
+		MagnifyFactor:        defaultMagnifyFactor,
 		OuterGap:             4, // Gap between windows and screen edge
 		InnerGap:             4, // Gap between windows
 		FocusedBorderColor:   ColorLavender,
@@ -90,140 +124,183 @@ func DefaultConfig() *Config {
 		ModKey:               xproto.ModMask4, // Super key
 		Terminal:             "kitty",
 		Launcher:             "sh ~/.config/rofi/scripts/rofi-main.sh",
+		Rules:                DefaultRules(),
+		Scratchpad:           DefaultScratchpad(),
+		Startup:              DefaultStartupConfig(),
+		SpawnItems:           DefaultSpawnItems(),
+		Submaps:              DefaultSubmaps(),
 	}
+	cfg.Bindings = DefaultKeybindings(cfg)
+	cfg.MouseBindings = DefaultMouseBindings(cfg)
+	return cfg
 }
 
-// SetupKeybindings configures all keybindings (called after WM has keysym mapping)
-func (wm *WindowManager) SetupKeybindings() {
-	mod := wm.config.ModKey
+// DefaultKeybindings returns the built-in keybindings for cfg's modifier key
+// and applications. Later entries override earlier ones for the same keys.
+func DefaultKeybindings(cfg *Config) []KeyBinding {
+	mod := cfg.ModKey
 	shift := uint16(xproto.ModMaskShift)
 	ctrl := uint16(xproto.ModMaskControl)
 
-	wm.config.Keybindings = map[KeyCombo]Action{
+	return []KeyBinding{
 		// Scratchpad
-		{mod, wm.keysymToKeycode(XK_grave)}: ActionToggleScratchpad,
+		{mod, XK_grave, ActionToggleScratchpad},
 
 		// Applications
-		{mod, wm.keysymToKeycode(XK_Return)}:         ActionSpawn(wm.config.Terminal),
-		{mod | shift, wm.keysymToKeycode(XK_Return)}: ActionSpawn(wm.config.Terminal + " --class floating"),
-		{mod | shift, wm.keysymToKeycode(XK_f)}:      ActionSpawn("thunar"),
-		{mod, wm.keysymToKeycode(XK_r)}:              ActionSpawn(wm.config.Launcher),
-		{mod, wm.keysymToKeycode(XK_d)}:              ActionSpawn(wm.config.Launcher),
+		{mod, XK_Return, ActionSpawn(cfg.Terminal)},
+		{mod | shift, XK_Return, ActionSpawn(cfg.Terminal + " --class floating")},
+		{mod | shift, XK_f, ActionSpawn("thunar")},
+		{mod, XK_r, ActionSpawn(cfg.Launcher)},
+		{mod, XK_d, ActionSpawn(cfg.Launcher)},
 
 		// Window management
-		{mod, wm.keysymToKeycode(XK_q)}:         ActionKill,
-		{mod | shift, wm.keysymToKeycode(XK_q)}: ActionKillAll,
-		{mod, wm.keysymToKeycode(XK_g)}:         ActionGridSelect,
-		{mod | shift, wm.keysymToKeycode(XK_g)}: ActionGridSelectWorkspaces,
-		{mod, wm.keysymToKeycode(XK_p)}:         ActionGridSelectSpawn,
+		{mod, XK_q, ActionKill},
+		{mod | shift, XK_q, ActionKillAll},
+		{mod, XK_g, ActionGridSelect},
+		{mod | shift, XK_g, ActionGridSelectWorkspaces},
+		{mod, XK_p, ActionGridSelectSpawn},
 
 		// Focus
-		{mod, wm.keysymToKeycode(XK_j)}:   ActionFocusNext,
-		{mod, wm.keysymToKeycode(XK_k)}:   ActionFocusPrev,
-		{mod, wm.keysymToKeycode(XK_Tab)}: ActionFocusNext,
-		{mod, wm.keysymToKeycode(XK_m)}:   ActionFocusMaster,
+		{mod, XK_j, ActionFocusNext},
+		{mod, XK_k, ActionFocusPrev},
+		{mod, XK_Tab, ActionFocusNext},
+		{mod, XK_m, ActionFocusMaster},
 
 		// Swap
-		{mod | shift, wm.keysymToKeycode(XK_j)}: ActionSwapNext,
-		{mod | shift, wm.keysymToKeycode(XK_k)}: ActionSwapPrev,
+		{mod | shift, XK_j, ActionSwapNext},
+		{mod | shift, XK_k, ActionSwapPrev},
 
 		// Resize
-		{mod, wm.keysymToKeycode(XK_h)}:      ActionShrink,
-		{mod, wm.keysymToKeycode(XK_l)}:      ActionExpand,
-		{mod, wm.keysymToKeycode(XK_comma)}:  ActionIncMaster,
-		{mod, wm.keysymToKeycode(XK_period)}: ActionDecMaster,
+		{mod, XK_h, ActionShrink},
+		{mod, XK_l, ActionExpand},
+		{mod, XK_comma, ActionIncMaster},
+		{mod, XK_period, ActionDecMaster},
 
 		// Layout
-		{mod, wm.keysymToKeycode(XK_space)}:         ActionNextLayout,
-		{mod | shift, wm.keysymToKeycode(XK_space)}: ActionResetLayout,
-		{mod, wm.keysymToKeycode(XK_b)}:             ActionToggleStruts,
+		{mod, XK_space, ActionNextLayout},
+		{mod | shift, XK_space, ActionResetLayout},
+		{mod, XK_b, ActionToggleStruts},
 
 		// Floating
-		{mod, wm.keysymToKeycode(XK_s)}: ActionSink,
+		{mod, XK_s, ActionSink},
 
 		// Restart/Quit
-		{mod | shift, wm.keysymToKeycode(XK_r)}: ActionRestart,
-		{mod | ctrl, wm.keysymToKeycode(XK_q)}:  ActionQuit,
+		{mod | shift, XK_r, ActionRestart},
+		{mod | ctrl, XK_q, ActionQuit},
+
+		// Modes
+		{mod | ctrl, XK_r, ActionEnterSubmap("resize")},
+
+		// Monitors
+		{mod, XK_o, ActionFocusNextMonitor},
+		{mod | shift, XK_o, ActionMoveToNextMonitor},
+		{mod | ctrl, XK_o, ActionSwapMonitors},
 
 		// Workspaces 1-9
-		{mod, wm.keysymToKeycode(XK_1)}: ActionSwitchWorkspace(0),
-		{mod, wm.keysymToKeycode(XK_2)}: ActionSwitchWorkspace(1),
-		{mod, wm.keysymToKeycode(XK_3)}: ActionSwitchWorkspace(2),
-		{mod, wm.keysymToKeycode(XK_4)}: ActionSwitchWorkspace(3),
-		{mod, wm.keysymToKeycode(XK_5)}: ActionSwitchWorkspace(4),
-		{mod, wm.keysymToKeycode(XK_6)}: ActionSwitchWorkspace(5),
-		{mod, wm.keysymToKeycode(XK_7)}: ActionSwitchWorkspace(6),
-		{mod, wm.keysymToKeycode(XK_8)}: ActionSwitchWorkspace(7),
-		{mod, wm.keysymToKeycode(XK_9)}: ActionSwitchWorkspace(8),
+		{mod, XK_1, ActionSwitchWorkspace(0)},
+		{mod, XK_2, ActionSwitchWorkspace(1)},
+		{mod, XK_3, ActionSwitchWorkspace(2)},
+		{mod, XK_4, ActionSwitchWorkspace(3)},
+		{mod, XK_5, ActionSwitchWorkspace(4)},
+		{mod, XK_6, ActionSwitchWorkspace(5)},
+		{mod, XK_7, ActionSwitchWorkspace(6)},
+		{mod, XK_8, ActionSwitchWorkspace(7)},
+		{mod, XK_9, ActionSwitchWorkspace(8)},
 
 		// Move to workspace 1-9
-		{mod | shift, wm.keysymToKeycode(XK_1)}: ActionMoveToWorkspace(0),
-		{mod | shift, wm.keysymToKeycode(XK_2)}: ActionMoveToWorkspace(1),
-		{mod | shift, wm.keysymToKeycode(XK_3)}: ActionMoveToWorkspace(2),
-		{mod | shift, wm.keysymToKeycode(XK_4)}: ActionMoveToWorkspace(3),
-		{mod | shift, wm.keysymToKeycode(XK_5)}: ActionMoveToWorkspace(4),
-		{mod | shift, wm.keysymToKeycode(XK_6)}: ActionMoveToWorkspace(5),
-		{mod | shift, wm.keysymToKeycode(XK_7)}: ActionMoveToWorkspace(6),
-		{mod | shift, wm.keysymToKeycode(XK_8)}: ActionMoveToWorkspace(7),
-		{mod | shift, wm.keysymToKeycode(XK_9)}: ActionMoveToWorkspace(8),
+		{mod | shift, XK_1, ActionMoveToWorkspace(0)},
+		{mod | shift, XK_2, ActionMoveToWorkspace(1)},
+		{mod | shift, XK_3, ActionMoveToWorkspace(2)},
+		{mod | shift, XK_4, ActionMoveToWorkspace(3)},
+		{mod | shift, XK_5, ActionMoveToWorkspace(4)},
+		{mod | shift, XK_6, ActionMoveToWorkspace(5)},
+		{mod | shift, XK_7, ActionMoveToWorkspace(6)},
+		{mod | shift, XK_8, ActionMoveToWorkspace(7)},
+		{mod | shift, XK_9, ActionMoveToWorkspace(8)},
 
 		// Developer tools
-		{mod | shift, wm.keysymToKeycode(XK_l)}: ActionSpawn("kitty -e lazydocker"),
-		{mod | shift, wm.keysymToKeycode(XK_b)}: ActionSpawn("kitty -e btop"),
+		{mod | shift, XK_l, ActionSpawn("kitty -e lazydocker")},
+		{mod | shift, XK_b, ActionSpawn("kitty -e btop")},
 
 		// Telegram
-		{mod | ctrl, wm.keysymToKeycode(XK_t)}: ActionSpawn("$HOME/Telegram/Telegram"),
+		{mod | ctrl, XK_t, ActionSpawn("$HOME/Telegram/Telegram")},
 
 		// Screenshots (using rofi_screenshot)
-		{0, wm.keysymToKeycode(XK_Print)}:       ActionSpawn("sh $HOME/.config/bspwm/scripts/rofi_screenshot"),
-		{mod, wm.keysymToKeycode(XK_Print)}:     ActionSpawn("sh $HOME/.config/bspwm/scripts/rofi_screenshot"),
-		{mod | shift, wm.keysymToKeycode(XK_s)}: ActionSpawn("sh $HOME/.config/bspwm/scripts/rofi_screenshot"),
+		{0, XK_Print, ActionSpawn("sh $HOME/.config/bspwm/scripts/rofi_screenshot")},
+		{mod, XK_Print, ActionSpawn("sh $HOME/.config/bspwm/scripts/rofi_screenshot")},
+		{mod | shift, XK_s, ActionSpawn("sh $HOME/.config/bspwm/scripts/rofi_screenshot")},
 
 		// Rofi scripts
-		{mod, wm.keysymToKeycode(XK_w)}:         ActionSpawn("sh ~/.config/rofi/scripts/rofi-window.sh"),
-		{mod | shift, wm.keysymToKeycode(XK_p)}: ActionSpawn("rofi -show run"),
-		{mod, wm.keysymToKeycode(XK_x)}:         ActionSpawn("rofi -show ssh"),
+		{mod, XK_w, ActionSpawn("sh ~/.config/rofi/scripts/rofi-window.sh")},
+		{mod | shift, XK_p, ActionSpawn("rofi -show run")},
+		{mod, XK_x, ActionSpawn("rofi -show ssh")},
 
 		// Config editing
-		{mod, wm.keysymToKeycode(XK_e)}:        ActionSpawn("kitty -e nvim ~/.config/gowm/"),
-		{mod | ctrl, wm.keysymToKeycode(XK_e)}: ActionSpawn("kitty -e nvim ~/.config"),
+		{mod, XK_e, ActionSpawn("kitty -e nvim ~/.config/gowm/")},
+		{mod | ctrl, XK_e, ActionSpawn("kitty -e nvim ~/.config")},
 
 		// Keyboard layout
-		{mod, wm.keysymToKeycode(XK_p)}: ActionSpawn("sh $HOME/.scripts/change-layout-br"),
-		{mod, wm.keysymToKeycode(XK_u)}: ActionSpawn("sh $HOME/.scripts/change-layout-us"),
+		{mod, XK_p, ActionSpawn("sh $HOME/.scripts/change-layout-br")},
+		{mod, XK_u, ActionSpawn("sh $HOME/.scripts/change-layout-us")},
 
 		// Compositor toggle
-		{mod | ctrl, wm.keysymToKeycode(XK_d)}: ActionSpawn("killall picom || picom --config ~/.config/picom/picom.conf"),
+		{mod | ctrl, XK_d, ActionSpawn("killall picom || picom --config ~/.config/picom/picom.conf")},
 
 		// Gaming mode
-		{mod | shift, wm.keysymToKeycode(XK_g)}: ActionSpawn("~/.xmonad/gaming-mode.sh"),
+		{mod | shift, XK_g, ActionSpawn("~/.xmonad/gaming-mode.sh")},
 
 		// Volume (XF86 keys)
-		{0, wm.keysymToKeycode(XF86XK_AudioMute)}:        ActionSpawn("~/.config/eww/scripts/volume toggle"),
-		{0, wm.keysymToKeycode(XF86XK_AudioLowerVolume)}: ActionSpawn("~/.config/eww/scripts/volume down"),
-		{0, wm.keysymToKeycode(XF86XK_AudioRaiseVolume)}: ActionSpawn("~/.config/eww/scripts/volume up"),
+		{0, XF86XK_AudioMute, ActionSpawn("~/.config/eww/scripts/volume toggle")},
+		{0, XF86XK_AudioLowerVolume, ActionSpawn("~/.config/eww/scripts/volume down")},
+		{0, XF86XK_AudioRaiseVolume, ActionSpawn("~/.config/eww/scripts/volume up")},
 
 		// Volume (Fn keys fallback)
-		{mod, wm.keysymToKeycode(XK_F1)}: ActionSpawn("~/.config/eww/scripts/volume toggle"),
-		{mod, wm.keysymToKeycode(XK_F2)}: ActionSpawn("~/.config/eww/scripts/volume down"),
-		{mod, wm.keysymToKeycode(XK_F3)}: ActionSpawn("~/.config/eww/scripts/volume up"),
+		{mod, XK_F1, ActionSpawn("~/.config/eww/scripts/volume toggle")},
+		{mod, XK_F2, ActionSpawn("~/.config/eww/scripts/volume down")},
+		{mod, XK_F3, ActionSpawn("~/.config/eww/scripts/volume up")},
 
 		// Brightness (XF86 keys)
-		{0, wm.keysymToKeycode(XF86XK_MonBrightnessUp)}:   ActionSpawn("xbacklight -inc 5"),
-		{0, wm.keysymToKeycode(XF86XK_MonBrightnessDown)}: ActionSpawn("xbacklight -dec 5"),
+		{0, XF86XK_MonBrightnessUp, ActionSpawn("xbacklight -inc 5")},
+		{0, XF86XK_MonBrightnessDown, ActionSpawn("xbacklight -dec 5")},
 
 		// Brightness (Fn keys fallback)
-		{mod, wm.keysymToKeycode(XK_F5)}: ActionSpawn("xbacklight -dec 5"),
-		{mod, wm.keysymToKeycode(XK_F6)}: ActionSpawn("xbacklight -inc 5"),
+		{mod, XK_F5, ActionSpawn("xbacklight -dec 5")},
+		{mod, XK_F6, ActionSpawn("xbacklight -inc 5")},
 
 		// Media controls (XF86 keys)
-		{0, wm.keysymToKeycode(XF86XK_AudioPlay)}: ActionSpawn("playerctl play-pause"),
-		{0, wm.keysymToKeycode(XF86XK_AudioNext)}: ActionSpawn("playerctl next"),
-		{0, wm.keysymToKeycode(XF86XK_AudioPrev)}: ActionSpawn("playerctl previous"),
+		{0, XF86XK_AudioPlay, ActionSpawn("playerctl play-pause")},
+		{0, XF86XK_AudioNext, ActionSpawn("playerctl next")},
+		{0, XF86XK_AudioPrev, ActionSpawn("playerctl previous")},
 
 		// Media controls (Fn keys fallback)
-		{mod, wm.keysymToKeycode(XK_F7)}: ActionSpawn("playerctl previous"),
-		{mod, wm.keysymToKeycode(XK_F8)}: ActionSpawn("playerctl play-pause"),
-		{mod, wm.keysymToKeycode(XK_F9)}: ActionSpawn("playerctl next"),
+		{mod, XK_F7, ActionSpawn("playerctl previous")},
+		{mod, XK_F8, ActionSpawn("playerctl play-pause")},
+		{mod, XK_F9, ActionSpawn("playerctl next")},
+	}
+}
+
+// SetupKeybindings resolves the configured keybindings to keycodes (called
+// after WM has keysym mapping). Bindings whose keysym is missing from the
+// keymap are logged and remembered in wm.unmappedKeys.
+func (wm *WindowManager) SetupKeybindings() {
+	wm.config.Keybindings = make(map[KeyCombo]Action)
+	wm.unmappedKeys = nil
+
+	for _, b := range wm.config.Bindings {
+		combos, err := wm.resolveKeyCombo(b.Mod, b.Keysym)
+		if err != nil {
+			if b.Action != nil {
+				log.Printf("Keybinding ignored: %v", err)
+				wm.unmappedKeys = append(wm.unmappedKeys, formatKeyCombo(b.Mod, b.Keysym))
+			}
+			continue
+		}
+		for _, combo := range combos {
+			if b.Action == nil {
+				delete(wm.config.Keybindings, combo)
+			} else {
+				wm.config.Keybindings[combo] = b.Action
+			}
+		}
 	}
 }
@@ -0,0 +1,980 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigError is a problem in the config file, reported with its location
+type ConfigError struct {
+	Path string
+	Line int
+	Msg  string
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Msg)
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/gowm/config.toml, falling back
+// to ~/.config/gowm/config.toml
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "gowm", "config.toml")
+}
+
+// LoadConfig reads the config file at path and layers it over DefaultConfig.
+// A missing file is not an error: the compiled-in defaults are returned.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DefaultConfig(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(path, string(data))
+}
+
+// parseConfig decodes config file contents over DefaultConfig
+func parseConfig(path, src string) (*Config, error) {
+	root, err := parseTOML(src)
+	if err != nil {
+		if te, ok := err.(*tomlError); ok {
+			return nil, &ConfigError{Path: path, Line: te.Line, Msg: te.Msg}
+		}
+		return nil, err
+	}
+
+	d := &configDecoder{
+		path:            path,
+		cfg:             DefaultConfig(),
+		defaultBindings: true,
+		defaultMouse:    true,
+		defaultRules:    true,
+	}
+	if err := d.decode(root); err != nil {
+		return nil, err
+	}
+	return d.cfg, nil
+}
+
+// configDecoder turns a parsed TOML document into a Config
+type configDecoder struct {
+	path string
+	cfg  *Config
+
+	defaultBindings bool
+	defaultMouse    bool
+	defaultRules    bool
+
+	// "submap NAME" actions, checked once every submap is known
+	submapRefs []submapRef
+}
+
+type submapRef struct {
+	name  string
+	value *tomlValue
+}
+
+// configSections lists the top-level tables in the order they are applied.
+// Appearance, behavior and apps come first because the default keybindings
+// depend on the modifier key and applications.
+var configSections = []string{
+	"appearance", "behavior", "apps", "keybindings", "mouse", "submap",
+	"rule", "scratchpad", "startup", "spawn", "display", "split",
+	"workspace",
+}
+
+func (d *configDecoder) decode(root *tomlTable) error {
+	for _, key := range root.Keys {
+		if !containsString(configSections, key) {
+			return d.errorf(root.Values[key], "unknown section %q", key)
+		}
+	}
+
+	for _, name := range configSections {
+		v := root.Values[name]
+
+		switch name {
+		case "keybindings":
+			// Rebuild the defaults now that the modifier and apps are known
+			d.cfg.Bindings = nil
+			if d.defaultBindings {
+				d.cfg.Bindings = DefaultKeybindings(d.cfg)
+			}
+		case "mouse":
+			d.cfg.MouseBindings = nil
+			if d.defaultMouse {
+				d.cfg.MouseBindings = DefaultMouseBindings(d.cfg)
+			}
+		case "rule":
+			if !d.defaultRules {
+				d.cfg.Rules = nil
+			}
+		}
+
+		if v == nil {
+			continue
+		}
+		if err := d.section(name, v); err != nil {
+			return err
+		}
+	}
+
+	for _, ref := range d.submapRefs {
+		if _, ok := d.cfg.Submaps[ref.name]; !ok {
+			return d.errorf(ref.value, "unknown submap %q", ref.name)
+		}
+	}
+	return nil
+}
+
+func (d *configDecoder) section(name string, v *tomlValue) error {
+	switch name {
+	case "appearance":
+		return d.decodeAppearance(v)
+	case "behavior":
+		return d.decodeBehavior(v)
+	case "apps":
+		return d.decodeApps(v)
+	case "keybindings":
+		return d.decodeKeybindings(v)
+	case "mouse":
+		return d.decodeMouse(v)
+	case "submap":
+		return d.decodeSubmaps(v)
+	case "rule":
+		return d.decodeRules(v)
+	case "scratchpad":
+		return d.decodeScratchpad(v)
+	case "startup":
+		return d.decodeStartup(v)
+	case "spawn":
+		return d.decodeSpawnItems(v)
+	case "display":
+		return d.decodeDisplayProfiles(v)
+	case "split":
+		return d.decodeSplits(v)
+	case "workspace":
+		return d.decodeWorkspaces(v)
+	}
+	return nil
+}
+
+func (d *configDecoder) decodeAppearance(v *tomlValue) error {
+	cfg := d.cfg
+	return d.fields(v, "appearance", map[string]func(*tomlValue) error{
+		"border_width": func(v *tomlValue) error {
+			n, err := d.integer(v, 0, 100)
+			cfg.BorderWidth = uint16(n)
+			return err
+		},
+		"outer_gap": func(v *tomlValue) error {
+			n, err := d.integer(v, 0, 1000)
+			cfg.OuterGap = uint16(n)
+			return err
+		},
+		"inner_gap": func(v *tomlValue) error {
+			n, err := d.integer(v, 0, 1000)
+			cfg.InnerGap = uint16(n)
+			return err
+		},
+		"focused_border_color": func(v *tomlValue) (err error) {
+			cfg.FocusedBorderColor, err = d.color(v)
+			return err
+		},
+		"unfocused_border_color": func(v *tomlValue) (err error) {
+			cfg.UnfocusedBorderColor, err = d.color(v)
+			return err
+		},
+		"urgent_border_color": func(v *tomlValue) (err error) {
+			cfg.UrgentBorderColor, err = d.color(v)
+			return err
+		},
+		"smart_gaps": func(v *tomlValue) (err error) {
+			cfg.SmartGaps, err = d.smartMode(v)
+			return err
+		},
+		"smart_borders": func(v *tomlValue) (err error) {
+			cfg.SmartBorders, err = d.smartMode(v)
+			return err
+		},
+		"magnify_factor": func(v *tomlValue) (err error) {
+			cfg.MagnifyFactor, err = d.number(v, minMagnifyFactor, maxMagnifyFactor)
+			return err
+		},
+	})
+}
+
+// smartMode reads when smart gaps or borders apply: "off", "single",
+// "monocle" or "on"
+func (d *configDecoder) smartMode(v *tomlValue) (SmartMode, error) {
+	s, err := d.str(v)
+	if err != nil {
+		return 0, err
+	}
+	mode, ok := smartModeNames[s]
+	if !ok {
+		return 0, d.errorf(v, "unknown smart mode %q (off, single, monocle or on)", s)
+	}
+	return mode, nil
+}
+
+// decodeWorkspaces reads [workspace.N] tables, which override the gaps,
+// border width and smart modes on workspace N
+func (d *configDecoder) decodeWorkspaces(v *tomlValue) error {
+	table, err := d.table(v, "workspace")
+	if err != nil {
+		return err
+	}
+
+	d.cfg.WorkspaceSettings = make(map[int]WorkspaceSettings)
+	for _, key := range table.Keys {
+		n, err := strconv.Atoi(key)
+		if err != nil || n < 1 || n > 9 {
+			return d.errorf(table.Values[key], "unknown workspace %q (1-9)", key)
+		}
+
+		var settings WorkspaceSettings
+		mode := func(field **SmartMode) func(*tomlValue) error {
+			return func(v *tomlValue) error {
+				m, err := d.smartMode(v)
+				*field = &m
+				return err
+			}
+		}
+		size := func(field **uint16, max int) func(*tomlValue) error {
+			return func(v *tomlValue) error {
+				n, err := d.integer(v, 0, max)
+				px := uint16(n)
+				*field = &px
+				return err
+			}
+		}
+		err = d.fields(table.Values[key], "workspace."+key, map[string]func(*tomlValue) error{
+			"outer_gap":     size(&settings.OuterGap, 1000),
+			"inner_gap":     size(&settings.InnerGap, 1000),
+			"border_width":  size(&settings.BorderWidth, 100),
+			"smart_gaps":    mode(&settings.SmartGaps),
+			"smart_borders": mode(&settings.SmartBorders),
+		})
+		if err != nil {
+			return err
+		}
+		d.cfg.WorkspaceSettings[n-1] = settings
+	}
+	return nil
+}
+
+func (d *configDecoder) decodeBehavior(v *tomlValue) error {
+	cfg := d.cfg
+	return d.fields(v, "behavior", map[string]func(*tomlValue) error{
+		"focus_follows_mouse": func(v *tomlValue) (err error) {
+			cfg.FocusFollowsMouse, err = d.boolean(v)
+			return err
+		},
+		"mod_key": func(v *tomlValue) error {
+			s, err := d.str(v)
+			if err != nil {
+				return err
+			}
+			mod, ok := parseModifier(s, 0)
+			if !ok || mod == 0 {
+				return d.errorf(v, "%v", unknownModifier(s))
+			}
+			cfg.ModKey = mod
+			return nil
+		},
+		"default_keybindings": func(v *tomlValue) (err error) {
+			d.defaultBindings, err = d.boolean(v)
+			return err
+		},
+		"default_mouse_bindings": func(v *tomlValue) (err error) {
+			d.defaultMouse, err = d.boolean(v)
+			return err
+		},
+		"default_rules": func(v *tomlValue) (err error) {
+			d.defaultRules, err = d.boolean(v)
+			return err
+		},
+	})
+}
+
+func (d *configDecoder) decodeApps(v *tomlValue) error {
+	cfg := d.cfg
+	return d.fields(v, "apps", map[string]func(*tomlValue) error{
+		"terminal": func(v *tomlValue) (err error) {
+			cfg.Terminal, err = d.str(v)
+			return err
+		},
+		"launcher": func(v *tomlValue) (err error) {
+			cfg.Launcher, err = d.str(v)
+			return err
+		},
+	})
+}
+
+// decodeKeybindings reads "Mod+Shift+key" = "action" pairs. They are added
+// after the defaults, so they override any default using the same keys.
+func (d *configDecoder) decodeKeybindings(v *tomlValue) error {
+	table, err := d.table(v, "keybindings")
+	if err != nil {
+		return err
+	}
+
+	bindings, err := d.bindings(table)
+	d.cfg.Bindings = append(d.cfg.Bindings, bindings...)
+	return err
+}
+
+// decodeMouse reads "Mod+Button1" = "action" pairs from [mouse.client],
+// for buttons on windows, and [mouse.root], for the desktop. Like
+// keybindings they are added after the defaults.
+func (d *configDecoder) decodeMouse(v *tomlValue) error {
+	targets := map[string]MouseTarget{"client": MouseOnClient, "root": MouseOnRoot}
+	handlers := make(map[string]func(*tomlValue) error)
+	for name, target := range targets {
+		name, target := name, target
+		handlers[name] = func(v *tomlValue) error {
+			table, err := d.table(v, "mouse."+name)
+			if err != nil {
+				return err
+			}
+			for _, key := range table.Keys {
+				value := table.Values[key]
+				mod, button, err := ParseButtonCombo(key, d.cfg.ModKey)
+				if err != nil {
+					return d.errorf(value, "%v", err)
+				}
+				action, err := d.mouseAction(value, target)
+				if err != nil {
+					return err
+				}
+				d.cfg.MouseBindings = append(d.cfg.MouseBindings,
+					MouseBinding{Mod: mod, Button: button, Target: target, Action: action})
+			}
+			return nil
+		}
+	}
+	return d.fields(v, "mouse", handlers)
+}
+
+// mouseAction parses a mouse binding's action: "mouse.move", "mouse.resize"
+// or any keyboard action, which runs on the clicked window
+func (d *configDecoder) mouseAction(v *tomlValue, target MouseTarget) (MouseAction, error) {
+	s, err := d.str(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var drag MouseAction
+	switch strings.TrimSpace(s) {
+	case "mouse.move":
+		drag = MouseMove
+	case "mouse.resize":
+		drag = MouseResize
+	}
+	if drag != nil {
+		if target != MouseOnClient {
+			return nil, d.errorf(v, "%s only works in [mouse.client]", strings.TrimSpace(s))
+		}
+		return drag, nil
+	}
+
+	action, err := d.action(v)
+	if err != nil || action == nil {
+		return nil, err
+	}
+	return MouseRun(action), nil
+}
+
+// decodeSubmaps reads [submap.NAME] tables with their bindings in
+// [submap.NAME.keys]. A submap replaces a default one of the same name.
+func (d *configDecoder) decodeSubmaps(v *tomlValue) error {
+	table, err := d.table(v, "submap")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range table.Keys {
+		sm := &Submap{Name: name}
+		section := "submap." + name
+		err := d.fields(table.Values[name], section, map[string]func(*tomlValue) error{
+			"sticky": func(v *tomlValue) (err error) {
+				sm.Sticky, err = d.boolean(v)
+				return err
+			},
+			"timeout": func(v *tomlValue) error {
+				ms, err := d.integer(v, 0, 600000)
+				sm.Timeout = time.Duration(ms) * time.Millisecond
+				return err
+			},
+			"keys": func(v *tomlValue) error {
+				keys, err := d.table(v, section+".keys")
+				if err != nil {
+					return err
+				}
+				sm.Bindings, err = d.bindings(keys)
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+		d.cfg.Submaps[name] = sm
+	}
+	return nil
+}
+
+// bindings reads a table of "Mod+Shift+key" = "action" pairs
+func (d *configDecoder) bindings(table *tomlTable) ([]KeyBinding, error) {
+	var bindings []KeyBinding
+	for _, key := range table.Keys {
+		value := table.Values[key]
+
+		mod, keysym, err := ParseKeyCombo(key, d.cfg.ModKey)
+		if err != nil {
+			return nil, d.errorf(value, "%v", err)
+		}
+		action, err := d.action(value)
+		if err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, KeyBinding{Mod: mod, Keysym: keysym, Action: action})
+	}
+	return bindings, nil
+}
+
+// action parses an action string, remembering submap references so they
+// can be checked once every submap is known
+func (d *configDecoder) action(v *tomlValue) (Action, error) {
+	s, err := d.str(v)
+	if err != nil {
+		return nil, err
+	}
+	action, err := parseAction(s)
+	if err != nil {
+		return nil, d.errorf(v, "%v", err)
+	}
+	if fields := strings.Fields(s); fields[0] == "submap" {
+		d.submapRefs = append(d.submapRefs, submapRef{name: fields[1], value: v})
+	}
+	return action, nil
+}
+
+// decodeRules reads [[rule]] entries, which are appended to the default rules
+func (d *configDecoder) decodeRules(v *tomlValue) error {
+	tables, err := d.tables(v, "rule")
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tables {
+		var rule WindowRule
+		err := d.fields(&tomlValue{Line: t.Line, Value: t}, "rule", map[string]func(*tomlValue) error{
+			"class": func(v *tomlValue) (err error) {
+				rule.Class, err = d.str(v)
+				return err
+			},
+			"instance": func(v *tomlValue) (err error) {
+				rule.Instance, err = d.str(v)
+				return err
+			},
+			"title": func(v *tomlValue) (err error) {
+				rule.Title, err = d.str(v)
+				return err
+			},
+			"floating": func(v *tomlValue) error {
+				b, err := d.boolean(v)
+				rule.Floating = &b
+				return err
+			},
+			"workspace": func(v *tomlValue) error {
+				n, err := d.integer(v, 1, 9)
+				rule.Workspace = intPtr(n - 1)
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if rule.Class == "" && rule.Instance == "" && rule.Title == "" {
+			return &ConfigError{Path: d.path, Line: t.Line, Msg: "rule needs a class, instance or title to match"}
+		}
+		d.cfg.Rules = append(d.cfg.Rules, rule)
+	}
+	return nil
+}
+
+func (d *configDecoder) decodeScratchpad(v *tomlValue) error {
+	sp := d.cfg.Scratchpad
+	return d.fields(v, "scratchpad", map[string]func(*tomlValue) error{
+		"command": func(v *tomlValue) (err error) {
+			sp.Command, err = d.str(v)
+			return err
+		},
+		"class": func(v *tomlValue) (err error) {
+			sp.Class, err = d.str(v)
+			return err
+		},
+		"width": func(v *tomlValue) error {
+			n, err := d.integer(v, 1, 100)
+			sp.Width = uint16(n)
+			return err
+		},
+		"height": func(v *tomlValue) error {
+			n, err := d.integer(v, 1, 100)
+			sp.Height = uint16(n)
+			return err
+		},
+	})
+}
+
+func (d *configDecoder) decodeStartup(v *tomlValue) error {
+	st := d.cfg.Startup
+	return d.fields(v, "startup", map[string]func(*tomlValue) error{
+		"wm_name": func(v *tomlValue) (err error) {
+			st.SetWMName, err = d.str(v)
+			return err
+		},
+		"keyboard_layout": func(v *tomlValue) (err error) {
+			st.KeyboardLayout, err = d.str(v)
+			return err
+		},
+		"keyboard_options": func(v *tomlValue) (err error) {
+			st.KeyboardOptions, err = d.str(v)
+			return err
+		},
+		"key_repeat_delay": func(v *tomlValue) (err error) {
+			st.KeyRepeatDelay, err = d.integer(v, 0, 10000)
+			return err
+		},
+		"key_repeat_rate": func(v *tomlValue) (err error) {
+			st.KeyRepeatRate, err = d.integer(v, 0, 1000)
+			return err
+		},
+		"disable_screensaver": func(v *tomlValue) (err error) {
+			st.DisableScreenSaver, err = d.boolean(v)
+			return err
+		},
+		"disable_dpms": func(v *tomlValue) (err error) {
+			st.DisableDPMS, err = d.boolean(v)
+			return err
+		},
+		"autostart_once": func(v *tomlValue) (err error) {
+			st.AutostartOnce, err = d.strings(v)
+			return err
+		},
+		"autostart_always": func(v *tomlValue) (err error) {
+			st.AutostartAlways, err = d.strings(v)
+			return err
+		},
+		"wallpaper": func(v *tomlValue) (err error) {
+			st.WallpaperCommand, err = d.str(v)
+			return err
+		},
+	})
+}
+
+// decodeSpawnItems reads [[spawn]] entries, which replace the default
+// GridSelect launcher items
+func (d *configDecoder) decodeSpawnItems(v *tomlValue) error {
+	tables, err := d.tables(v, "spawn")
+	if err != nil {
+		return err
+	}
+
+	d.cfg.SpawnItems = nil
+	for _, t := range tables {
+		var item SpawnItem
+		err := d.fields(&tomlValue{Line: t.Line, Value: t}, "spawn", map[string]func(*tomlValue) error{
+			"name": func(v *tomlValue) (err error) {
+				item.Name, err = d.str(v)
+				return err
+			},
+			"command": func(v *tomlValue) (err error) {
+				item.Command, err = d.str(v)
+				return err
+			},
+			"action": func(v *tomlValue) (err error) {
+				item.Action, err = d.action(v)
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if item.Name == "" || (item.Command == "") == (item.Action == nil) {
+			return &ConfigError{Path: d.path, Line: t.Line, Msg: "spawn item needs a name and either a command or an action"}
+		}
+		d.cfg.SpawnItems = append(d.cfg.SpawnItems, item)
+	}
+	return nil
+}
+
+// decodeDisplayProfiles reads [[display]] profiles and their
+// [[display.output]] entries
+func (d *configDecoder) decodeDisplayProfiles(v *tomlValue) error {
+	tables, err := d.tables(v, "display")
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tables {
+		p := &DisplayProfile{}
+		err := d.fields(&tomlValue{Line: t.Line, Value: t}, "display", map[string]func(*tomlValue) error{
+			"name": func(v *tomlValue) (err error) {
+				p.Name, err = d.str(v)
+				return err
+			},
+			"output": func(v *tomlValue) error {
+				outputs, err := d.tables(v, "display.output")
+				if err != nil {
+					return err
+				}
+				for _, o := range outputs {
+					setup, err := d.outputSetup(o)
+					if err != nil {
+						return err
+					}
+					p.Outputs = append(p.Outputs, setup)
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if p.Name == "" || len(p.Outputs) == 0 {
+			return &ConfigError{Path: d.path, Line: t.Line, Msg: "display profile needs a name and at least one [[display.output]]"}
+		}
+		for _, other := range d.cfg.DisplayProfiles {
+			if other.Name == p.Name {
+				return &ConfigError{Path: d.path, Line: t.Line, Msg: fmt.Sprintf("display profile %q defined twice", p.Name)}
+			}
+		}
+		primaries := 0
+		for _, o := range p.Outputs {
+			if o.Primary {
+				primaries++
+			}
+		}
+		if primaries > 1 {
+			return &ConfigError{Path: d.path, Line: t.Line, Msg: fmt.Sprintf("display profile %q has more than one primary output", p.Name)}
+		}
+		d.cfg.DisplayProfiles = append(d.cfg.DisplayProfiles, p)
+	}
+	return nil
+}
+
+// outputSetup reads one [[display.output]] entry
+func (d *configDecoder) outputSetup(t *tomlTable) (OutputSetup, error) {
+	setup := OutputSetup{}
+	enabled := true
+	err := d.fields(&tomlValue{Line: t.Line, Value: t}, "display.output", map[string]func(*tomlValue) error{
+		"name": func(v *tomlValue) (err error) {
+			setup.Name, err = d.str(v)
+			return err
+		},
+		"edid": func(v *tomlValue) (err error) {
+			setup.EDID, err = d.str(v)
+			return err
+		},
+		"enabled": func(v *tomlValue) (err error) {
+			enabled, err = d.boolean(v)
+			return err
+		},
+		"mode": func(v *tomlValue) error {
+			s, err := d.str(v)
+			if err != nil || s == "preferred" {
+				return err
+			}
+			w, h, err := d.dimensions(v, s)
+			setup.Width, setup.Height = uint16(w), uint16(h)
+			return err
+		},
+		"rate": func(v *tomlValue) (err error) {
+			setup.Rate, err = d.number(v, 1, 1000)
+			return err
+		},
+		"position": func(v *tomlValue) error {
+			s, err := d.str(v)
+			if err != nil {
+				return err
+			}
+			x, y, err := d.dimensions(v, s)
+			setup.X, setup.Y, setup.Placed = int16(x), int16(y), true
+			return err
+		},
+		"rotation": func(v *tomlValue) error {
+			s, err := d.str(v)
+			if err != nil {
+				return err
+			}
+			r, ok := rotationNames[s]
+			if !ok {
+				return d.errorf(v, "rotation must be normal, left, right or inverted")
+			}
+			setup.Rotation = r
+			return nil
+		},
+		"primary": func(v *tomlValue) (err error) {
+			setup.Primary, err = d.boolean(v)
+			return err
+		},
+	})
+	if err != nil {
+		return setup, err
+	}
+	if setup.Name == "" && setup.EDID == "" {
+		return setup, &ConfigError{Path: d.path, Line: t.Line, Msg: "display output needs a name or edid to match"}
+	}
+	setup.Off = !enabled
+	return setup, nil
+}
+
+// decodeSplits reads [[split]] entries, which divide a monitor into
+// virtual monitors
+func (d *configDecoder) decodeSplits(v *tomlValue) error {
+	tables, err := d.tables(v, "split")
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tables {
+		var split MonitorSplit
+		err := d.fields(&tomlValue{Line: t.Line, Value: t}, "split", map[string]func(*tomlValue) error{
+			"monitor": func(v *tomlValue) (err error) {
+				split.Monitor, err = d.str(v)
+				return err
+			},
+			"sizes": func(v *tomlValue) error {
+				items, ok := v.Value.([]*tomlValue)
+				if !ok || len(items) < 2 {
+					return d.errorf(v, "sizes must list at least two percentages")
+				}
+				total := 0
+				for _, item := range items {
+					n, err := d.integer(item, 1, 99)
+					if err != nil {
+						return err
+					}
+					split.Sizes = append(split.Sizes, n)
+					total += n
+				}
+				if total != 100 {
+					return d.errorf(v, "sizes add up to %d%%, not 100%%", total)
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if split.Monitor == "" || split.Sizes == nil {
+			return &ConfigError{Path: d.path, Line: t.Line, Msg: "split needs a monitor and sizes"}
+		}
+		for _, other := range d.cfg.MonitorSplits {
+			if other.Monitor == split.Monitor {
+				return &ConfigError{Path: d.path, Line: t.Line, Msg: fmt.Sprintf("monitor %q split twice", split.Monitor)}
+			}
+		}
+		d.cfg.MonitorSplits = append(d.cfg.MonitorSplits, split)
+	}
+	return nil
+}
+
+// fields decodes a table by calling the handler registered for each key.
+// Unknown keys are errors so typos don't go unnoticed.
+func (d *configDecoder) fields(v *tomlValue, section string, handlers map[string]func(*tomlValue) error) error {
+	table, err := d.table(v, section)
+	if err != nil {
+		return err
+	}
+	for _, key := range table.Keys {
+		handler, ok := handlers[key]
+		if !ok {
+			return d.errorf(table.Values[key], "unknown key %q in [%s]", key, section)
+		}
+		if err := handler(table.Values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *configDecoder) errorf(v *tomlValue, format string, args ...interface{}) error {
+	return &ConfigError{Path: d.path, Line: v.Line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (d *configDecoder) table(v *tomlValue, section string) (*tomlTable, error) {
+	t, ok := v.Value.(*tomlTable)
+	if !ok {
+		return nil, d.errorf(v, "%s must be a table", section)
+	}
+	return t, nil
+}
+
+func (d *configDecoder) tables(v *tomlValue, section string) ([]*tomlTable, error) {
+	t, ok := v.Value.([]*tomlTable)
+	if !ok {
+		return nil, d.errorf(v, "%s must be written as [[%s]]", section, section)
+	}
+	return t, nil
+}
+
+func (d *configDecoder) str(v *tomlValue) (string, error) {
+	s, ok := v.Value.(string)
+	if !ok {
+		return "", d.errorf(v, "expected a string")
+	}
+	return s, nil
+}
+
+func (d *configDecoder) boolean(v *tomlValue) (bool, error) {
+	b, ok := v.Value.(bool)
+	if !ok {
+		return false, d.errorf(v, "expected true or false")
+	}
+	return b, nil
+}
+
+func (d *configDecoder) integer(v *tomlValue, min, max int) (int, error) {
+	n, ok := v.Value.(int64)
+	if !ok {
+		return 0, d.errorf(v, "expected an integer")
+	}
+	if n < int64(min) || n > int64(max) {
+		return 0, d.errorf(v, "%d is out of range (%d-%d)", n, min, max)
+	}
+	return int(n), nil
+}
+
+// number accepts integers and floats
+func (d *configDecoder) number(v *tomlValue, min, max float64) (float64, error) {
+	var n float64
+	switch x := v.Value.(type) {
+	case int64:
+		n = float64(x)
+	case float64:
+		n = x
+	default:
+		return 0, d.errorf(v, "expected a number")
+	}
+	if n < min || n > max {
+		return 0, d.errorf(v, "%g is out of range (%g-%g)", n, min, max)
+	}
+	return n, nil
+}
+
+// dimensions parses "1920x1080" style sizes and positions
+func (d *configDecoder) dimensions(v *tomlValue, s string) (int, int, error) {
+	var a, b int
+	var rest string
+	if n, _ := fmt.Sscanf(s, "%dx%d%s", &a, &b, &rest); n != 2 || a < 0 || b < 0 || a > 32767 || b > 32767 {
+		return 0, 0, d.errorf(v, "expected WIDTHxHEIGHT like \"1920x1080\", got %q", s)
+	}
+	return a, b, nil
+}
+
+func (d *configDecoder) strings(v *tomlValue) ([]string, error) {
+	items, ok := v.Value.([]*tomlValue)
+	if !ok {
+		return nil, d.errorf(v, "expected an array of strings")
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, err := d.str(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// color accepts "#rrggbb" strings or plain integers
+func (d *configDecoder) color(v *tomlValue) (uint32, error) {
+	switch c := v.Value.(type) {
+	case int64:
+		if c >= 0 && c <= 0xffffff {
+			return uint32(c), nil
+		}
+	case string:
+		hex := strings.TrimPrefix(c, "#")
+		if n, err := strconv.ParseUint(hex, 16, 32); err == nil && len(hex) == 6 {
+			return uint32(n), nil
+		}
+	}
+	return 0, d.errorf(v, "expected a color like \"#rrggbb\"")
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadConfig re-reads the config file and switches to it. An invalid file
+// is rejected and the running configuration stays active.
+func (wm *WindowManager) reloadConfig() error {
+	cfg, err := LoadConfig(wm.configPath)
+	if err != nil {
+		log.Printf("Config reload failed, keeping current config: %v", err)
+		spawn("notify-send -u critical 'gowm config' %s", shellQuote(err.Error()))
+		return err
+	}
+
+	wm.applyConfig(cfg)
+	log.Printf("Config reloaded from %s", wm.configPath)
+	return nil
+}
+
+// applyConfig replaces the running configuration without restarting:
+// key and mouse bindings are resolved and re-grabbed, borders redrawn, window rules
+// rebuilt and every workspace retiled
+func (wm *WindowManager) applyConfig(cfg *Config) {
+	// Keep tracking the scratchpad window that is already open
+	cfg.Scratchpad.window = wm.scratchpad.window
+	cfg.Scratchpad.visible = wm.scratchpad.visible
+
+	wm.exitSubmap()
+	wm.setConfig(cfg)
+
+	wm.SetupKeybindings()
+	wm.grabKeys()
+	wm.regrabMouseButtons()
+
+	wm.resetBorders()
+	for _, c := range wm.clients {
+		if c.Urgent {
+			wm.setUrgentBorder(c)
+		} else {
+			wm.setNormalBorder(c)
+		}
+	}
+
+	// Profiles and splits may have changed, so set the monitors up again
+	wm.outputsKey = ""
+	wm.autoDisplayProfile()
+	wm.updateMonitors()
+
+	wm.tile()
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// findBinding returns the last binding for mod+keysym, as SetupKeybindings
+// would resolve it
+func findBinding(cfg *Config, mod uint16, keysym xproto.Keysym) (KeyBinding, bool) {
+	var found KeyBinding
+	ok := false
+	for _, b := range cfg.Bindings {
+		if b.Mod == mod && b.Keysym == keysym {
+			found, ok = b, true
+		}
+	}
+	return found, ok
+}
+
+func TestLoadConfigMissingFileUsesDefaults(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := DefaultConfig()
+	if cfg.BorderWidth != def.BorderWidth || len(cfg.Bindings) != len(def.Bindings) ||
+		len(cfg.Rules) != len(def.Rules) {
+		t.Error("missing config file did not produce the defaults")
+	}
+}
+
+func TestParseConfigOverridesDefaults(t *testing.T) {
+	cfg, err := parseConfig("test.toml", `
+[appearance]
+border_width = 3
+focused_border_color = "#ff0000"
+
+[behavior]
+mod_key = "Alt"
+
+[apps]
+terminal = "alacritty"
+
+[keybindings]
+"Mod+Shift+x" = "spawn xterm -e top"
+"Mod+q" = "workspace.switch 4"
+"Mod+j" = "none"
+
+[[rule]]
+class = "discord"
+workspace = 8
+
+[scratchpad]
+width = 50
+
+[startup]
+autostart_always = ["dunst"]
+
+[[spawn]]
+name = "Editor"
+command = "nvim"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.BorderWidth != 3 || cfg.FocusedBorderColor != 0xff0000 {
+		t.Errorf("appearance = %d %#x", cfg.BorderWidth, cfg.FocusedBorderColor)
+	}
+	if cfg.UnfocusedBorderColor != ColorSurface0 || cfg.InnerGap != 4 {
+		t.Error("unset appearance values should keep their defaults")
+	}
+	if cfg.ModKey != xproto.ModMask1 || cfg.Terminal != "alacritty" {
+		t.Errorf("mod key = %#x, terminal = %q", cfg.ModKey, cfg.Terminal)
+	}
+
+	// Defaults are rebuilt with the new modifier key
+	if _, ok := findBinding(cfg, xproto.ModMask4, XK_Return); ok {
+		t.Error("default binding still uses Super")
+	}
+	if _, ok := findBinding(cfg, xproto.ModMask1, XK_Return); !ok {
+		t.Error("default Mod+Return missing for Alt")
+	}
+	if _, ok := findBinding(cfg, xproto.ModMask1|xproto.ModMaskShift, XK_x); !ok {
+		t.Error("custom binding missing")
+	}
+	if b, _ := findBinding(cfg, xproto.ModMask1, XK_j); b.Action != nil {
+		t.Error("\"none\" did not unbind Mod+j")
+	}
+
+	last := cfg.Rules[len(cfg.Rules)-1]
+	if len(cfg.Rules) != len(DefaultRules())+1 || last.Class != "discord" || *last.Workspace != 7 {
+		t.Errorf("rules = %d, last = %+v", len(cfg.Rules), last)
+	}
+	if cfg.Scratchpad.Width != 50 || cfg.Scratchpad.Height != 60 {
+		t.Errorf("scratchpad = %+v", cfg.Scratchpad)
+	}
+	if len(cfg.Startup.AutostartAlways) != 1 || cfg.Startup.KeyboardLayout != "us" {
+		t.Errorf("startup = %+v", cfg.Startup)
+	}
+	if len(cfg.SpawnItems) != 1 || cfg.SpawnItems[0].Command != "nvim" {
+		t.Errorf("spawn items = %+v", cfg.SpawnItems)
+	}
+}
+
+func TestParseConfigDisableDefaults(t *testing.T) {
+	cfg, err := parseConfig("test.toml", `
+[behavior]
+default_keybindings = false
+default_rules = false
+
+[keybindings]
+"Super+Return" = "spawn kitty"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Bindings) != 1 || len(cfg.Rules) != 0 {
+		t.Errorf("bindings = %d, rules = %d", len(cfg.Bindings), len(cfg.Rules))
+	}
+}
+
+func TestParseConfigErrorsPointAtLine(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"[apperance]\nborder_width = 2", "test.toml:1: unknown section \"apperance\""},
+		{"[appearance]\nborder_wdth = 2", "test.toml:2: unknown key \"border_wdth\" in [appearance]"},
+		{"[appearance]\n\nborder_width = \"2\"", "test.toml:3: expected an integer"},
+		{"[appearance]\nfocused_border_color = \"blue\"", "test.toml:2: expected a color"},
+		{"[appearance]\nmagnify_factor = 4", "test.toml:2: 4 is out of range (1-3)"},
+		{"[keybindings]\n\"Super+j\" = \"windw.kill\"", "test.toml:2: unknown action \"windw.kill\""},
+		{"[keybindings]\n\"Hyper+j\" = \"window.kill\"", "test.toml:2: unknown modifier \"Hyper\""},
+		{"[keybindings]\n\"Mod2+j\" = \"window.kill\"", "test.toml:2: Mod2 is num lock and can't be used in bindings in \"Mod2+j\""},
+		{"[behavior]\nmod_key = \"Mod2\"", "test.toml:2: Mod2 is num lock and can't be used in bindings"},
+		{"[keybindings]\n\"Super+nokey\" = \"window.kill\"", "test.toml:2: unknown key \"nokey\""},
+		{"[keybindings]\n\"Super+1\" = \"workspace.switch 10\"", "test.toml:2: workspace.switch needs a workspace number 1-9"},
+		{"[[rule]]\nfloating = true", "test.toml:1: rule needs a class"},
+		{"[[rule]]\nclass = \"x\"\nworkspace = 0", "test.toml:3: 0 is out of range (1-9)"},
+		{"[rule]\nclass = \"x\"", "test.toml:1: rule must be written as [[rule]]"},
+		{"[startup]\nautostart_once = [\"a\", 1]", "test.toml:2: expected a string"},
+		{"a = 1\nb = ", "test.toml:2: expected a value"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseConfig("test.toml", tt.src)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%q: error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestConfigFileAppliesToWM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	src := "[appearance]\nborder_width = 5\n\n[keybindings]\n\"Super+Shift+j\" = \"window.focus-next\"\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wm, f := newTestWM(t)
+	wm.setConfig(cfg)
+	wm.SetupKeybindings()
+	wm.grabKeys()
+
+	first := mapClient(wm, f)
+	mapClient(wm, f)
+	if got := f.window(first).borderWidth; got != 5 {
+		t.Errorf("border width = %d", got)
+	}
+
+	wm.handleKeyPress(xproto.KeyPressEvent{
+		Detail: f.keycode(XK_j),
+		State:  xproto.ModMask4 | xproto.ModMaskShift,
+	})
+	if wm.focused == nil || wm.focused.Window != first {
+		t.Errorf("Super+Shift+j rebound to focus-next did not focus: %v", wm.focused)
+	}
+}
+
+func TestExampleConfigLoads(t *testing.T) {
+	if _, err := LoadConfig("config.example.toml"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.configPath = filepath.Join(t.TempDir(), "config.toml")
+	ipc := &IPCServer{wm: wm}
+
+	first := mapClient(wm, f)
+	second := mapClient(wm, f)
+
+	src := `
+[appearance]
+border_width = 6
+inner_gap = 0
+outer_gap = 0
+focused_border_color = "#00ff00"
+
+[keybindings]
+"Super+z" = "window.focus-next"
+`
+	if err := os.WriteFile(wm.configPath, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if resp := ipc.handleCommand("config reload"); !resp.Success {
+		t.Fatalf("reload failed: %s", resp.Message)
+	}
+
+	for _, win := range []xproto.Window{first, second} {
+		if got := f.window(win).borderWidth; got != 6 {
+			t.Errorf("window %d border width = %d", win, got)
+		}
+	}
+	if got := f.window(second).borderPixel; got != 0x00ff00 {
+		t.Errorf("focused border = %#x", got)
+	}
+	// Retiled without gaps: 1920 split in two, minus 6px borders
+	if got := f.window(first).geom; got != (Rect{Width: 948, Height: 1068}) {
+		t.Errorf("master geometry = %+v", got)
+	}
+	grabbed := false
+	for _, g := range f.keyGrabs {
+		if g.mod == xproto.ModMask4 && g.detail == byte(f.keycode(XK_z)) {
+			grabbed = true
+		}
+	}
+	if !grabbed {
+		t.Error("new binding Super+z not grabbed")
+	}
+
+	// An invalid file is rejected and the running config kept
+	if err := os.WriteFile(wm.configPath, []byte("[appearance]\nborder_width = -1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resp := ipc.handleCommand("config reload")
+	if resp.Success || !strings.Contains(resp.Message, "config.toml:2:") {
+		t.Errorf("invalid reload response = %+v", resp)
+	}
+	if wm.config.BorderWidth != 6 || f.window(first).borderWidth != 6 {
+		t.Error("invalid config replaced the running one")
+	}
+}
+
+func TestParseConfigSubmaps(t *testing.T) {
+	cfg, err := parseConfig("test.toml", `
+[keybindings]
+"Mod+a" = "submap launch"
+
+[submap.launch]
+timeout = 1500
+
+[submap.launch.keys]
+"f" = "spawn firefox"
+"Shift+t" = "submap resize"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := cfg.Submaps["launch"]
+	if sm == nil || sm.Timeout != 1500*time.Millisecond || sm.Sticky || len(sm.Bindings) != 2 {
+		t.Fatalf("launch submap = %+v", sm)
+	}
+	if sm.Bindings[1].Mod != xproto.ModMaskShift || sm.Bindings[1].Keysym != XK_t {
+		t.Errorf("binding = %+v", sm.Bindings[1])
+	}
+	if cfg.Submaps["resize"] == nil {
+		t.Error("default resize submap dropped")
+	}
+
+	_, err = parseConfig("test.toml", "[keybindings]\n\"Mod+a\" = \"submap nope\"\n")
+	if err == nil || err.Error() != `test.toml:2: unknown submap "nope"` {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestParseConfigMouse(t *testing.T) {
+	cfg, err := parseConfig("test.toml", `
+[behavior]
+default_mouse_bindings = false
+
+[mouse.client]
+"Mod+Button1" = "mouse.move"
+"Mod+Shift+Button1" = "window.toggle-float"
+
+[mouse.root]
+"ScrollUp" = "workspace.prev"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.MouseBindings) != 3 {
+		t.Fatalf("mouse bindings = %+v", cfg.MouseBindings)
+	}
+	b := cfg.MouseBindings[1]
+	if b.Mod != xproto.ModMask4|xproto.ModMaskShift || b.Button != 1 || b.Target != MouseOnClient || b.Action == nil {
+		t.Errorf("binding = %+v", b)
+	}
+	if b := cfg.MouseBindings[2]; b.Target != MouseOnRoot || b.Button != 4 {
+		t.Errorf("root binding = %+v", b)
+	}
+
+	_, err = parseConfig("test.toml", "[mouse.root]\n\"Mod+Button1\" = \"mouse.move\"\n")
+	if err == nil || err.Error() != "test.toml:2: mouse.move only works in [mouse.client]" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestParseConfigDisplayProfiles(t *testing.T) {
+	cfg, err := parseConfig("test.toml", `
+[[display]]
+name = "docked"
+
+[[display.output]]
+edid = "dell u2720q"
+mode = "2560x1440"
+rate = 60
+position = "0x0"
+primary = true
+
+[[display.output]]
+name = "eDP-1"
+rotation = "left"
+enabled = false
+
+[[display]]
+name = "laptop"
+
+[[display.output]]
+name = "eDP-1"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.DisplayProfiles) != 2 {
+		t.Fatalf("profiles = %+v", cfg.DisplayProfiles)
+	}
+	docked := cfg.DisplayProfiles[0]
+	want := OutputSetup{EDID: "dell u2720q", Width: 2560, Height: 1440, Rate: 60, Placed: true, Primary: true}
+	if docked.Name != "docked" || len(docked.Outputs) != 2 || docked.Outputs[0] != want {
+		t.Errorf("docked = %+v", docked)
+	}
+	if o := docked.Outputs[1]; !o.Off || o.Rotation != RotateLeft {
+		t.Errorf("eDP-1 = %+v", o)
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"[[display]]\nname = \"x\"", "test.toml:1: display profile needs a name"},
+		{"[[display]]\nname = \"x\"\n[[display.output]]\nmode = \"1920x1080\"", "test.toml:3: display output needs a name or edid"},
+		{"[[display]]\nname = \"x\"\n[[display.output]]\nname = \"a\"\nmode = \"big\"", "test.toml:5: expected WIDTHxHEIGHT"},
+		{"[[display]]\nname = \"x\"\n[[display.output]]\nname = \"a\"\nrotation = \"up\"", "test.toml:5: rotation must be"},
+	}
+	for _, tt := range tests {
+		_, err := parseConfig("test.toml", tt.src)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%q: error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestParseConfigSplits(t *testing.T) {
+	cfg, err := parseConfig("test.toml", `
+[[split]]
+monitor = "DP-1"
+sizes = [25, 50, 25]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.MonitorSplits) != 1 || cfg.MonitorSplits[0].Monitor != "DP-1" ||
+		!slices.Equal(cfg.MonitorSplits[0].Sizes, []int{25, 50, 25}) {
+		t.Errorf("splits = %+v", cfg.MonitorSplits)
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"[[split]]\nsizes = [50, 50]", "test.toml:1: split needs a monitor and sizes"},
+		{"[[split]]\nmonitor = \"a\"\nsizes = [100]", "test.toml:3: sizes must list at least two percentages"},
+		{"[[split]]\nmonitor = \"a\"\nsizes = [50, 40]", "test.toml:3: sizes add up to 90%, not 100%"},
+		{"[[split]]\nmonitor = \"a\"\nsizes = [100, 0]", "test.toml:3: 100 is out of range (1-99)"},
+		{"[[split]]\nmonitor = \"a\"\nsizes = [50, 50]\n[[split]]\nmonitor = \"a\"\nsizes = [50, 50]", "test.toml:4: monitor \"a\" split twice"},
+	}
+	for _, tt := range tests {
+		_, err := parseConfig("test.toml", tt.src)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%q: error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestParseConfigSmartModes(t *testing.T) {
+	cfg, err := parseConfig("test.toml", `
+[appearance]
+smart_gaps = "single"
+smart_borders = "on"
+
+[workspace.3]
+smart_borders = "off"
+inner_gap = 10
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SmartGaps != SmartSingle || cfg.SmartBorders != SmartOn {
+		t.Errorf("smart gaps, borders = %v, %v", cfg.SmartGaps, cfg.SmartBorders)
+	}
+	ws := cfg.WorkspaceSettings[2]
+	if ws.SmartGaps != nil || ws.SmartBorders == nil || *ws.SmartBorders != SmartOff {
+		t.Errorf("workspace 3 settings = %+v", ws)
+	}
+	if ws.InnerGap == nil || *ws.InnerGap != 10 || ws.OuterGap != nil {
+		t.Errorf("workspace 3 gaps = %v, %v", ws.OuterGap, ws.InnerGap)
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"[appearance]\nsmart_gaps = \"always\"", "test.toml:2: unknown smart mode \"always\""},
+		{"[workspace.10]\nsmart_gaps = \"on\"", "test.toml:1: unknown workspace \"10\" (1-9)"},
+		{"[workspace.1]\ngaps = 4", "test.toml:2: unknown key \"gaps\" in [workspace.1]"},
+	}
+	for _, tt := range tests {
+		_, err := parseConfig("test.toml", tt.src)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%q: error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Output is a video connector reported by RandR
+type Output struct {
+	Name      string
+	Connected bool
+	EDID      string       // Monitor description from its EDID, e.g. "GSM LG ULTRAGEAR 104NTABC"
+	Modes     []OutputMode // Supported modes, preferred first
+}
+
+// OutputMode is a resolution and refresh rate an output supports
+type OutputMode struct {
+	Width, Height uint16
+	Rate          float64 // Refresh rate in Hz
+}
+
+// String formats the mode the way profiles and xrandr write it
+func (m OutputMode) String() string {
+	return fmt.Sprintf("%dx%d@%.2f", m.Width, m.Height, m.Rate)
+}
+
+// Output rotations, as in RandR
+const (
+	RotateNormal   uint16 = 1
+	RotateLeft     uint16 = 2
+	RotateInverted uint16 = 4
+	RotateRight    uint16 = 8
+)
+
+// rotationNames maps the rotation names used in profiles, which follow
+// xrandr --rotate
+var rotationNames = map[string]uint16{
+	"normal":   RotateNormal,
+	"left":     RotateLeft,
+	"inverted": RotateInverted,
+	"right":    RotateRight,
+}
+
+// OutputConfig is the state a profile puts an output in
+type OutputConfig struct {
+	Name     string
+	Enabled  bool
+	Mode     OutputMode
+	X, Y     int16
+	Rotation uint16
+	Primary  bool
+}
+
+// DisplayProfile is a named output layout. The first profile whose outputs
+// are all connected is applied at startup and whenever monitors are
+// plugged in or out.
+type DisplayProfile struct {
+	Name    string
+	Outputs []OutputSetup
+}
+
+// OutputSetup configures one output of a display profile
+type OutputSetup struct {
+	Name     string // Output name such as "DP-2"
+	EDID     string // Case-insensitive substring of the monitor's EDID description
+	Off      bool   // Turn the output off
+	Width    uint16 // Mode; zero means the preferred mode
+	Height   uint16
+	Rate     float64 // Refresh rate in Hz; zero means the fastest for the mode
+	X, Y     int16
+	Placed   bool // X and Y are set; otherwise placed right of the previous output
+	Rotation uint16
+	Primary  bool
+}
+
+// matches reports whether the setup describes output o
+func (s *OutputSetup) matches(o *Output) bool {
+	if s.Name != "" && s.Name != o.Name {
+		return false
+	}
+	if s.EDID != "" && !strings.Contains(strings.ToLower(o.EDID), strings.ToLower(s.EDID)) {
+		return false
+	}
+	return true
+}
+
+// match pairs every output of the profile with a distinct connected
+// output, or returns nil if some output is missing
+func (p *DisplayProfile) match(outputs []*Output) map[*Output]*OutputSetup {
+	assigned := make(map[*Output]*OutputSetup)
+	for i := range p.Outputs {
+		setup := &p.Outputs[i]
+		found := false
+		for _, o := range outputs {
+			if _, taken := assigned[o]; !taken && o.Connected && setup.matches(o) {
+				assigned[o] = setup
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return assigned
+}
+
+// plan works out the configuration of every output under the profile.
+// Outputs the profile does not mention are turned off.
+func (p *DisplayProfile) plan(outputs []*Output) ([]OutputConfig, error) {
+	assigned := p.match(outputs)
+	if assigned == nil {
+		return nil, fmt.Errorf("profile %q: not all of its outputs are connected", p.Name)
+	}
+
+	// Walk outputs in profile order so unplaced ones line up left to right
+	ordered := make([]*Output, 0, len(outputs))
+	for i := range p.Outputs {
+		for o, setup := range assigned {
+			if setup == &p.Outputs[i] {
+				ordered = append(ordered, o)
+			}
+		}
+	}
+	for _, o := range outputs {
+		if _, ok := assigned[o]; !ok {
+			ordered = append(ordered, o)
+		}
+	}
+
+	var configs []OutputConfig
+	var nextX int16
+	for _, o := range ordered {
+		setup, ok := assigned[o]
+		if !ok || setup.Off {
+			configs = append(configs, OutputConfig{Name: o.Name})
+			continue
+		}
+
+		mode, err := setup.pickMode(o)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %v", p.Name, err)
+		}
+		cfg := OutputConfig{
+			Name:     o.Name,
+			Enabled:  true,
+			Mode:     mode,
+			X:        setup.X,
+			Y:        setup.Y,
+			Rotation: setup.Rotation,
+			Primary:  setup.Primary,
+		}
+		if cfg.Rotation == 0 {
+			cfg.Rotation = RotateNormal
+		}
+		if !setup.Placed {
+			cfg.X, cfg.Y = nextX, 0
+		}
+		width, _ := cfg.size()
+		nextX = max(nextX, cfg.X+int16(width))
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// pickMode finds the mode the setup asks for among those o supports
+func (s *OutputSetup) pickMode(o *Output) (OutputMode, error) {
+	if len(o.Modes) == 0 {
+		return OutputMode{}, fmt.Errorf("%s has no modes", o.Name)
+	}
+	if s.Width == 0 && s.Rate == 0 {
+		return o.Modes[0], nil
+	}
+
+	width, height := s.Width, s.Height
+	if width == 0 {
+		width, height = o.Modes[0].Width, o.Modes[0].Height
+	}
+
+	var best *OutputMode
+	for i := range o.Modes {
+		m := &o.Modes[i]
+		if m.Width != width || m.Height != height {
+			continue
+		}
+		if best == nil ||
+			s.Rate == 0 && m.Rate > best.Rate ||
+			s.Rate != 0 && math.Abs(m.Rate-s.Rate) < math.Abs(best.Rate-s.Rate) {
+			best = m
+		}
+	}
+	if best == nil {
+		return OutputMode{}, fmt.Errorf("%s does not support %dx%d", o.Name, width, height)
+	}
+	if s.Rate != 0 && math.Abs(best.Rate-s.Rate) > 1 {
+		return OutputMode{}, fmt.Errorf("%s does not support %dx%d at %.2f Hz", o.Name, width, height, s.Rate)
+	}
+	return *best, nil
+}
+
+// size returns the area the output covers on the screen after rotation
+func (c OutputConfig) size() (uint16, uint16) {
+	if c.Rotation&(RotateLeft|RotateRight) != 0 {
+		return c.Mode.Height, c.Mode.Width
+	}
+	return c.Mode.Width, c.Mode.Height
+}
+
+// outputsKey identifies the set of connected monitors, to notice when one
+// is plugged in or out
+func outputsKey(outputs []*Output) string {
+	var keys []string
+	for _, o := range outputs {
+		if o.Connected {
+			keys = append(keys, o.Name+"="+o.EDID)
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// findDisplayProfile returns the configured profile called name
+func (wm *WindowManager) findDisplayProfile(name string) *DisplayProfile {
+	for _, p := range wm.config.DisplayProfiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// applyDisplayProfile sets the outputs up as the profile describes
+func (wm *WindowManager) applyDisplayProfile(p *DisplayProfile) error {
+	outputs, err := wm.x.Outputs()
+	if err != nil {
+		return err
+	}
+	configs, err := p.plan(outputs)
+	if err != nil {
+		return err
+	}
+	if err := wm.x.ApplyOutputs(configs); err != nil {
+		return fmt.Errorf("profile %q: %v", p.Name, err)
+	}
+
+	wm.displayProfile = p.Name
+	wm.outputsKey = outputsKey(outputs)
+	log.Printf("Applied display profile %q", p.Name)
+	return nil
+}
+
+// autoDisplayProfile applies the first profile matching the connected
+// monitors. It does nothing while the same monitors stay connected, so a
+// profile picked by hand is kept and applying a profile, which itself
+// causes RandR events, does not loop.
+func (wm *WindowManager) autoDisplayProfile() {
+	if len(wm.config.DisplayProfiles) == 0 {
+		return
+	}
+	outputs, err := wm.x.Outputs()
+	if err != nil {
+		log.Printf("Display profiles disabled: %v", err)
+		return
+	}
+	key := outputsKey(outputs)
+	if key == wm.outputsKey {
+		return
+	}
+	wm.outputsKey = key
+
+	for _, p := range wm.config.DisplayProfiles {
+		if p.match(outputs) == nil {
+			continue
+		}
+		if err := wm.applyDisplayProfile(p); err != nil {
+			log.Printf("Display profile failed: %v", err)
+		}
+		return
+	}
+	log.Printf("No display profile matches the connected outputs (%s)", key)
+}
+
+// ActionDisplayProfile returns an action that applies the named profile
+func ActionDisplayProfile(name string) Action {
+	return func(wm *WindowManager) {
+		p := wm.findDisplayProfile(name)
+		if p == nil {
+			log.Printf("Unknown display profile %q", name)
+			return
+		}
+		if err := wm.applyDisplayProfile(p); err != nil {
+			log.Printf("Display profile failed: %v", err)
+			spawn("notify-send -u critical 'gowm display' %s", shellQuote(err.Error()))
+		}
+	}
+}
+
+// ActionDisplayAuto applies the first profile matching the connected
+// monitors, undoing a profile picked by hand
+func ActionDisplayAuto(wm *WindowManager) {
+	wm.outputsKey = ""
+	wm.autoDisplayProfile()
+}
+
+// describeEDID summarises an EDID block as "MFG name serial", leaving out
+// what the monitor does not report
+func describeEDID(edid []byte) string {
+	if len(edid) < 128 || string(edid[:8]) != "\x00\xff\xff\xff\xff\xff\xff\x00" {
+		return ""
+	}
+
+	// Manufacturer ID: three 5-bit letters
+	id := uint16(edid[8])<<8 | uint16(edid[9])
+	parts := []string{string([]byte{
+		byte(id>>10&0x1f) + 'A' - 1,
+		byte(id>>5&0x1f) + 'A' - 1,
+		byte(id&0x1f) + 'A' - 1,
+	})}
+
+	// Name and serial come from the display descriptors
+	var name, serial string
+	for off := 54; off+18 <= 126; off += 18 {
+		d := edid[off : off+18]
+		if d[0] != 0 || d[1] != 0 {
+			continue // Detailed timing
+		}
+		text := strings.TrimSpace(strings.SplitN(string(d[5:]), "\n", 2)[0])
+		switch d[3] {
+		case 0xfc:
+			name = text
+		case 0xff:
+			serial = text
+		}
+	}
+	for _, s := range []string{name, serial} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
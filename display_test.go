@@ -0,0 +1,156 @@
+package main
+
+import (
+	"testing"
+)
+
+// testEDID builds a minimal EDID block for manufacturer "DEL" with the
+// given monitor name and serial descriptors
+func testEDID(name, serial string) []byte {
+	edid := make([]byte, 128)
+	copy(edid, "\x00\xff\xff\xff\xff\xff\xff\x00")
+	edid[8], edid[9] = 0x10, 0xac // DEL
+	descriptor := func(off int, tag byte, text string) {
+		edid[off+3] = tag
+		copy(edid[off+5:off+18], text+"\n            ")
+	}
+	descriptor(72, 0xfc, name)
+	descriptor(90, 0xff, serial)
+	return edid
+}
+
+func TestDescribeEDID(t *testing.T) {
+	if got := describeEDID(testEDID("DELL U2720Q", "7ABC123")); got != "DEL DELL U2720Q 7ABC123" {
+		t.Errorf("describeEDID = %q", got)
+	}
+	if got := describeEDID([]byte{1, 2, 3}); got != "" {
+		t.Errorf("describeEDID of garbage = %q", got)
+	}
+}
+
+// testOutputs returns a laptop panel and an external monitor, which is
+// connected if docked
+func testOutputs(docked bool) []*Output {
+	return []*Output{
+		{Name: "eDP-1", Connected: true, EDID: "BOE 0x0a9d", Modes: []OutputMode{
+			{1920, 1200, 60}, {1920, 1200, 48}, {1280, 800, 60},
+		}},
+		{Name: "DP-2", Connected: docked, EDID: "DEL DELL U2720Q 7ABC123", Modes: []OutputMode{
+			{2560, 1440, 59.95}, {1920, 1080, 60}, {1920, 1080, 165}, {1920, 1080, 144},
+		}},
+		{Name: "HDMI-1"},
+	}
+}
+
+var testProfiles = []*DisplayProfile{
+	{Name: "docked", Outputs: []OutputSetup{
+		{EDID: "u2720q", Width: 1920, Height: 1080, Primary: true},
+		{Name: "eDP-1"},
+	}},
+	{Name: "laptop", Outputs: []OutputSetup{
+		{Name: "eDP-1", Primary: true},
+	}},
+}
+
+func TestDisplayProfilePlan(t *testing.T) {
+	configs, err := testProfiles[0].plan(testOutputs(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []OutputConfig{
+		// Fastest 1920x1080 mode, then the panel's preferred mode to its right
+		{Name: "DP-2", Enabled: true, Mode: OutputMode{1920, 1080, 165}, Rotation: RotateNormal, Primary: true},
+		{Name: "eDP-1", Enabled: true, Mode: OutputMode{1920, 1200, 60}, X: 1920, Rotation: RotateNormal},
+		{Name: "HDMI-1"},
+	}
+	if len(configs) != len(want) {
+		t.Fatalf("configs = %+v", configs)
+	}
+	for i := range want {
+		if configs[i] != want[i] {
+			t.Errorf("config %d = %+v, want %+v", i, configs[i], want[i])
+		}
+	}
+
+	if _, err := testProfiles[0].plan(testOutputs(false)); err == nil {
+		t.Error("docked profile planned without its monitor")
+	}
+}
+
+func TestOutputSetupPickMode(t *testing.T) {
+	o := testOutputs(true)[1]
+	tests := []struct {
+		setup OutputSetup
+		want  OutputMode
+		err   bool
+	}{
+		{OutputSetup{}, OutputMode{2560, 1440, 59.95}, false},
+		{OutputSetup{Width: 1920, Height: 1080, Rate: 144}, OutputMode{1920, 1080, 144}, false},
+		{OutputSetup{Rate: 60}, OutputMode{2560, 1440, 59.95}, false},
+		{OutputSetup{Width: 1920, Height: 1080, Rate: 75}, OutputMode{}, true},
+		{OutputSetup{Width: 800, Height: 600}, OutputMode{}, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.setup.pickMode(o)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("pickMode(%+v) = %v, %v", tt.setup, got, err)
+		}
+	}
+}
+
+func TestDisplayProfileFollowsHotplug(t *testing.T) {
+	wm, f := newTestWM(t)
+	f.outputs = testOutputs(false)
+	wm.config.DisplayProfiles = testProfiles
+
+	wm.handleScreenChange()
+	if wm.displayProfile != "laptop" || len(f.applied) != 1 {
+		t.Fatalf("profile = %q after %d applies", wm.displayProfile, len(f.applied))
+	}
+	if len(wm.monitors) != 1 || wm.monitors[0].Name != "eDP-1" {
+		t.Errorf("monitors = %+v", wm.monitors)
+	}
+
+	// Plug in the external monitor
+	f.outputs[1].Connected = true
+	wm.handleScreenChange()
+	if wm.displayProfile != "docked" || len(wm.monitors) != 2 {
+		t.Fatalf("profile = %q with %d monitors", wm.displayProfile, len(wm.monitors))
+	}
+	if m := wm.monitors[0]; m.Name != "DP-2" || m.Geometry != (Rect{Width: 1920, Height: 1080}) {
+		t.Errorf("primary monitor = %+v", m)
+	}
+
+	// The RandR events caused by applying it don't apply it again
+	wm.handleScreenChange()
+	if len(f.applied) != 2 {
+		t.Errorf("profile applied %d times", len(f.applied))
+	}
+}
+
+func TestIPCDisplayProfile(t *testing.T) {
+	wm, f := newTestWM(t)
+	f.outputs = testOutputs(true)
+	wm.config.DisplayProfiles = testProfiles
+	ipc := &IPCServer{wm: wm}
+
+	if resp := ipc.handleCommand("display profile laptop"); !resp.Success {
+		t.Fatalf("display profile failed: %s", resp.Message)
+	}
+	if configs := f.applied[0]; !configs[0].Enabled || configs[1].Enabled {
+		t.Errorf("laptop profile applied as %+v", configs)
+	}
+	if resp := ipc.handleCommand("display profile nope"); resp.Success {
+		t.Error("unknown profile applied")
+	}
+
+	profiles := ipc.handleCommand("query profiles").Data.([]ProfileInfo)
+	if len(profiles) != 2 || profiles[0].Active || !profiles[0].Matches || !profiles[1].Active {
+		t.Errorf("profiles = %+v", profiles)
+	}
+
+	outputs := ipc.handleCommand("query outputs").Data.([]OutputInfo)
+	if len(outputs) != 3 || outputs[1].EDID != "DEL DELL U2720Q 7ABC123" || outputs[1].Modes[0] != "2560x1440@59.95" {
+		t.Errorf("outputs = %+v", outputs)
+	}
+}
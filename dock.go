@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"sort"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// Dock is a panel or bar such as eww or polybar. Docks are not managed,
+// but their struts reserve space on the monitors they sit on, for as long
+// as they stay mapped.
+type Dock struct {
+	Window xproto.Window
+	Strut  Strut
+}
+
+// trackDock starts tracking a mapped dock window. The caller updates the
+// struts and retiles.
+func (wm *WindowManager) trackDock(win xproto.Window) {
+	if _, ok := wm.docks[win]; ok {
+		return
+	}
+	wm.docks[win] = &Dock{Window: win}
+
+	// Watch for the dock changing its strut
+	wm.x.ChangeWindowAttributes(win, xproto.CwEventMask,
+		[]uint32{xproto.EventMaskPropertyChange})
+	log.Printf("Dock window detected: %d", win)
+}
+
+// untrackDock stops tracking a dock that was unmapped or destroyed and
+// gives its space back. It reports whether win was a dock.
+func (wm *WindowManager) untrackDock(win xproto.Window) bool {
+	if _, ok := wm.docks[win]; !ok {
+		return false
+	}
+	delete(wm.docks, win)
+	log.Printf("Dock window removed: %d", win)
+
+	wm.updateStruts()
+	wm.tile()
+	return true
+}
+
+// handleDockProperty retiles when a dock changes its strut
+func (wm *WindowManager) handleDockProperty(e xproto.PropertyNotifyEvent) {
+	if _, ok := wm.docks[e.Window]; !ok {
+		return
+	}
+	if e.Atom != wm.atoms.NET_WM_STRUT && e.Atom != wm.atoms.NET_WM_STRUT_PARTIAL {
+		return
+	}
+	wm.updateStruts()
+	wm.tile()
+}
+
+// sortedDocks returns the docks in a stable order
+func (wm *WindowManager) sortedDocks() []*Dock {
+	docks := make([]*Dock, 0, len(wm.docks))
+	for _, d := range wm.docks {
+		docks = append(docks, d)
+	}
+	sort.Slice(docks, func(i, j int) bool { return docks[i].Window < docks[j].Window })
+	return docks
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// mapDock maps a dock window with the given _NET_WM_STRUT_PARTIAL, or
+// _NET_WM_STRUT if only four values are given
+func mapDock(wm *WindowManager, f *fakeBackend, strut ...uint32) xproto.Window {
+	win := f.addWindow(Rect{Width: 100, Height: 30})
+	f.setCardinals(win, f.atom("_NET_WM_WINDOW_TYPE"), xproto.AtomAtom,
+		uint32(f.atom("_NET_WM_WINDOW_TYPE_DOCK")))
+	prop := "_NET_WM_STRUT_PARTIAL"
+	if len(strut) == 4 {
+		prop = "_NET_WM_STRUT"
+	}
+	f.setCardinals(win, f.atom(prop), xproto.AtomCardinal, strut...)
+	wm.handleMapRequest(xproto.MapRequestEvent{Parent: f.root, Window: win})
+	return win
+}
+
+func TestDockUnmapGivesSpaceBack(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	dock := mapDock(wm, f, 0, 0, 30, 0)
+	win := mapClient(wm, f)
+	if got := f.window(win).geom; got.Y != 38 {
+		t.Fatalf("window below the bar at y=%d, want 38", got.Y)
+	}
+
+	f.UnmapWindow(dock)
+	wm.handleUnmapNotify(xproto.UnmapNotifyEvent{Event: f.root, Window: dock})
+
+	if len(wm.docks) != 0 {
+		t.Error("unmapped dock still tracked")
+	}
+	if got := f.window(win).geom; got.Y != 8 {
+		t.Errorf("window at y=%d after the bar went away, want 8", got.Y)
+	}
+}
+
+func TestDockDestroyGivesSpaceBack(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	dock := mapDock(wm, f, 0, 0, 30, 0)
+	wm.handleDestroyNotify(xproto.DestroyNotifyEvent{Event: f.root, Window: dock})
+
+	if len(wm.docks) != 0 || wm.monitors[0].Struts != [4]uint32{} {
+		t.Errorf("destroyed dock still reserves %v", wm.monitors[0].Struts)
+	}
+}
+
+func TestDockStrutChange(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	dock := mapDock(wm, f, 0, 0, 30, 0)
+	win := mapClient(wm, f)
+
+	// The bar moves to the bottom and grows
+	strut := f.atom("_NET_WM_STRUT")
+	f.setCardinals(dock, strut, xproto.AtomCardinal, 0, 0, 0, 40)
+	wm.handlePropertyNotify(xproto.PropertyNotifyEvent{Window: dock, Atom: strut})
+
+	if got := wm.monitors[0].Struts; got != [4]uint32{0, 0, 0, 40} {
+		t.Errorf("struts = %v after the change", got)
+	}
+	if got := f.window(win).geom; got.Y != 8 || got.Height != 1020 {
+		t.Errorf("window not retiled: %+v", got)
+	}
+}
+
+func TestScanTracksDocks(t *testing.T) {
+	f := newFakeBackend(1920, 1080)
+	dock := f.addWindow(Rect{Width: 1920, Height: 30})
+	f.setCardinals(dock, f.atom("_NET_WM_WINDOW_TYPE"), xproto.AtomAtom,
+		uint32(f.atom("_NET_WM_WINDOW_TYPE_DOCK")))
+	f.setCardinals(dock, f.atom("_NET_WM_STRUT"), xproto.AtomCardinal, 0, 0, 30, 0)
+	f.MapWindow(dock)
+
+	wm, _ := startTestWM(t, f)
+
+	if _, managed := wm.clients[dock]; managed {
+		t.Error("dock managed as a client")
+	}
+	if _, ok := wm.docks[dock]; !ok {
+		t.Fatal("dock not tracked")
+	}
+	if got := wm.monitors[0].Struts; got != [4]uint32{0, 0, 30, 0} {
+		t.Errorf("struts = %v", got)
+	}
+}
+
+func TestIPCQueryDocks(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+	ipc := &IPCServer{wm: wm}
+
+	dock := mapDock(wm, f, 0, 0, 30, 0, 0, 0, 0, 0, 1920, 3199, 0, 0)
+
+	resp := ipc.handleCommand("query docks")
+	if !resp.Success {
+		t.Fatalf("query docks failed: %s", resp.Message)
+	}
+	docks := resp.Data.([]DockInfo)
+	if len(docks) != 1 {
+		t.Fatalf("got %d docks", len(docks))
+	}
+	d := docks[0]
+	if d.ID != uint32(dock) || d.Top != 30 || len(d.Monitors) != 1 || d.Monitors[0] != "HDMI-1" {
+		t.Errorf("dock = %+v", d)
+	}
+}
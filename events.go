@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// eventTopics are the event kinds a subscriber can ask for
+var eventTopics = []string{
+	"workspace",  // Workspace switched
+	"window",     // Window managed or unmanaged
+	"focus",      // Focused window changed
+	"title",      // Window title changed
+	"layout",     // Workspace layout changed
+	"urgent",     // Window urgency set or cleared
+	"fullscreen", // Window entered or left fullscreen
+	"mode",       // Key submap entered or left
+}
+
+// IPCEvent is one line of a subscription stream
+type IPCEvent struct {
+	Event     string      `json:"event"`
+	Change    string      `json:"change,omitempty"`
+	Workspace int         `json:"workspace,omitempty"`
+	Name      string      `json:"name,omitempty"`
+	Layout    string      `json:"layout,omitempty"`
+	Mode      string      `json:"mode,omitempty"`
+	Window    *WindowInfo `json:"window,omitempty"`
+}
+
+// subscriber is a connection streaming events
+type subscriber struct {
+	topics map[string]bool
+	events chan []byte
+}
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// it is dropped
+const subscriberBuffer = 64
+
+// parseTopics parses the topics of a subscribe command. No topics, or
+// "all", subscribes to everything.
+func parseTopics(args []string) (map[string]bool, error) {
+	topics := make(map[string]bool)
+	for _, arg := range args {
+		for _, topic := range strings.Split(arg, ",") {
+			topic = strings.ToLower(strings.TrimSpace(topic))
+			switch {
+			case topic == "":
+			case topic == "all":
+				for _, t := range eventTopics {
+					topics[t] = true
+				}
+			case containsString(eventTopics, topic):
+				topics[topic] = true
+			default:
+				return nil, fmt.Errorf("unknown topic %q (topics: %s)", topic, strings.Join(eventTopics, ", "))
+			}
+		}
+	}
+	if len(topics) == 0 {
+		return parseTopics([]string{"all"})
+	}
+	return topics, nil
+}
+
+// serveSubscription keeps a connection open and streams the events it
+// subscribed to as JSON lines, after a first line acknowledging the
+// subscription. It returns once the client has disconnected.
+func (ipc *IPCServer) serveSubscription(conn net.Conn, args []string) {
+	topics, err := parseTopics(args)
+	if err != nil {
+		writeJSONLine(conn, IPCResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	sub := &subscriber{topics: topics, events: make(chan []byte, subscriberBuffer)}
+	ipc.subsMu.Lock()
+	if ipc.subscribers == nil {
+		ipc.subscribers = make(map[*subscriber]bool)
+	}
+	ipc.subscribers[sub] = true
+	ipc.subsMu.Unlock()
+	defer ipc.unsubscribe(sub)
+
+	var names []string
+	for _, t := range eventTopics {
+		if topics[t] {
+			names = append(names, t)
+		}
+	}
+	if err := writeJSONLine(conn, IPCResponse{Success: true, Message: "subscribed to " + strings.Join(names, ", ")}); err != nil {
+		return
+	}
+
+	// A client that went away is noticed by the next failed write. Reading
+	// for EOF instead would drop clients like nc that half-close after
+	// sending the command.
+	for data := range sub.events {
+		if _, err := conn.Write(data); err != nil {
+			return
+		}
+	}
+}
+
+// unsubscribe removes a subscriber and ends its stream
+func (ipc *IPCServer) unsubscribe(sub *subscriber) {
+	ipc.subsMu.Lock()
+	defer ipc.subsMu.Unlock()
+	if ipc.subscribers[sub] {
+		delete(ipc.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// subscribed reports whether anyone subscribed to a topic
+func (ipc *IPCServer) subscribed(topic string) bool {
+	ipc.subsMu.Lock()
+	defer ipc.subsMu.Unlock()
+	for sub := range ipc.subscribers {
+		if sub.topics[topic] {
+			return true
+		}
+	}
+	return false
+}
+
+// publish sends an event to every subscriber of its topic. Subscribers that
+// stopped reading are dropped rather than blocking the event loop.
+func (ipc *IPCServer) publish(ev IPCEvent) {
+	ipc.subsMu.Lock()
+	defer ipc.subsMu.Unlock()
+	if len(ipc.subscribers) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("IPC event: %v", err)
+		return
+	}
+	data = append(data, '\n')
+
+	for sub := range ipc.subscribers {
+		if !sub.topics[ev.Event] {
+			continue
+		}
+		select {
+		case sub.events <- data:
+		default:
+			log.Println("IPC subscriber is not reading, dropping it")
+			delete(ipc.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// writeJSONLine writes v as a single JSON line
+func writeJSONLine(conn net.Conn, v interface{}) error {
+	data, _ := json.Marshal(v)
+	_, err := conn.Write(append(data, '\n'))
+	return err
+}
+
+// emit publishes an event to IPC subscribers
+func (wm *WindowManager) emit(ev IPCEvent) {
+	if wm.ipc != nil {
+		wm.ipc.publish(ev)
+	}
+}
+
+// emitWindow publishes an event about a client window
+func (wm *WindowManager) emitWindow(event, change string, c *Client) {
+	// Describing the window asks X, so skip it when nobody listens
+	if wm.ipc == nil || !wm.ipc.subscribed(event) {
+		return
+	}
+	info := wm.windowInfo(c)
+	wm.emit(IPCEvent{Event: event, Change: change, Workspace: c.Workspace + 1, Window: &info})
+}
+
+// emitLayout publishes the layout of a workspace
+func (wm *WindowManager) emitLayout(ws *Workspace) {
+	wm.emit(IPCEvent{Event: "layout", Workspace: ws.ID + 1, Layout: ws.Layout.Name()})
+}
+
+// windowInfo describes a client for IPC
+func (wm *WindowManager) windowInfo(c *Client) WindowInfo {
+	return WindowInfo{
+		ID:         uint32(c.Window),
+		Title:      c.Title,
+		Class:      c.Class,
+		Workspace:  c.Workspace + 1,
+		Floating:   c.Floating,
+		Focused:    c == wm.focused,
+		Urgent:     c.Urgent,
+		Fullscreen: wm.hasFullscreenState(c.Window),
+	}
+}
+
+// updateClientNames reads a client's title and class into its cache
+func (wm *WindowManager) updateClientNames(c *Client) {
+	c.Title = wm.getWindowTitle(c.Window)
+	c.Class = wm.getWMClass(c.Window)
+}
+
+// isTitleAtom reports whether a property change renames a window
+func (wm *WindowManager) isTitleAtom(atom xproto.Atom) bool {
+	return atom == wm.atoms.NET_WM_NAME || atom == xproto.AtomWmName
+}
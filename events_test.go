@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// subscribe opens a subscription over an in-memory connection and returns
+// a reader for its stream, positioned after the acknowledgement
+func subscribe(t *testing.T, wm *WindowManager, topics string) (*bufio.Reader, net.Conn) {
+	t.Helper()
+	if wm.ipc == nil {
+		wm.ipc = &IPCServer{wm: wm}
+	}
+
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	go wm.ipc.handleConnection(server)
+
+	client.SetDeadline(time.Now().Add(time.Second))
+	client.Write([]byte("subscribe " + topics + "\n"))
+	r := bufio.NewReader(client)
+
+	var resp IPCResponse
+	line, err := r.ReadBytes('\n')
+	if err != nil || json.Unmarshal(line, &resp) != nil || !resp.Success {
+		t.Fatalf("subscribe %s: %s %v", topics, line, err)
+	}
+	return r, client
+}
+
+// readEvent reads the next event from a subscription stream
+func readEvent(t *testing.T, r *bufio.Reader) IPCEvent {
+	t.Helper()
+	var ev IPCEvent
+	line, err := r.ReadBytes('\n')
+	if err != nil || json.Unmarshal(line, &ev) != nil {
+		t.Fatalf("reading event: %s %v", line, err)
+	}
+	return ev
+}
+
+func TestSubscribeStreamsEvents(t *testing.T) {
+	wm, f := newTestWM(t)
+	r, _ := subscribe(t, wm, "workspace,focus window")
+
+	win := mapClient(wm, f)
+	// The same focus is reported once even though tiling refocuses
+	if ev := readEvent(t, r); ev.Event != "focus" || ev.Window == nil || ev.Window.ID != uint32(win) {
+		t.Fatalf("first event = %+v", ev)
+	}
+	if ev := readEvent(t, r); ev.Event != "window" || ev.Change != "manage" || ev.Window.ID != uint32(win) {
+		t.Fatalf("second event = %+v", ev)
+	}
+
+	wm.enterSubmap("resize") // not subscribed
+	wm.exitSubmap()
+	wm.switchToWorkspace(2)
+	if ev := readEvent(t, r); ev.Event != "focus" || ev.Window != nil {
+		t.Errorf("empty workspace focus event = %+v", ev)
+	}
+	if ev := readEvent(t, r); ev.Event != "workspace" || ev.Workspace != 3 || ev.Name != "3" {
+		t.Errorf("workspace event = %+v", ev)
+	}
+}
+
+func TestSubscribeTitleAndUrgency(t *testing.T) {
+	wm, f := newTestWM(t)
+	first := mapClient(wm, f)
+	mapClient(wm, f)
+	r, _ := subscribe(t, wm, "title urgent")
+
+	f.window(first).props[wm.atoms.NET_WM_NAME] = fakeProperty{typ: wm.atoms.UTF8_STRING, format: 8, data: []byte("vim")}
+	wm.handlePropertyNotify(xproto.PropertyNotifyEvent{Window: first, Atom: wm.atoms.NET_WM_NAME})
+	if ev := readEvent(t, r); ev.Event != "title" || ev.Window.Title != "vim" {
+		t.Errorf("title event = %+v", ev)
+	}
+
+	wm.focus(wm.clients[first])
+	wm.clients[first].Urgent = true
+	wm.focus(wm.clients[first])
+	if ev := readEvent(t, r); ev.Event != "urgent" || ev.Window.Urgent {
+		t.Errorf("urgency cleared event = %+v", ev)
+	}
+}
+
+func TestUnmanageEventNamesDestroyedWindow(t *testing.T) {
+	wm, f := newTestWM(t)
+	win := f.addWindow(Rect{Width: 100, Height: 100})
+	f.setClass(win, "kitty", "kitty")
+	f.window(win).props[wm.atoms.NET_WM_NAME] = fakeProperty{typ: wm.atoms.UTF8_STRING, format: 8, data: []byte("shell")}
+	wm.handleMapRequest(xproto.MapRequestEvent{Parent: f.root, Window: win})
+	r, _ := subscribe(t, wm, "window")
+
+	// The window is gone by the time gowm hears of it
+	f.destroyWindow(win)
+	wm.handleDestroyNotify(xproto.DestroyNotifyEvent{Event: f.root, Window: win})
+	ev := readEvent(t, r)
+	if ev.Change != "unmanage" || ev.Window.Title != "shell" || ev.Window.Class != "kitty" {
+		t.Errorf("unmanage event = %+v %+v", ev, ev.Window)
+	}
+}
+
+func TestSubscribeRejectsUnknownTopic(t *testing.T) {
+	wm, _ := newTestWM(t)
+	wm.ipc = &IPCServer{wm: wm}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go wm.ipc.handleConnection(server)
+	client.SetDeadline(time.Now().Add(time.Second))
+	client.Write([]byte("subscribe workspaces\n"))
+
+	var resp IPCResponse
+	line, _ := bufio.NewReader(client).ReadBytes('\n')
+	if json.Unmarshal(line, &resp) != nil || resp.Success {
+		t.Errorf("response = %s", line)
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	ipc := &IPCServer{}
+	sub := &subscriber{topics: map[string]bool{"mode": true}, events: make(chan []byte, subscriberBuffer)}
+	ipc.subscribers = map[*subscriber]bool{sub: true}
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		ipc.publish(IPCEvent{Event: "mode", Mode: "default"})
+	}
+	if len(ipc.subscribers) != 0 {
+		t.Error("subscriber that stopped reading was kept")
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"encoding/binary"
+	"log"
 
 	"github.com/jezek/xgb/xproto"
 )
@@ -23,28 +25,25 @@ const (
 // setupEWMH sets up EWMH support
 func (wm *WindowManager) setupEWMH() {
 	// Create a supporting window for EWMH compliance
-	wm.wmCheckWin, _ = xproto.NewWindowId(wm.conn)
-	xproto.CreateWindow(wm.conn, 0, wm.wmCheckWin, wm.root,
-		0, 0, 1, 1, 0,
-		xproto.WindowClassInputOnly,
-		wm.screen.RootVisual,
-		0, nil)
+	win, err := wm.x.CreateWindow(wm.root, Rect{Width: 1, Height: 1}, 0,
+		xproto.WindowClassInputOnly, 0, nil)
+	if err != nil {
+		log.Printf("Failed to create EWMH check window: %v", err)
+	}
+	wm.wmCheckWin = win
 
 	// Set WM name on check window
 	wmName := "gowm"
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, wm.wmCheckWin,
-		wm.atoms.NET_WM_NAME, wm.atoms.UTF8_STRING, 8,
-		uint32(len(wmName)), []byte(wmName))
+	wm.x.ChangeProperty(wm.wmCheckWin,
+		wm.atoms.NET_WM_NAME, wm.atoms.UTF8_STRING, 8, []byte(wmName))
 
 	// Set _NET_SUPPORTING_WM_CHECK on both root and check window
 	data := make([]byte, 4)
 	binary.LittleEndian.PutUint32(data, uint32(wm.wmCheckWin))
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, wm.root,
-		wm.atoms.NET_SUPPORTING_WM_CHECK, xproto.AtomWindow, 32,
-		1, data)
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, wm.wmCheckWin,
-		wm.atoms.NET_SUPPORTING_WM_CHECK, xproto.AtomWindow, 32,
-		1, data)
+	wm.x.ChangeProperty(wm.root,
+		wm.atoms.NET_SUPPORTING_WM_CHECK, xproto.AtomWindow, 32, data)
+	wm.x.ChangeProperty(wm.wmCheckWin,
+		wm.atoms.NET_SUPPORTING_WM_CHECK, xproto.AtomWindow, 32, data)
 
 	// Set _NET_SUPPORTED
 	supported := []xproto.Atom{
@@ -61,15 +60,15 @@ func (wm *WindowManager) setupEWMH() {
 		wm.atoms.NET_WM_STATE,
 		wm.atoms.NET_WM_STATE_FULLSCREEN,
 		wm.atoms.NET_WM_STRUT_PARTIAL,
+		wm.atoms.NET_WORKAREA,
 		wm.atoms.NET_CLOSE_WINDOW,
 	}
 	data = make([]byte, len(supported)*4)
 	for i, atom := range supported {
 		binary.LittleEndian.PutUint32(data[i*4:], uint32(atom))
 	}
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, wm.root,
-		wm.atoms.NET_SUPPORTED, xproto.AtomAtom, 32,
-		uint32(len(supported)), data)
+	wm.x.ChangeProperty(wm.root,
+		wm.atoms.NET_SUPPORTED, xproto.AtomAtom, 32, data)
 
 	// Set _NET_NUMBER_OF_DESKTOPS
 	wm.updateDesktopCount()
@@ -92,27 +91,51 @@ func (wm *WindowManager) updateClientList() {
 	for i, win := range windows {
 		binary.LittleEndian.PutUint32(data[i*4:], uint32(win))
 	}
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, wm.root,
-		wm.atoms.NET_CLIENT_LIST, xproto.AtomWindow, 32,
-		uint32(len(windows)), data)
+	wm.x.ChangeProperty(wm.root,
+		wm.atoms.NET_CLIENT_LIST, xproto.AtomWindow, 32, data)
 }
 
 // updateDesktopCount updates _NET_NUMBER_OF_DESKTOPS
 func (wm *WindowManager) updateDesktopCount() {
 	data := make([]byte, 4)
 	binary.LittleEndian.PutUint32(data, uint32(len(wm.workspaces)))
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, wm.root,
-		wm.atoms.NET_NUMBER_OF_DESKTOPS, xproto.AtomCardinal, 32,
-		1, data)
+	wm.x.ChangeProperty(wm.root,
+		wm.atoms.NET_NUMBER_OF_DESKTOPS, xproto.AtomCardinal, 32, data)
 }
 
 // updateCurrentDesktop updates _NET_CURRENT_DESKTOP
 func (wm *WindowManager) updateCurrentDesktop() {
 	data := make([]byte, 4)
 	binary.LittleEndian.PutUint32(data, uint32(wm.current))
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, wm.root,
-		wm.atoms.NET_CURRENT_DESKTOP, xproto.AtomCardinal, 32,
-		1, data)
+	wm.x.ChangeProperty(wm.root,
+		wm.atoms.NET_CURRENT_DESKTOP, xproto.AtomCardinal, 32, data)
+
+	ws := wm.currentWorkspace()
+	wm.emit(IPCEvent{Event: "workspace", Workspace: ws.ID + 1, Name: ws.Name})
+}
+
+// updateWorkarea updates _NET_WORKAREA with the work area of the monitor
+// showing each desktop. Hidden desktops get the primary monitor's.
+func (wm *WindowManager) updateWorkarea() {
+	data := make([]byte, len(wm.workspaces)*16)
+	for i := range wm.workspaces {
+		m := wm.monitorShowing(i)
+		if m == nil {
+			m = wm.monitors[0]
+		}
+		area := wm.workArea(m)
+		for j, v := range []uint32{uint32(area.X), uint32(area.Y), uint32(area.Width), uint32(area.Height)} {
+			binary.LittleEndian.PutUint32(data[i*16+j*4:], v)
+		}
+	}
+
+	// Only write on change, as tiling calls this often
+	if bytes.Equal(data, wm.workarea) {
+		return
+	}
+	wm.workarea = data
+	wm.x.ChangeProperty(wm.root,
+		wm.atoms.NET_WORKAREA, xproto.AtomCardinal, 32, data)
 }
 
 // updateDesktopNames updates _NET_DESKTOP_NAMES
@@ -123,9 +146,8 @@ func (wm *WindowManager) updateDesktopNames() {
 		names = append(names, []byte(ws.Name)...)
 		names = append(names, 0)
 	}
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, wm.root,
-		wm.atoms.NET_DESKTOP_NAMES, wm.atoms.UTF8_STRING, 8,
-		uint32(len(names)), names)
+	wm.x.ChangeProperty(wm.root,
+		wm.atoms.NET_DESKTOP_NAMES, wm.atoms.UTF8_STRING, 8, names)
 }
 
 // updateActiveWindow updates _NET_ACTIVE_WINDOW
@@ -136,25 +158,34 @@ func (wm *WindowManager) updateActiveWindow() {
 	}
 	data := make([]byte, 4)
 	binary.LittleEndian.PutUint32(data, uint32(win))
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, wm.root,
-		wm.atoms.NET_ACTIVE_WINDOW, xproto.AtomWindow, 32,
-		1, data)
+	wm.x.ChangeProperty(wm.root,
+		wm.atoms.NET_ACTIVE_WINDOW, xproto.AtomWindow, 32, data)
+
+	// focus() runs on every retile, so only report actual changes
+	if win == wm.activeWindow {
+		return
+	}
+	wm.activeWindow = win
+	if wm.focused != nil {
+		wm.emitWindow("focus", "", wm.focused)
+	} else {
+		wm.emit(IPCEvent{Event: "focus"})
+	}
 }
 
 // setClientDesktop sets _NET_WM_DESKTOP for a client
 func (wm *WindowManager) setClientDesktop(c *Client) {
 	data := make([]byte, 4)
 	binary.LittleEndian.PutUint32(data, uint32(c.Workspace))
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, c.Window,
-		wm.atoms.NET_WM_DESKTOP, xproto.AtomCardinal, 32,
-		1, data)
+	wm.x.ChangeProperty(c.Window,
+		wm.atoms.NET_WM_DESKTOP, xproto.AtomCardinal, 32, data)
 }
 
 // getClientDesktop reads _NET_WM_DESKTOP from a window (for WM restart)
 func (wm *WindowManager) getClientDesktop(win xproto.Window) int {
-	prop, err := xproto.GetProperty(wm.conn, false, win,
+	prop, err := wm.x.GetProperty(win,
 		wm.atoms.NET_WM_DESKTOP, xproto.AtomCardinal,
-		0, 1).Reply()
+		0, 1)
 
 	if err != nil || prop == nil || prop.ValueLen == 0 {
 		return -1
@@ -168,19 +199,22 @@ func (wm *WindowManager) setFullscreenState(win xproto.Window, fullscreen bool)
 	if fullscreen {
 		data := make([]byte, 4)
 		binary.LittleEndian.PutUint32(data, uint32(wm.atoms.NET_WM_STATE_FULLSCREEN))
-		xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, win,
-			wm.atoms.NET_WM_STATE, xproto.AtomAtom, 32,
-			1, data)
+		wm.x.ChangeProperty(win,
+			wm.atoms.NET_WM_STATE, xproto.AtomAtom, 32, data)
 	} else {
-		xproto.DeleteProperty(wm.conn, win, wm.atoms.NET_WM_STATE)
+		wm.x.DeleteProperty(win, wm.atoms.NET_WM_STATE)
+	}
+
+	if c, ok := wm.clients[win]; ok {
+		wm.emitWindow("fullscreen", "", c)
 	}
 }
 
 // getWindowType returns the EWMH window type
 func (wm *WindowManager) getWindowType(win xproto.Window) WindowType {
-	prop, err := xproto.GetProperty(wm.conn, false, win,
+	prop, err := wm.x.GetProperty(win,
 		wm.atoms.NET_WM_WINDOW_TYPE, xproto.AtomAtom,
-		0, 32).Reply()
+		0, 32)
 
 	if err != nil || prop == nil || prop.ValueLen == 0 {
 		return WindowTypeNormal
@@ -210,18 +244,18 @@ func (wm *WindowManager) getWindowType(win xproto.Window) WindowType {
 
 // isTransient checks if the window is transient (a dialog)
 func (wm *WindowManager) isTransient(win xproto.Window) bool {
-	prop, err := xproto.GetProperty(wm.conn, false, win,
+	prop, err := wm.x.GetProperty(win,
 		xproto.AtomWmTransientFor, xproto.AtomWindow,
-		0, 1).Reply()
+		0, 1)
 
 	return err == nil && prop != nil && prop.ValueLen > 0
 }
 
 // hasFullscreenState checks if a window has _NET_WM_STATE_FULLSCREEN set
 func (wm *WindowManager) hasFullscreenState(win xproto.Window) bool {
-	prop, err := xproto.GetProperty(wm.conn, false, win,
+	prop, err := wm.x.GetProperty(win,
 		wm.atoms.NET_WM_STATE, xproto.AtomAtom,
-		0, 32).Reply()
+		0, 32)
 
 	if err != nil || prop == nil || prop.ValueLen == 0 {
 		return false
@@ -256,9 +290,9 @@ func (wm *WindowManager) shouldFloat(win xproto.Window) bool {
 
 // getWMClass returns the WM_CLASS instance name
 func (wm *WindowManager) getWMClass(win xproto.Window) string {
-	prop, err := xproto.GetProperty(wm.conn, false, win,
+	prop, err := wm.x.GetProperty(win,
 		xproto.AtomWmClass, xproto.AtomString,
-		0, 256).Reply()
+		0, 256)
 
 	if err != nil || prop == nil || prop.ValueLen == 0 {
 		return ""
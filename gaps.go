@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// SmartMode says when smart gaps or smart borders leave gaps or borders
+// out
+type SmartMode uint8
+
+const (
+	SmartSingle  SmartMode = 1 << iota // One tiled window on the monitor
+	SmartMonocle                       // A monocle layout such as full
+
+	SmartOff SmartMode = 0
+	SmartOn            = SmartSingle | SmartMonocle
+)
+
+// smartModeNames maps the smart mode names used in the config
+var smartModeNames = map[string]SmartMode{
+	"off":     SmartOff,
+	"single":  SmartSingle,
+	"monocle": SmartMonocle,
+	"on":      SmartOn,
+}
+
+// String returns the config name of the mode
+func (m SmartMode) String() string {
+	for name, mode := range smartModeNames {
+		if mode == m {
+			return name
+		}
+	}
+	return "off"
+}
+
+// applies reports whether the mode leaves gaps or borders out on a monitor
+// tiling the given number of windows
+func (m SmartMode) applies(tiled int, monocle bool) bool {
+	return m&SmartSingle != 0 && tiled == 1 || m&SmartMonocle != 0 && monocle
+}
+
+// WorkspaceSettings overrides appearance settings on one workspace. Nil
+// fields use the global setting.
+type WorkspaceSettings struct {
+	OuterGap     *uint16
+	InnerGap     *uint16
+	BorderWidth  *uint16
+	SmartGaps    *SmartMode
+	SmartBorders *SmartMode
+}
+
+// Spacing is a gap or the border width, which can be changed at runtime
+type Spacing int
+
+const (
+	SpacingOuterGap Spacing = iota
+	SpacingInnerGap
+	SpacingBorder
+)
+
+// String returns the name used in logs and notifications
+func (s Spacing) String() string {
+	switch s {
+	case SpacingOuterGap:
+		return "outer gap"
+	case SpacingInnerGap:
+		return "inner gap"
+	}
+	return "border width"
+}
+
+// max returns the largest size allowed, as in the config file
+func (s Spacing) max() int {
+	if s == SpacingBorder {
+		return 100
+	}
+	return 1000
+}
+
+// field returns the global setting of s
+func (cfg *Config) field(s Spacing) *uint16 {
+	switch s {
+	case SpacingOuterGap:
+		return &cfg.OuterGap
+	case SpacingInnerGap:
+		return &cfg.InnerGap
+	}
+	return &cfg.BorderWidth
+}
+
+// field returns the workspace override of s
+func (ws *WorkspaceSettings) field(s Spacing) **uint16 {
+	switch s {
+	case SpacingOuterGap:
+		return &ws.OuterGap
+	case SpacingInnerGap:
+		return &ws.InnerGap
+	}
+	return &ws.BorderWidth
+}
+
+// Adjustment changes a gap or the border width: it sets it, adds to it, or
+// toggles it between zero and its last size
+type Adjustment struct {
+	N        int
+	Relative bool // Add N instead of setting it
+	Toggle   bool
+}
+
+// parseAdjustment parses "N", "+N", "-N" or "toggle"
+func parseAdjustment(s string) (Adjustment, bool) {
+	if s == "toggle" {
+		return Adjustment{Toggle: true}, true
+	}
+	relative := strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-")
+	n, err := strconv.Atoi(s)
+	if err != nil || n < -1000 || n > 1000 {
+		return Adjustment{}, false
+	}
+	return Adjustment{N: n, Relative: relative}, true
+}
+
+// String returns the adjustment as it is written in actions
+func (a Adjustment) String() string {
+	switch {
+	case a.Toggle:
+		return "toggle"
+	case a.Relative:
+		return fmt.Sprintf("%+d", a.N)
+	}
+	return strconv.Itoa(a.N)
+}
+
+// spacingKey names a size saved by toggling it off: workspace -1 is the
+// global setting
+type spacingKey struct {
+	spacing   Spacing
+	workspace int
+}
+
+// newSpacingKey returns the key of s on ws, or of the global size when ws
+// is nil
+func newSpacingKey(ws *Workspace, s Spacing) spacingKey {
+	if ws == nil {
+		return spacingKey{s, -1}
+	}
+	return spacingKey{s, ws.ID}
+}
+
+// spacing returns the size of s on ws before smart gaps and borders, or
+// the global size when ws is nil
+func (wm *WindowManager) spacing(ws *Workspace, s Spacing) uint16 {
+	if ws != nil {
+		if v := *ws.Settings.field(s); v != nil {
+			return *v
+		}
+	}
+	return *wm.config.field(s)
+}
+
+// adjustSpacing changes s on ws, or the global size when ws is nil, and
+// applies it to every window. It returns the new size.
+func (wm *WindowManager) adjustSpacing(ws *Workspace, s Spacing, adj Adjustment) uint16 {
+	v := wm.setSpacing(ws, s, adj)
+	if s == SpacingBorder {
+		wm.resetBorders()
+	}
+	wm.tile()
+	return v
+}
+
+// setSpacing changes s like adjustSpacing without applying it. Workspaces
+// with their own size keep it when the global size changes.
+func (wm *WindowManager) setSpacing(ws *Workspace, s Spacing, adj Adjustment) uint16 {
+	key := newSpacingKey(ws, s)
+	cur := wm.spacing(ws, s)
+
+	var n int
+	switch {
+	case adj.Toggle && cur != 0:
+		wm.toggledSpacing[key] = cur
+	case adj.Toggle:
+		// Back to the size before toggling off, or else the global or
+		// default size
+		saved, ok := wm.toggledSpacing[key]
+		switch {
+		case ok:
+			n = int(saved)
+		case ws != nil:
+			n = int(*wm.config.field(s))
+		default:
+			n = int(*DefaultConfig().field(s))
+		}
+	case adj.Relative:
+		n = int(cur) + adj.N
+	default:
+		n = adj.N
+	}
+	v := uint16(min(max(n, 0), s.max()))
+
+	if ws != nil {
+		*ws.Settings.field(s) = &v
+	} else {
+		*wm.config.field(s) = v
+	}
+	return v
+}
+
+// tileStyle returns the outer gap, inner gap and border width to tile a
+// workspace with, given how many windows it tiles
+func (wm *WindowManager) tileStyle(ws *Workspace, tiled int) (outer, inner, border uint16) {
+	cfg := wm.config
+	outer = wm.spacing(ws, SpacingOuterGap)
+	inner = wm.spacing(ws, SpacingInnerGap)
+	border = wm.spacing(ws, SpacingBorder)
+
+	smartGaps, smartBorders := cfg.SmartGaps, cfg.SmartBorders
+	if ws.Settings.SmartGaps != nil {
+		smartGaps = *ws.Settings.SmartGaps
+	}
+	if ws.Settings.SmartBorders != nil {
+		smartBorders = *ws.Settings.SmartBorders
+	}
+
+	monocle := ws.Layout.IsMonocle()
+	if smartGaps.applies(tiled, monocle) {
+		outer, inner = 0, 0
+	}
+	if smartBorders.applies(tiled, monocle) {
+		border = 0
+	}
+	return outer, inner, border
+}
+
+// restoreBorder sets a window's border to its workspace's width: on new
+// windows, on windows leaving fullscreen, and on windows that start floating
+// in case smart borders left it out while they were tiled
+func (wm *WindowManager) restoreBorder(c *Client) {
+	c.BorderWidth = wm.spacing(wm.workspaces[c.Workspace], SpacingBorder)
+	wm.x.ConfigureWindow(c.Window,
+		xproto.ConfigWindowBorderWidth, []uint32{uint32(c.BorderWidth)})
+}
+
+// resetBorders sets the border width of every window but fullscreen ones.
+// Tiling then corrects the tiled windows for smart borders.
+func (wm *WindowManager) resetBorders() {
+	for _, c := range wm.clients {
+		if !wm.hasFullscreenState(c.Window) {
+			wm.restoreBorder(c)
+		}
+	}
+}
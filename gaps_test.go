@@ -0,0 +1,196 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+func TestSmartGapsAndBorders(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.applyConfig(mustParseConfig(t, "[appearance]\nsmart_gaps = \"single\"\nsmart_borders = \"single\"\n"))
+
+	first := mapClient(wm, f)
+	if got := f.window(first).geom; got != (Rect{X: 0, Y: 0, Width: 1920, Height: 1080}) {
+		t.Errorf("lone window geometry = %+v", got)
+	}
+	if bw := f.window(first).borderWidth; bw != 0 {
+		t.Errorf("lone window border = %d, want 0", bw)
+	}
+
+	// A second window brings gaps and borders back
+	second := mapClient(wm, f)
+	for _, win := range []xproto.Window{first, second} {
+		w := f.window(win)
+		if w.borderWidth != 2 {
+			t.Errorf("window %d border = %d, want 2", win, w.borderWidth)
+		}
+		if w.geom.Y != 8 || w.geom.Height != 1060 {
+			t.Errorf("window %d geometry = %+v, want gaps", win, w.geom)
+		}
+	}
+
+	// Floating one leaves a lone tiled window, but the floating one keeps
+	// its border
+	wm.focus(wm.clients[second])
+	ActionFloat(wm)
+	if bw := f.window(first).borderWidth; bw != 0 {
+		t.Errorf("lone tiled window border = %d, want 0", bw)
+	}
+	if bw := f.window(second).borderWidth; bw != 2 {
+		t.Errorf("floating window border = %d, want 2", bw)
+	}
+}
+
+func TestSmartBordersMonocle(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.applyConfig(mustParseConfig(t, "[appearance]\nsmart_borders = \"monocle\"\n"))
+
+	first := mapClient(wm, f)
+	if bw := f.window(first).borderWidth; bw != 2 {
+		t.Errorf("lone tall window border = %d, want 2", bw)
+	}
+
+	mapClient(wm, f)
+	wm.currentWorkspace().SetLayout(NewFullLayout())
+	wm.tile()
+	focused := wm.currentWorkspace().Focused.Window
+	if got := f.window(focused).geom; got != (Rect{X: 4, Y: 4, Width: 1912, Height: 1072}) {
+		t.Errorf("monocle window geometry = %+v", got)
+	}
+	if bw := f.window(focused).borderWidth; bw != 0 {
+		t.Errorf("monocle window border = %d, want 0", bw)
+	}
+}
+
+func TestSmartGapsWorkspaceOverride(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.applyConfig(mustParseConfig(t, `
+[appearance]
+smart_gaps = "on"
+
+[workspace.1]
+smart_gaps = "off"
+smart_borders = "on"
+`))
+
+	win := mapClient(wm, f)
+	if got := f.window(win).geom; got != (Rect{X: 8, Y: 8, Width: 1904, Height: 1064}) {
+		t.Errorf("workspace 1 window geometry = %+v", got)
+	}
+
+	wm.switchToWorkspace(1)
+	other := mapClient(wm, f)
+	if got := f.window(other).geom; got != (Rect{X: 0, Y: 0, Width: 1916, Height: 1076}) {
+		t.Errorf("workspace 2 window geometry = %+v", got)
+	}
+}
+
+func TestAdjustSpacingOverIPC(t *testing.T) {
+	wm, f := newTestWM(t)
+	ipc := &IPCServer{wm: wm}
+	win := mapClient(wm, f)
+
+	for _, cmd := range []string{"gaps outer 0", "gaps inner +2", "border width 3"} {
+		if resp := ipc.handleCommand(cmd); !resp.Success {
+			t.Fatalf("%s: %s", cmd, resp.Message)
+		}
+	}
+	if got := f.window(win).geom; got != (Rect{X: 6, Y: 6, Width: 1902, Height: 1062}) {
+		t.Errorf("window geometry = %+v", got)
+	}
+	if bw := f.window(win).borderWidth; bw != 3 {
+		t.Errorf("border = %d, want 3", bw)
+	}
+
+	// Toggling turns the gaps off and brings back the sizes set before
+	ipc.handleCommand("gaps toggle")
+	if got := f.window(win).geom; got != (Rect{X: 0, Y: 0, Width: 1914, Height: 1074}) {
+		t.Errorf("window geometry with gaps off = %+v", got)
+	}
+	ipc.handleCommand("gaps toggle")
+	if got := f.window(win).geom; got != (Rect{X: 6, Y: 6, Width: 1902, Height: 1062}) {
+		t.Errorf("window geometry with gaps back = %+v", got)
+	}
+
+	// Workspace sizes only hold on their workspace
+	ipc.handleCommand("border workspace width 0")
+	if bw := f.window(win).borderWidth; bw != 0 {
+		t.Errorf("workspace border = %d, want 0", bw)
+	}
+	resp := ipc.handleCommand("query workspaces")
+	infos := resp.Data.([]WorkspaceInfo)
+	if infos[0].BorderWidth != 0 || infos[1].BorderWidth != 3 || infos[1].InnerGap != 6 {
+		t.Errorf("workspaces = %+v", infos[:2])
+	}
+	gaps := ipc.handleCommand("query gaps").Data.(GapsInfo)
+	if gaps != (GapsInfo{OuterGap: 0, InnerGap: 6, BorderWidth: 3, SmartGaps: "off", SmartBorders: "off"}) {
+		t.Errorf("gaps = %+v", gaps)
+	}
+}
+
+func TestGlobalGapsKeepWorkspaceOverrides(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.applyConfig(mustParseConfig(t, `
+[workspace.2]
+outer_gap = 20
+`))
+	ipc := &IPCServer{wm: wm}
+
+	wm.switchToWorkspace(1)
+	win := mapClient(wm, f)
+	for _, cmd := range []string{"gaps toggle", "gaps outer 10"} {
+		if resp := ipc.handleCommand(cmd); !resp.Success {
+			t.Fatalf("%s: %s", cmd, resp.Message)
+		}
+	}
+	if wm.config.OuterGap != 10 || wm.config.InnerGap != 0 {
+		t.Errorf("global gaps = %d %d, want 10 0", wm.config.OuterGap, wm.config.InnerGap)
+	}
+	// The [workspace.2] outer gap survives; its inner gap follows the global toggle
+	if got := f.window(win).geom; got != (Rect{X: 20, Y: 20, Width: 1876, Height: 1036}) {
+		t.Errorf("workspace 2 window geometry = %+v", got)
+	}
+}
+
+func TestConfigureNotifyReportsAppliedBorder(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.applyConfig(mustParseConfig(t, `
+[appearance]
+smart_borders = "single"
+`))
+	win := mapClient(wm, f)
+
+	wm.handleConfigureRequest(xproto.ConfigureRequestEvent{
+		Window: win, ValueMask: xproto.ConfigWindowWidth, Width: 100,
+	})
+	if len(f.sent) == 0 {
+		t.Fatal("no ConfigureNotify sent")
+	}
+	ev := xproto.ConfigureNotifyEventNew(f.sent[len(f.sent)-1].data).(xproto.ConfigureNotifyEvent)
+	if ev.BorderWidth != 0 || ev.Width != 1904 {
+		t.Errorf("ConfigureNotify border %d width %d, want 0 1904", ev.BorderWidth, ev.Width)
+	}
+}
+
+func TestFloatingWindowsGetWorkspaceBorder(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.applyConfig(mustParseConfig(t, `
+[workspace.1]
+border_width = 5
+
+[[rule]]
+class = "Pavucontrol"
+floating = true
+`))
+
+	win := f.addWindow(Rect{Width: 400, Height: 300})
+	f.setClass(win, "pavucontrol", "Pavucontrol")
+	wm.handleMapRequest(xproto.MapRequestEvent{Parent: f.root, Window: win})
+	if !wm.clients[win].Floating {
+		t.Fatal("window should float")
+	}
+	if bw := f.window(win).borderWidth; bw != 5 {
+		t.Errorf("floating window border = %d, want 5", bw)
+	}
+}
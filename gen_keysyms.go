@@ -0,0 +1,111 @@
+//go:build ignore
+
+// gen_keysyms generates keysym_table.go from the X11 keysym headers.
+//
+//	go run gen_keysyms.go [-include /usr/include/X11]
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// #define XK_name 0x1234 or #define XF86XK_name _EVDEVK(0x123)
+	defineRe = regexp.MustCompile(`^#define\s+(XF86XK_|XK_)(\w+)\s+(0x[0-9a-fA-F]+|_EVDEVK\((0x[0-9a-fA-F]+)\))`)
+)
+
+// evdevBase matches the _EVDEVK macro in XF86keysym.h
+const evdevBase = 0x10081000
+
+type keysym struct {
+	name  string
+	value uint64
+}
+
+func main() {
+	include := flag.String("include", "/usr/include/X11", "directory holding keysymdef.h and XF86keysym.h")
+	out := flag.String("o", "keysym_table.go", "output file")
+	flag.Parse()
+
+	var syms []keysym
+	seen := make(map[string]bool)
+	for _, header := range []string{"keysymdef.h", "XF86keysym.h"} {
+		parsed, err := parseHeader(filepath.Join(*include, header))
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range parsed {
+			if !seen[s.name] {
+				seen[s.name] = true
+				syms = append(syms, s)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen_keysyms.go from keysymdef.h and XF86keysym.h; DO NOT EDIT.\n\n")
+	buf.WriteString("package main\n\n")
+	buf.WriteString("import \"github.com/jezek/xgb/xproto\"\n\n")
+	buf.WriteString("// keysymTable lists every named X keysym in header order. When several\n")
+	buf.WriteString("// names share a value the first one is the canonical name.\n")
+	buf.WriteString("var keysymTable = []struct {\n\tName   string\n\tKeysym xproto.Keysym\n}{\n")
+	for _, s := range syms {
+		fmt.Fprintf(&buf, "\t{%q, %#x},\n", s.name, s.value)
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d keysyms to %s", len(syms), *out)
+}
+
+// parseHeader reads the keysym #defines from a header. XF86 keysyms keep
+// their XF86 prefix, as in xmodmap and xkb ("XF86AudioMute").
+func parseHeader(path string) ([]keysym, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var syms []keysym
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := defineRe.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		name := m[2]
+		if m[1] == "XF86XK_" {
+			name = "XF86" + name
+		}
+
+		var value uint64
+		if m[4] != "" {
+			value, err = strconv.ParseUint(m[4], 0, 32)
+			value += evdevBase
+		} else {
+			value, err = strconv.ParseUint(m[3], 0, 32)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		syms = append(syms, keysym{name, value})
+	}
+	return syms, scanner.Err()
+}
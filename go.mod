@@ -2,7 +2,6 @@ module github.com/0xb0b1/gowm
 
 go 1.25.5
 
-require (
-	github.com/jezek/xgb v1.2.0 // indirect
-	github.com/jezek/xgbutil v0.0.0-20250620170308-517212d66001 // indirect
-)
+require github.com/jezek/xgb v1.2.0
+
+require github.com/jezek/xgbutil v0.0.0-20250620170308-517212d66001 // indirect
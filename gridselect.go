@@ -1,68 +1,9 @@
 package main
 
-/*
-#cgo pkg-config: xft x11
-#include <X11/Xlib.h>
-#include <X11/Xft/Xft.h>
-#include <stdlib.h>
-#include <string.h>
-
-// Helper functions to work with Xft from Go
-
-static XftFont* openFont(Display *dpy, int screen, const char *name) {
-    return XftFontOpenName(dpy, screen, name);
-}
-
-static void closeFont(Display *dpy, XftFont *font) {
-    if (font) XftFontClose(dpy, font);
-}
-
-static XftDraw* createDraw(Display *dpy, Drawable d, Visual *visual, Colormap cmap) {
-    return XftDrawCreate(dpy, d, visual, cmap);
-}
-
-static void destroyDraw(XftDraw *draw) {
-    if (draw) XftDrawDestroy(draw);
-}
-
-static void drawString(XftDraw *draw, XftColor *color, XftFont *font, int x, int y, const char *str, int len) {
-    XftDrawStringUtf8(draw, color, font, x, y, (const FcChar8*)str, len);
-}
-
-static void allocColor(Display *dpy, Visual *visual, Colormap cmap, XftColor *color, unsigned int rgb) {
-    XRenderColor xrc;
-    xrc.red = ((rgb >> 16) & 0xFF) * 257;
-    xrc.green = ((rgb >> 8) & 0xFF) * 257;
-    xrc.blue = (rgb & 0xFF) * 257;
-    xrc.alpha = 0xFFFF;
-    XftColorAllocValue(dpy, visual, cmap, &xrc, color);
-}
-
-static void freeColor(Display *dpy, Visual *visual, Colormap cmap, XftColor *color) {
-    XftColorFree(dpy, visual, cmap, color);
-}
-
-static int fontHeight(XftFont *font) {
-    return font->ascent + font->descent;
-}
-
-static int fontAscent(XftFont *font) {
-    return font->ascent;
-}
-
-static int textWidth(Display *dpy, XftFont *font, const char *str, int len) {
-    XGlyphInfo extents;
-    XftTextExtentsUtf8(dpy, font, (const FcChar8*)str, len, &extents);
-    return extents.xOff;
-}
-*/
-import "C"
-
 import (
 	"fmt"
 	"log"
 	"strings"
-	"unsafe"
 
 	"github.com/jezek/xgb/xproto"
 )
@@ -98,9 +39,8 @@ type GridSelect struct {
 	originFractY float64 // 0.0 = top, 0.5 = center, 1.0 = bottom
 
 	// Xft resources for font rendering
-	xftFont *C.XftFont
-	xftDraw *C.XftDraw
-	display *C.Display
+	font *xftFont
+	draw *xftDraw
 }
 
 // GridItem represents an item in the grid
@@ -109,6 +49,7 @@ type GridItem struct {
 	Workspace int     // Workspace index
 	Label     string
 	Action    string // Command to run (for spawn mode)
+	Run       Action // Named action to run instead of Action
 	X, Y      int
 	W, H      int
 	BGColor   uint32
@@ -206,39 +147,8 @@ func NewGridSelect(wm *WindowManager) *GridSelect {
 		mode:         GridModeWindows,
 	}
 
-	// Open Xlib display connection for Xft
-	displayName := C.CString("")
-	defer C.free(unsafe.Pointer(displayName))
-	gs.display = C.XOpenDisplay(displayName)
-	if gs.display == nil {
-		log.Println("GridSelect: Failed to open X display for Xft")
-		return gs
-	}
-
 	// Load font - try primary first, then fallbacks
-	// Use default screen (0) for font loading
-	screenNum := C.XDefaultScreen(gs.display)
-	fontName := C.CString(gridFontName)
-	gs.xftFont = C.openFont(gs.display, screenNum, fontName)
-	C.free(unsafe.Pointer(fontName))
-
-	if gs.xftFont == nil {
-		for _, fallback := range gridFontFallbacks {
-			fontName = C.CString(fallback)
-			gs.xftFont = C.openFont(gs.display, screenNum, fontName)
-			C.free(unsafe.Pointer(fontName))
-			if gs.xftFont != nil {
-				log.Printf("GridSelect: Using fallback font: %s", fallback)
-				break
-			}
-		}
-	}
-
-	if gs.xftFont == nil {
-		log.Println("GridSelect: Failed to load any font, text will use default")
-	} else {
-		log.Printf("GridSelect: Loaded font, height=%d", int(C.fontHeight(gs.xftFont)))
-	}
+	gs.font = openXftFont("GridSelect", append([]string{gridFontName}, gridFontFallbacks...))
 
 	return gs
 }
@@ -342,22 +252,26 @@ func (gs *GridSelect) ShowWorkspaces() {
 	gs.Draw()
 }
 
-// SpawnItem represents an application to launch
+// SpawnItem represents an application to launch, or a named action such
+// as window.toggle-float to run
 type SpawnItem struct {
 	Name    string
 	Command string
+	Action  Action
 }
 
-// Default spawn items - can be customized in config
-var defaultSpawnItems = []SpawnItem{
-	{"Terminal", "alacritty"},
-	{"Browser", "firefox"},
-	{"File Manager", "thunar"},
-	{"Editor", "code"},
-	{"Discord", "discord"},
-	{"Spotify", "spotify"},
-	{"Steam", "steam"},
-	{"OBS", "obs"},
+// DefaultSpawnItems returns the default launcher items
+func DefaultSpawnItems() []SpawnItem {
+	return []SpawnItem{
+		{Name: "Terminal", Command: "alacritty"},
+		{Name: "Browser", Command: "firefox"},
+		{Name: "File Manager", Command: "thunar"},
+		{Name: "Editor", Command: "code"},
+		{Name: "Discord", Command: "discord"},
+		{Name: "Spotify", Command: "spotify"},
+		{Name: "Steam", Command: "steam"},
+		{Name: "OBS", Command: "obs"},
+	}
 }
 
 // ShowSpawn displays a grid of applications to launch
@@ -370,12 +284,13 @@ func (gs *GridSelect) ShowSpawn() {
 	gs.search = ""
 	gs.mode = GridModeSpawn
 
-	for i, app := range defaultSpawnItems {
+	for i, app := range gs.wm.config.SpawnItems {
 		bgColor := colorFromClassHash(app.Name)
 		gs.items = append(gs.items, &GridItem{
 			Workspace: i, // Reuse for index
 			Label:     app.Name,
 			Action:    app.Command,
+			Run:       app.Action,
 			BGColor:   bgColor,
 			FGColor:   gridColors.Text,
 		})
@@ -421,8 +336,9 @@ func (gs *GridSelect) createWindow() {
 		}
 	}
 
-	// Limit max columns based on screen width
-	screenW := int(gs.wm.screen.WidthInPixels)
+	// Limit max columns based on the width of the focused monitor
+	mon := gs.wm.currentMonitor().Geometry
+	screenW := int(mon.Width)
 	maxCols := (screenW - gs.padding*2) / (gs.cellW + gs.padding)
 	if gs.cols > maxCols {
 		gs.cols = maxCols
@@ -433,9 +349,9 @@ func (gs *GridSelect) createWindow() {
 	gridH := gs.rows*(gs.cellH+gs.padding) + gs.padding
 
 	// Position using originFractX/Y (0.5 = center)
-	screenH := int(gs.wm.screen.HeightInPixels)
-	x := int(float64(screenW-gridW) * gs.originFractX)
-	y := int(float64(screenH-gridH) * gs.originFractY)
+	screenH := int(mon.Height)
+	x := int(mon.X) + int(float64(screenW-gridW)*gs.originFractX)
+	y := int(mon.Y) + int(float64(screenH-gridH)*gs.originFractY)
 
 	// Calculate item positions in grid
 	for i, item := range gs.filtered {
@@ -479,15 +395,11 @@ func (gs *GridSelect) createWindow() {
 	xproto.SetInputFocus(gs.wm.conn, xproto.InputFocusPointerRoot, gs.window, xproto.TimeCurrentTime)
 
 	// Create XftDraw for the window (must be done after MapWindow)
-	if gs.display != nil && gs.xftFont != nil {
-		screen := C.XDefaultScreenOfDisplay(gs.display)
-		visual := C.XDefaultVisualOfScreen(screen)
-		colormap := C.XDefaultColormapOfScreen(screen)
-
+	if gs.font != nil {
 		// Sync xgb connection to ensure window is visible to Xlib
 		gs.wm.conn.Sync()
 
-		gs.xftDraw = C.createDraw(gs.display, C.Drawable(gs.window), visual, colormap)
+		gs.draw = gs.font.newDraw(gs.window)
 	}
 
 	// Grab keyboard
@@ -521,10 +433,8 @@ func (gs *GridSelect) Hide() {
 	}
 
 	// Clean up XftDraw
-	if gs.xftDraw != nil {
-		C.destroyDraw(gs.xftDraw)
-		gs.xftDraw = nil
-	}
+	gs.draw.Close()
+	gs.draw = nil
 
 	xproto.UngrabPointer(gs.wm.conn, xproto.TimeCurrentTime)
 	xproto.UngrabKeyboard(gs.wm.conn, xproto.TimeCurrentTime)
@@ -567,23 +477,9 @@ func (gs *GridSelect) Draw() {
 
 // drawText draws text using Xft if available, otherwise falls back to X11 core
 func (gs *GridSelect) drawText(text string, x, y int, color uint32) {
-	if gs.xftDraw != nil && gs.xftFont != nil && gs.display != nil {
+	if gs.draw != nil {
 		// Use Xft for anti-aliased text
-		screen := C.XDefaultScreenOfDisplay(gs.display)
-		visual := C.XDefaultVisualOfScreen(screen)
-		colormap := C.XDefaultColormapOfScreen(screen)
-
-		var xftColor C.XftColor
-		C.allocColor(gs.display, visual, colormap, &xftColor, C.uint(color))
-
-		cstr := C.CString(text)
-		C.drawString(gs.xftDraw, &xftColor, gs.xftFont, C.int(x), C.int(y), cstr, C.int(len(text)))
-		C.free(unsafe.Pointer(cstr))
-
-		C.freeColor(gs.display, visual, colormap, &xftColor)
-
-		// Flush to ensure drawing is visible
-		C.XFlush(gs.display)
+		gs.draw.String(text, x, y, color)
 	} else {
 		// Fallback to X11 core text
 		xproto.ChangeGC(gs.wm.conn, gs.gc, xproto.GcForeground, []uint32{color})
@@ -601,11 +497,8 @@ func (gs *GridSelect) drawText(text string, x, y int, color uint32) {
 
 // getTextWidth returns the width of text in pixels
 func (gs *GridSelect) getTextWidth(text string) int {
-	if gs.xftFont != nil && gs.display != nil {
-		cstr := C.CString(text)
-		width := int(C.textWidth(gs.display, gs.xftFont, cstr, C.int(len(text))))
-		C.free(unsafe.Pointer(cstr))
-		return width
+	if gs.font != nil {
+		return gs.font.Width(text)
 	}
 	// Fallback: approximate 7 pixels per character
 	return len(text) * 7
@@ -613,8 +506,8 @@ func (gs *GridSelect) getTextWidth(text string) int {
 
 // getFontHeight returns the font height in pixels
 func (gs *GridSelect) getFontHeight() int {
-	if gs.xftFont != nil {
-		return int(C.fontHeight(gs.xftFont))
+	if gs.font != nil {
+		return gs.font.Height()
 	}
 	return 14 // Default height
 }
@@ -777,10 +670,8 @@ func (gs *GridSelect) updateFilter() {
 	// Recreate window with new size
 	if gs.visible {
 		// Clean up XftDraw before destroying window
-		if gs.xftDraw != nil {
-			C.destroyDraw(gs.xftDraw)
-			gs.xftDraw = nil
-		}
+		gs.draw.Close()
+		gs.draw = nil
 
 		xproto.UngrabPointer(gs.wm.conn, xproto.TimeCurrentTime)
 		xproto.UngrabKeyboard(gs.wm.conn, xproto.TimeCurrentTime)
@@ -844,10 +735,18 @@ func (gs *GridSelect) SelectCurrent() {
 
 	item := gs.filtered[gs.selected]
 
+	// Named actions run once the grid is gone, since they may open
+	// another grid or change focus
+	if item.Run != nil {
+		gs.Hide()
+		item.Run(gs.wm)
+		return
+	}
+
 	switch gs.mode {
 	case GridModeWindows:
-		// Switch to workspace if needed
-		if item.Workspace != gs.wm.current {
+		// Switch to workspace if it is not on any monitor
+		if !gs.wm.isVisible(item.Workspace) {
 			gs.wm.switchToWorkspace(item.Workspace)
 		}
 		// Focus the window
@@ -865,7 +764,7 @@ func (gs *GridSelect) SelectCurrent() {
 	case GridModeSpawn:
 		// Launch the application
 		if item.Action != "" {
-			spawn(item.Action)
+			spawn("%s", item.Action)
 		}
 	}
 
@@ -945,13 +844,6 @@ func (gs *GridSelect) HandleMotionNotify(e xproto.MotionNotifyEvent) bool {
 func (gs *GridSelect) Close() {
 	gs.Hide()
 
-	if gs.xftFont != nil && gs.display != nil {
-		C.closeFont(gs.display, gs.xftFont)
-		gs.xftFont = nil
-	}
-
-	if gs.display != nil {
-		C.XCloseDisplay(gs.display)
-		gs.display = nil
-	}
+	gs.font.Close()
+	gs.font = nil
 }
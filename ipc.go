@@ -8,8 +8,8 @@ import (
 	"net"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"sync"
 )
 
 // IPCServer handles IPC communication via Unix socket
@@ -17,6 +17,19 @@ type IPCServer struct {
 	wm       *WindowManager
 	listener net.Listener
 	sockPath string
+
+	// Commands are executed by the event loop, never by connection goroutines
+	requests chan ipcRequest
+
+	// Connections streaming events (see events.go)
+	subsMu      sync.Mutex
+	subscribers map[*subscriber]bool
+}
+
+// ipcRequest is a command waiting to be run on the event loop
+type ipcRequest struct {
+	cmd   string
+	reply chan IPCResponse
 }
 
 // IPCResponse represents a response to an IPC command
@@ -32,17 +45,72 @@ type WorkspaceInfo struct {
 	Name    string `json:"name"`
 	Current bool   `json:"current"`
 	Windows int    `json:"windows"`
+	Monitor string `json:"monitor,omitempty"` // Monitor showing it, if visible
+
+	// Sizes on this workspace, before smart gaps and borders
+	OuterGap    uint16 `json:"outer_gap"`
+	InnerGap    uint16 `json:"inner_gap"`
+	BorderWidth uint16 `json:"border_width"`
 }
 
-// WindowInfo represents window information for IPC
-type WindowInfo struct {
-	ID        uint32 `json:"id"`
-	Title     string `json:"title"`
-	Class     string `json:"class"`
+// GapsInfo represents the global gap and border settings for IPC
+type GapsInfo struct {
+	OuterGap     uint16 `json:"outer_gap"`
+	InnerGap     uint16 `json:"inner_gap"`
+	BorderWidth  uint16 `json:"border_width"`
+	SmartGaps    string `json:"smart_gaps"`
+	SmartBorders string `json:"smart_borders"`
+}
+
+// MonitorInfo represents monitor information for IPC
+type MonitorInfo struct {
+	Name      string `json:"name"`
+	Primary   bool   `json:"primary"`
+	X         int16  `json:"x"`
+	Y         int16  `json:"y"`
+	Width     uint16 `json:"width"`
+	Height    uint16 `json:"height"`
 	Workspace int    `json:"workspace"`
-	Floating  bool   `json:"floating"`
 	Focused   bool   `json:"focused"`
-	Urgent    bool   `json:"urgent"`
+}
+
+// WindowInfo represents window information for IPC
+type WindowInfo struct {
+	ID         uint32 `json:"id"`
+	Title      string `json:"title"`
+	Class      string `json:"class"`
+	Workspace  int    `json:"workspace"`
+	Floating   bool   `json:"floating"`
+	Focused    bool   `json:"focused"`
+	Urgent     bool   `json:"urgent"`
+	Fullscreen bool   `json:"fullscreen"`
+}
+
+// DockInfo represents a panel or bar for IPC
+type DockInfo struct {
+	ID       uint32   `json:"id"`
+	Title    string   `json:"title"`
+	Class    string   `json:"class"`
+	Left     uint32   `json:"left"`
+	Right    uint32   `json:"right"`
+	Top      uint32   `json:"top"`
+	Bottom   uint32   `json:"bottom"`
+	Monitors []string `json:"monitors"` // Monitors it reserves space on
+}
+
+// OutputInfo represents a video output for IPC
+type OutputInfo struct {
+	Name      string   `json:"name"`
+	Connected bool     `json:"connected"`
+	EDID      string   `json:"edid,omitempty"`
+	Modes     []string `json:"modes,omitempty"`
+}
+
+// ProfileInfo represents a display profile for IPC
+type ProfileInfo struct {
+	Name    string `json:"name"`
+	Active  bool   `json:"active"`  // Last applied
+	Matches bool   `json:"matches"` // All of its outputs are connected
 }
 
 // NewIPCServer creates a new IPC server
@@ -71,6 +139,7 @@ func NewIPCServer(wm *WindowManager) (*IPCServer, error) {
 		wm:       wm,
 		listener: listener,
 		sockPath: sockPath,
+		requests: make(chan ipcRequest),
 	}, nil
 }
 
@@ -111,14 +180,26 @@ func (ipc *IPCServer) handleConnection(conn net.Conn) {
 	}
 
 	cmd := strings.TrimSpace(line)
-	response := ipc.handleCommand(cmd)
+	if fields := strings.Fields(cmd); len(fields) > 0 && strings.ToLower(fields[0]) == "subscribe" {
+		ipc.serveSubscription(conn, fields[1:])
+		return
+	}
+	response := ipc.submit(cmd)
 
 	// Send JSON response
 	jsonResp, _ := json.Marshal(response)
 	conn.Write(append(jsonResp, '\n'))
 }
 
-// handleCommand processes an IPC command and returns a response
+// submit hands a command to the event loop and waits for its response
+func (ipc *IPCServer) submit(cmd string) IPCResponse {
+	req := ipcRequest{cmd: cmd, reply: make(chan IPCResponse, 1)}
+	ipc.requests <- req
+	return <-req.reply
+}
+
+// handleCommand processes an IPC command and returns a response.
+// It must only be called from the event loop.
 func (ipc *IPCServer) handleCommand(cmd string) IPCResponse {
 	parts := strings.Fields(cmd)
 	if len(parts) == 0 {
@@ -129,16 +210,22 @@ func (ipc *IPCServer) handleCommand(cmd string) IPCResponse {
 	args := parts[1:]
 
 	switch action {
-	case "workspace":
-		return ipc.cmdWorkspace(args)
-	case "window":
-		return ipc.cmdWindow(args)
-	case "layout":
-		return ipc.cmdLayout(args)
+	case "workspace", "window", "layout", "monitor", "gaps", "border":
+		return ipc.cmdGroup(action, args)
 	case "query":
 		return ipc.cmdQuery(args)
 	case "action":
 		return ipc.cmdAction(args)
+	case "actions":
+		return ipc.cmdActions()
+	case "config":
+		return ipc.cmdConfig(args)
+	case "display":
+		return ipc.cmdDisplay(args)
+	case "bind":
+		return ipc.cmdBind(args)
+	case "unbind":
+		return ipc.cmdUnbind(args)
 	case "help":
 		return ipc.cmdHelp()
 	default:
@@ -146,215 +233,283 @@ func (ipc *IPCServer) handleCommand(cmd string) IPCResponse {
 	}
 }
 
-// cmdWorkspace handles workspace commands
-func (ipc *IPCServer) cmdWorkspace(args []string) IPCResponse {
+// cmdGroup runs the original grouped commands such as "workspace switch 3"
+// or "window focus next" through the action registry, as workspace.switch 3
+// and window.focus-next
+func (ipc *IPCServer) cmdGroup(group string, args []string) IPCResponse {
 	if len(args) == 0 {
-		return IPCResponse{Success: false, Message: "usage: workspace <switch|move> <n>"}
-	}
-
-	switch args[0] {
-	case "switch":
-		if len(args) < 2 {
-			return IPCResponse{Success: false, Message: "usage: workspace switch <n>"}
-		}
-		n, err := strconv.Atoi(args[1])
-		if err != nil || n < 1 || n > 9 {
-			return IPCResponse{Success: false, Message: "workspace must be 1-9"}
+		var names []string
+		for _, spec := range actionSpecs() {
+			if strings.HasPrefix(spec.Name, group+".") {
+				names = append(names, strings.TrimPrefix(spec.Name, group+"."))
+			}
 		}
-		ipc.wm.switchToWorkspace(n - 1)
-		return IPCResponse{Success: true, Message: fmt.Sprintf("switched to workspace %d", n)}
+		return IPCResponse{Success: false, Message: fmt.Sprintf("usage: %s <%s>", group, strings.Join(names, "|"))}
+	}
 
-	case "move":
-		if len(args) < 2 {
-			return IPCResponse{Success: false, Message: "usage: workspace move <n>"}
-		}
-		n, err := strconv.Atoi(args[1])
-		if err != nil || n < 1 || n > 9 {
-			return IPCResponse{Success: false, Message: "workspace must be 1-9"}
-		}
-		if ipc.wm.focused != nil {
-			ipc.wm.moveToWorkspace(ipc.wm.focused, n-1)
-			return IPCResponse{Success: true, Message: fmt.Sprintf("moved window to workspace %d", n)}
+	name, rest := group+"."+args[0], args[1:]
+	if len(rest) > 0 {
+		if _, ok := lookupAction(name + "-" + rest[0]); ok {
+			name, rest = name+"-"+rest[0], rest[1:]
 		}
-		return IPCResponse{Success: false, Message: "no focused window"}
-
-	default:
-		return IPCResponse{Success: false, Message: fmt.Sprintf("unknown workspace command: %s", args[0])}
 	}
+	if _, ok := lookupAction(name); !ok {
+		return IPCResponse{Success: false, Message: fmt.Sprintf("unknown %s command: %s", group, strings.Join(args, " "))}
+	}
+	return ipc.runAction(strings.Join(append([]string{name}, rest...), " "))
+}
+
+// legacyActions are the names "action" accepted before the registry
+var legacyActions = map[string]string{
+	"restart":    "wm.restart",
+	"quit":       "wm.quit",
+	"scratchpad": "scratchpad.toggle",
 }
 
-// cmdWindow handles window commands
-func (ipc *IPCServer) cmdWindow(args []string) IPCResponse {
+// cmdAction runs any registered action, e.g. "action window.toggle-float"
+func (ipc *IPCServer) cmdAction(args []string) IPCResponse {
 	if len(args) == 0 {
-		return IPCResponse{Success: false, Message: "usage: window <close|focus|float|sink>"}
+		return IPCResponse{Success: false, Message: "usage: action <name> [arg] (see 'actions')"}
 	}
-
-	switch args[0] {
-	case "close":
-		if ipc.wm.focused != nil {
-			ActionKill(ipc.wm)
-			return IPCResponse{Success: true, Message: "window closed"}
-		}
-		return IPCResponse{Success: false, Message: "no focused window"}
-
-	case "focus":
-		if len(args) < 2 {
-			return IPCResponse{Success: false, Message: "usage: window focus <next|prev|master>"}
-		}
-		switch args[1] {
-		case "next":
-			ActionFocusNext(ipc.wm)
-		case "prev":
-			ActionFocusPrev(ipc.wm)
-		case "master":
-			ActionFocusMaster(ipc.wm)
-		default:
-			return IPCResponse{Success: false, Message: fmt.Sprintf("unknown focus direction: %s", args[1])}
-		}
-		return IPCResponse{Success: true, Message: "focus changed"}
-
-	case "float":
-		if ipc.wm.focused != nil {
-			ipc.wm.focused.Floating = true
-			ipc.wm.tile()
-			return IPCResponse{Success: true, Message: "window floating"}
-		}
-		return IPCResponse{Success: false, Message: "no focused window"}
-
-	case "sink":
-		if ipc.wm.focused != nil {
-			ipc.wm.focused.Floating = false
-			ipc.wm.tile()
-			return IPCResponse{Success: true, Message: "window sunk"}
-		}
-		return IPCResponse{Success: false, Message: "no focused window"}
-
-	case "swap":
-		if len(args) < 2 {
-			return IPCResponse{Success: false, Message: "usage: window swap <next|prev>"}
-		}
-		switch args[1] {
-		case "next":
-			ActionSwapNext(ipc.wm)
-		case "prev":
-			ActionSwapPrev(ipc.wm)
-		default:
-			return IPCResponse{Success: false, Message: fmt.Sprintf("unknown swap direction: %s", args[1])}
-		}
-		return IPCResponse{Success: true, Message: "window swapped"}
-
-	default:
-		return IPCResponse{Success: false, Message: fmt.Sprintf("unknown window command: %s", args[0])}
+	if name, ok := legacyActions[args[0]]; ok {
+		args[0] = name
 	}
+	return ipc.runAction(strings.Join(args, " "))
 }
 
-// cmdLayout handles layout commands
-func (ipc *IPCServer) cmdLayout(args []string) IPCResponse {
-	if len(args) == 0 {
-		return IPCResponse{Success: false, Message: "usage: layout <next|reset|shrink|expand>"}
+// runAction parses and runs a registry action on the event loop
+func (ipc *IPCServer) runAction(s string) IPCResponse {
+	spec, arg, err := parseActionSpec(s)
+	if err != nil {
+		return IPCResponse{Success: false, Message: err.Error()}
+	}
+	if spec.Window && ipc.wm.focused == nil {
+		return IPCResponse{Success: false, Message: "no focused window"}
 	}
 
-	switch args[0] {
-	case "next":
-		ActionNextLayout(ipc.wm)
-		return IPCResponse{Success: true, Message: fmt.Sprintf("layout: %s", ipc.wm.currentWorkspace().Layout.Name())}
-
-	case "reset":
-		ActionResetLayout(ipc.wm)
-		return IPCResponse{Success: true, Message: "layout reset to tall"}
-
-	case "shrink":
-		ActionShrink(ipc.wm)
-		return IPCResponse{Success: true, Message: "master shrunk"}
+	spec.New(arg)(ipc.wm)
+	return IPCResponse{Success: true, Message: strings.TrimSpace(s)}
+}
 
-	case "expand":
-		ActionExpand(ipc.wm)
-		return IPCResponse{Success: true, Message: "master expanded"}
+// ActionInfo describes a registered action for "actions"
+type ActionInfo struct {
+	Name   string `json:"name"`
+	Arg    string `json:"arg,omitempty"`
+	Window bool   `json:"window,omitempty"`
+	Help   string `json:"help"`
+}
 
-	default:
-		return IPCResponse{Success: false, Message: fmt.Sprintf("unknown layout command: %s", args[0])}
+// cmdActions lists every registered action
+func (ipc *IPCServer) cmdActions() IPCResponse {
+	var actions []ActionInfo
+	for _, spec := range actionSpecs() {
+		actions = append(actions, ActionInfo{
+			Name:   spec.Name,
+			Arg:    spec.Arg.String(),
+			Window: spec.Window,
+			Help:   spec.Help,
+		})
 	}
+	return IPCResponse{Success: true, Data: actions}
 }
 
 // cmdQuery handles query commands
 func (ipc *IPCServer) cmdQuery(args []string) IPCResponse {
 	if len(args) == 0 {
-		return IPCResponse{Success: false, Message: "usage: query <workspaces|windows|focused>"}
+		return IPCResponse{Success: false, Message: "usage: query <workspaces|monitors|docks|outputs|profiles|windows|focused|layout|gaps|mode>"}
 	}
 
 	switch args[0] {
 	case "workspaces":
 		var workspaces []WorkspaceInfo
 		for _, ws := range ipc.wm.workspaces {
-			workspaces = append(workspaces, WorkspaceInfo{
+			info := WorkspaceInfo{
 				ID:      ws.ID + 1,
 				Name:    ws.Name,
 				Current: ws.ID == ipc.wm.current,
 				Windows: len(ws.Clients),
-			})
+
+				OuterGap:    ipc.wm.spacing(ws, SpacingOuterGap),
+				InnerGap:    ipc.wm.spacing(ws, SpacingInnerGap),
+				BorderWidth: ipc.wm.spacing(ws, SpacingBorder),
+			}
+			if m := ipc.wm.monitorShowing(ws.ID); m != nil {
+				info.Monitor = m.Name
+			}
+			workspaces = append(workspaces, info)
 		}
 		return IPCResponse{Success: true, Data: workspaces}
 
+	case "monitors":
+		var monitors []MonitorInfo
+		for _, m := range ipc.wm.monitors {
+			monitors = append(monitors, MonitorInfo{
+				Name:      m.Name,
+				Primary:   m.Primary,
+				X:         m.Geometry.X,
+				Y:         m.Geometry.Y,
+				Width:     m.Geometry.Width,
+				Height:    m.Geometry.Height,
+				Workspace: m.Workspace + 1,
+				Focused:   m.Workspace == ipc.wm.current,
+			})
+		}
+		return IPCResponse{Success: true, Data: monitors}
+
+	case "docks":
+		var docks []DockInfo
+		for _, d := range ipc.wm.sortedDocks() {
+			info := DockInfo{
+				ID:     uint32(d.Window),
+				Title:  ipc.wm.getWindowTitle(d.Window),
+				Class:  ipc.wm.getWMClass(d.Window),
+				Left:   d.Strut.Left,
+				Right:  d.Strut.Right,
+				Top:    d.Strut.Top,
+				Bottom: d.Strut.Bottom,
+			}
+			for _, m := range ipc.wm.monitors {
+				if ipc.wm.strutOn(d.Strut, m) != [4]uint32{} {
+					info.Monitors = append(info.Monitors, m.Name)
+				}
+			}
+			docks = append(docks, info)
+		}
+		return IPCResponse{Success: true, Data: docks}
+
+	case "outputs":
+		outputs, err := ipc.wm.x.Outputs()
+		if err != nil {
+			return IPCResponse{Success: false, Message: err.Error()}
+		}
+		var infos []OutputInfo
+		for _, o := range outputs {
+			info := OutputInfo{Name: o.Name, Connected: o.Connected, EDID: o.EDID}
+			for _, m := range o.Modes {
+				info.Modes = append(info.Modes, m.String())
+			}
+			infos = append(infos, info)
+		}
+		return IPCResponse{Success: true, Data: infos}
+
+	case "profiles":
+		outputs, _ := ipc.wm.x.Outputs()
+		var profiles []ProfileInfo
+		for _, p := range ipc.wm.config.DisplayProfiles {
+			profiles = append(profiles, ProfileInfo{
+				Name:    p.Name,
+				Active:  p.Name == ipc.wm.displayProfile,
+				Matches: p.match(outputs) != nil,
+			})
+		}
+		return IPCResponse{Success: true, Data: profiles}
+
 	case "windows":
 		var windows []WindowInfo
 		for _, c := range ipc.wm.clients {
-			windows = append(windows, WindowInfo{
-				ID:        uint32(c.Window),
-				Title:     ipc.wm.getWindowTitle(c.Window),
-				Class:     ipc.wm.getWMClass(c.Window),
-				Workspace: c.Workspace + 1,
-				Floating:  c.Floating,
-				Focused:   c == ipc.wm.focused,
-				Urgent:    c.Urgent,
-			})
+			windows = append(windows, ipc.wm.windowInfo(c))
 		}
 		return IPCResponse{Success: true, Data: windows}
 
 	case "focused":
 		if ipc.wm.focused != nil {
-			info := WindowInfo{
-				ID:        uint32(ipc.wm.focused.Window),
-				Title:     ipc.wm.getWindowTitle(ipc.wm.focused.Window),
-				Class:     ipc.wm.getWMClass(ipc.wm.focused.Window),
-				Workspace: ipc.wm.focused.Workspace + 1,
-				Floating:  ipc.wm.focused.Floating,
-				Focused:   true,
-				Urgent:    ipc.wm.focused.Urgent,
-			}
-			return IPCResponse{Success: true, Data: info}
+			return IPCResponse{Success: true, Data: ipc.wm.windowInfo(ipc.wm.focused)}
 		}
 		return IPCResponse{Success: false, Message: "no focused window"}
 
 	case "layout":
 		return IPCResponse{Success: true, Data: ipc.wm.currentWorkspace().Layout.Name()}
 
+	case "gaps":
+		cfg := ipc.wm.config
+		return IPCResponse{Success: true, Data: GapsInfo{
+			OuterGap:     cfg.OuterGap,
+			InnerGap:     cfg.InnerGap,
+			BorderWidth:  cfg.BorderWidth,
+			SmartGaps:    cfg.SmartGaps.String(),
+			SmartBorders: cfg.SmartBorders.String(),
+		}}
+
+	case "mode":
+		return IPCResponse{Success: true, Data: ipc.wm.currentMode()}
+
 	default:
 		return IPCResponse{Success: false, Message: fmt.Sprintf("unknown query: %s", args[0])}
 	}
 }
 
-// cmdAction handles generic actions
-func (ipc *IPCServer) cmdAction(args []string) IPCResponse {
-	if len(args) == 0 {
-		return IPCResponse{Success: false, Message: "usage: action <restart|quit|scratchpad>"}
+// cmdConfig handles config commands
+func (ipc *IPCServer) cmdConfig(args []string) IPCResponse {
+	if len(args) == 0 || args[0] != "reload" {
+		return IPCResponse{Success: false, Message: "usage: config reload"}
 	}
 
-	switch args[0] {
-	case "restart":
-		ActionRestart(ipc.wm)
-		return IPCResponse{Success: true, Message: "restarting"}
+	if err := ipc.wm.reloadConfig(); err != nil {
+		return IPCResponse{Success: false, Message: fmt.Sprintf("config not reloaded: %v", err)}
+	}
+	msg := "config reloaded"
+	if len(ipc.wm.unmappedKeys) > 0 {
+		msg += "; no keycode for " + strings.Join(ipc.wm.unmappedKeys, ", ")
+	}
+	return IPCResponse{Success: true, Message: msg}
+}
 
-	case "quit":
-		ActionQuit(ipc.wm)
-		return IPCResponse{Success: true, Message: "quitting"}
+// cmdDisplay switches display profiles, reporting errors to the caller
+func (ipc *IPCServer) cmdDisplay(args []string) IPCResponse {
+	wm := ipc.wm
+	switch {
+	case len(args) == 2 && args[0] == "profile":
+		p := wm.findDisplayProfile(args[1])
+		if p == nil {
+			return IPCResponse{Success: false, Message: fmt.Sprintf("unknown display profile %q", args[1])}
+		}
+		if err := wm.applyDisplayProfile(p); err != nil {
+			return IPCResponse{Success: false, Message: err.Error()}
+		}
+		return IPCResponse{Success: true, Message: "display profile " + p.Name}
 
-	case "scratchpad":
-		ActionToggleScratchpad(ipc.wm)
-		return IPCResponse{Success: true, Message: "scratchpad toggled"}
+	case len(args) == 1 && args[0] == "auto":
+		ActionDisplayAuto(wm)
+		if wm.displayProfile == "" {
+			return IPCResponse{Success: false, Message: "no display profile matches the connected outputs"}
+		}
+		return IPCResponse{Success: true, Message: "display profile " + wm.displayProfile}
+	}
+	return IPCResponse{Success: false, Message: "usage: display <profile NAME|auto>"}
+}
 
-	default:
-		return IPCResponse{Success: false, Message: fmt.Sprintf("unknown action: %s", args[0])}
+// cmdBind adds a keybinding at runtime. It lasts until the config is reloaded.
+func (ipc *IPCServer) cmdBind(args []string) IPCResponse {
+	if len(args) < 2 {
+		return IPCResponse{Success: false, Message: "usage: bind <keys> <action>"}
+	}
+
+	wm := ipc.wm
+	mod, keysym, err := ParseKeyCombo(args[0], wm.config.ModKey)
+	if err != nil {
+		return IPCResponse{Success: false, Message: err.Error()}
+	}
+	if _, err := wm.resolveKeyCombo(mod, keysym); err != nil {
+		return IPCResponse{Success: false, Message: err.Error()}
+	}
+	action, err := parseAction(strings.Join(args[1:], " "))
+	if err != nil {
+		return IPCResponse{Success: false, Message: err.Error()}
+	}
+
+	wm.config.Bindings = append(wm.config.Bindings, KeyBinding{Mod: mod, Keysym: keysym, Action: action})
+	wm.SetupKeybindings()
+	wm.grabKeys()
+
+	return IPCResponse{Success: true, Message: fmt.Sprintf("bound %s", formatKeyCombo(mod, keysym))}
+}
+
+// cmdUnbind removes a keybinding at runtime
+func (ipc *IPCServer) cmdUnbind(args []string) IPCResponse {
+	if len(args) != 1 {
+		return IPCResponse{Success: false, Message: "usage: unbind <keys>"}
 	}
+	return ipc.cmdBind([]string{args[0], "none"})
 }
 
 // cmdHelp returns help information
@@ -362,22 +517,57 @@ func (ipc *IPCServer) cmdHelp() IPCResponse {
 	help := `Available commands:
   workspace switch <1-9>    - Switch to workspace
   workspace move <1-9>      - Move focused window to workspace
+  workspace <next|prev>     - Cycle workspaces
   window close              - Close focused window
   window focus <next|prev|master> - Change focus
   window float              - Float focused window
   window sink               - Sink focused window to tiled
-  window swap <next|prev>   - Swap focused window
+  window toggle-float       - Toggle focused window floating
+  window swap <next|prev|master> - Swap focused window
   layout next               - Cycle to next layout
   layout reset              - Reset to tall layout
+  layout set <name>         - Switch to a layout, e.g. split(full,grid)
+  layout split <shrink|expand|move> - Resize the split, or move the focused
+                              window to its other half
   layout shrink             - Shrink master area
   layout expand             - Expand master area
+  layout <inc|dec>-master   - Change the number of master windows
+  layout <shrink|expand> window - Resize the focused window in its column
+  layout reset-weights      - Give the windows of each column equal heights
+  layout toggle <mirror|reflect-x|reflect-y|magnify> - Toggle a layout modifier
+  layout magnify <more|less> - Change how much the focused window is enlarged
+  layout rotate             - Rotate the focused split clockwise (bsp)
+  layout flip <horizontal|vertical> - Mirror the focused split (bsp)
+  layout preselect <left|right|up|down|cancel> - Side for the next window (bsp)
+  layout preselect ratio <1-99> - Share of the space it takes (bsp)
+  gaps <outer|inner> <N|+N|-N|toggle> - Set or change a gap
+  gaps toggle               - Turn the gaps off, or back on
+  gaps workspace <outer|inner|toggle> ... - The same on this workspace only
+  border width <N|+N|-N|toggle> - Set or change the border width
+  border workspace width <N|+N|-N|toggle> - The same on this workspace only
+  monitor focus <next|prev> - Focus another monitor
+  monitor move <next|prev>  - Move focused window to another monitor
+  monitor swap              - Swap workspaces with the next monitor
   query workspaces          - List all workspaces
+  query monitors            - List monitors and the workspace each shows
+  query docks               - List panels and bars and the space they reserve
+  query outputs             - List video outputs with their EDID and modes
+  query profiles            - List display profiles and which one is active
   query windows             - List all windows
   query focused             - Get focused window info
   query layout              - Get current layout name
-  action restart            - Restart window manager
-  action quit               - Quit window manager
-  action scratchpad         - Toggle scratchpad
+  query gaps                - Get the gaps, border width and smart modes
+  query mode                - Get the active key submap ("default" if none)
+  action <name> [arg]       - Run any action, e.g. action struts.toggle
+  actions                   - List every action with its argument
+  config reload             - Reload the config file
+  display profile <name>    - Apply a display profile
+  display auto              - Apply the first profile matching the outputs
+  subscribe [topics]        - Stream events as JSON lines (topics: workspace,
+                              window, focus, title, layout, urgent,
+                              fullscreen, mode; default all)
+  bind <keys> <action>      - Bind keys, e.g. bind Super+Shift+x spawn xterm
+  unbind <keys>             - Remove a keybinding
   help                      - Show this help`
 	return IPCResponse{Success: true, Message: help}
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// modifierNames lists modifiers in the order formatKeyCombo writes them
+var modifierNames = []struct {
+	mask uint16
+	name string
+}{
+	{xproto.ModMask4, "Super"},
+	{xproto.ModMaskControl, "Ctrl"},
+	{xproto.ModMask1, "Alt"},
+	{xproto.ModMaskShift, "Shift"},
+	{xproto.ModMask3, "Mod3"},
+	{xproto.ModMask5, "Mod5"},
+}
+
+// parseModifier parses a single modifier name. "Mod" stands for the
+// configured modifier key.
+func parseModifier(name string, modKey uint16) (uint16, bool) {
+	switch strings.ToLower(name) {
+	case "mod":
+		return modKey, true
+	case "shift":
+		return xproto.ModMaskShift, true
+	case "ctrl", "control":
+		return xproto.ModMaskControl, true
+	case "alt", "mod1":
+		return xproto.ModMask1, true
+	case "mod3":
+		return xproto.ModMask3, true
+	case "super", "win", "mod4":
+		return xproto.ModMask4, true
+	case "mod5":
+		return xproto.ModMask5, true
+	}
+	return 0, false
+}
+
+// unknownModifier explains why parseModifier rejected a name. Mod2 is num
+// lock, which key and button handling ignore, so it can't be bound.
+func unknownModifier(name string) error {
+	if strings.EqualFold(name, "mod2") {
+		return fmt.Errorf("%s is num lock and can't be used in bindings", name)
+	}
+	return fmt.Errorf("unknown modifier %q", name)
+}
+
+// ParseKeyCombo parses a binding like "Mod4+Ctrl+bracketleft" into a
+// modifier mask and keysym. Keys are X keysym names (any name from
+// keysymdef.h or XF86keysym.h) or a single character, so "Super+[" and
+// "Super++" work too.
+func ParseKeyCombo(s string, modKey uint16) (uint16, xproto.Keysym, error) {
+	mask, name, err := splitCombo(s, modKey)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if keysym, ok := lookupKeysym(name); ok {
+		return mask, keysym, nil
+	}
+	if r, size := utf8.DecodeRuneInString(name); size == len(name) && r != utf8.RuneError {
+		if keysym, ok := lookupKeysym(fmt.Sprintf("U%04X", r)); ok {
+			return mask, keysym, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("unknown key %q in %q", name, s)
+}
+
+// buttonNames are the aliases accepted for mouse buttons besides Button1-9
+var buttonNames = map[string]byte{
+	"left":        xproto.ButtonIndex1,
+	"middle":      xproto.ButtonIndex2,
+	"right":       xproto.ButtonIndex3,
+	"scrollup":    xproto.ButtonIndex4,
+	"scrolldown":  xproto.ButtonIndex5,
+	"scrollleft":  6,
+	"scrollright": 7,
+}
+
+// ParseButtonCombo parses a mouse binding like "Mod+Shift+Button1" or
+// "ScrollUp" into a modifier mask and button number
+func ParseButtonCombo(s string, modKey uint16) (uint16, byte, error) {
+	mask, name, err := splitCombo(s, modKey)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lower := strings.ToLower(name)
+	if button, ok := buttonNames[lower]; ok {
+		return mask, button, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(lower, "button")); err == nil &&
+		strings.HasPrefix(lower, "button") && n >= 1 && n <= 9 {
+		return mask, byte(n), nil
+	}
+	return 0, 0, fmt.Errorf("unknown mouse button %q in %q", name, s)
+}
+
+// splitCombo splits "Mod+Shift+x" into a modifier mask and the final key
+// or button name. "X++" names the plus key.
+func splitCombo(s string, modKey uint16) (uint16, string, error) {
+	mods, name := "", s
+	if strings.HasSuffix(s, "++") {
+		mods, name = s[:len(s)-2], "+"
+	} else if i := strings.LastIndex(s, "+"); i >= 0 {
+		mods, name = s[:i], s[i+1:]
+	}
+
+	var mask uint16
+	if mods != "" {
+		for _, part := range strings.Split(mods, "+") {
+			mod, ok := parseModifier(strings.TrimSpace(part), modKey)
+			if !ok {
+				return 0, "", fmt.Errorf("%w in %q", unknownModifier(strings.TrimSpace(part)), s)
+			}
+			mask |= mod
+		}
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, "", fmt.Errorf("missing key in %q", s)
+	}
+	return mask, name, nil
+}
+
+// formatKeyCombo writes a modifier mask and keysym the way ParseKeyCombo
+// reads them
+func formatKeyCombo(mod uint16, keysym xproto.Keysym) string {
+	var parts []string
+	for _, m := range modifierNames {
+		if mod&m.mask != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	return strings.Join(append(parts, keysymName(keysym)), "+")
+}
+
+// resolveKeyCombo looks up every keycode for a parsed combo in the current
+// keymap
+func (wm *WindowManager) resolveKeyCombo(mod uint16, keysym xproto.Keysym) ([]KeyCombo, error) {
+	keycodes := wm.keysymToKeycodes(keysym)
+	if len(keycodes) == 0 {
+		return nil, fmt.Errorf("%s: keysym %s has no keycode in the current keymap",
+			formatKeyCombo(mod, keysym), keysymName(keysym))
+	}
+
+	combos := make([]KeyCombo, len(keycodes))
+	for i, keycode := range keycodes {
+		combos[i] = KeyCombo{Mod: mod, Keycode: keycode}
+	}
+	return combos, nil
+}
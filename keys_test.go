@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+func TestParseKeyCombo(t *testing.T) {
+	tests := []struct {
+		s    string
+		mod  uint16
+		sym  xproto.Keysym
+		fail bool
+	}{
+		{s: "Super+Return", mod: xproto.ModMask4, sym: XK_Return},
+		{s: "mod+shift+j", mod: xproto.ModMask1 | xproto.ModMaskShift, sym: XK_j},
+		{s: "Ctrl + Alt + Delete", mod: xproto.ModMaskControl | xproto.ModMask1, sym: XK_Delete},
+		{s: "Mod4+Ctrl+bracketleft", mod: xproto.ModMask4 | xproto.ModMaskControl, sym: XK_bracketleft},
+		{s: "XF86AudioMicMute", sym: 0x1008ffb2},
+		{s: "Super+return", mod: xproto.ModMask4, sym: XK_Return},
+		{s: "Super+A", mod: xproto.ModMask4, sym: XK_A},
+		{s: "Super+[", mod: xproto.ModMask4, sym: XK_bracketleft},
+		{s: "Super++", mod: xproto.ModMask4, sym: XK_plus},
+		{s: "Super+odiaeresis", mod: xproto.ModMask4, sym: 0xf6},
+		{s: "Super+U20AC", mod: xproto.ModMask4, sym: 0x010020ac},
+		{s: "Mod3+Mod5+x", mod: xproto.ModMask3 | xproto.ModMask5, sym: XK_x},
+		{s: "Super+", fail: true},
+		{s: "Mod2+x", fail: true},
+		{s: "Meta+a", fail: true},
+		{s: "Super+nokey", fail: true},
+	}
+
+	for _, tt := range tests {
+		mod, sym, err := ParseKeyCombo(tt.s, xproto.ModMask1)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.s)
+			}
+			continue
+		}
+		if err != nil || mod != tt.mod || sym != tt.sym {
+			t.Errorf("%q = %#x %#x %v, want %#x %#x", tt.s, mod, sym, err, tt.mod, tt.sym)
+		}
+	}
+}
+
+func TestKeyModsKeepsBindableModifiers(t *testing.T) {
+	state := uint16(xproto.ModMask3 | xproto.ModMask5 | xproto.ModMask2 | xproto.ModMaskLock)
+	if got := keyMods(state); got != xproto.ModMask3|xproto.ModMask5 {
+		t.Errorf("keyMods(%#x) = %#x, want Mod3+Mod5", state, got)
+	}
+}
+
+func TestParseButtonCombo(t *testing.T) {
+	tests := []struct {
+		in     string
+		mod    uint16
+		button byte
+	}{
+		{"Mod+Button1", xproto.ModMask1, 1},
+		{"Mod+Shift+button3", xproto.ModMask1 | xproto.ModMaskShift, 3},
+		{"ScrollDown", 0, 5},
+		{"Ctrl+Middle", xproto.ModMaskControl, 2},
+	}
+	for _, tt := range tests {
+		mod, button, err := ParseButtonCombo(tt.in, xproto.ModMask1)
+		if err != nil || mod != tt.mod || button != tt.button {
+			t.Errorf("ParseButtonCombo(%q) = %#x, %d, %v", tt.in, mod, button, err)
+		}
+	}
+
+	for _, in := range []string{"Mod+Button0", "Mod+Button10", "Hyper+Button1", "Mod+a"} {
+		if _, _, err := ParseButtonCombo(in, xproto.ModMask4); err == nil {
+			t.Errorf("ParseButtonCombo(%q) succeeded", in)
+		}
+	}
+}
+
+func TestFormatKeyComboRoundTrips(t *testing.T) {
+	for _, s := range []string{"Super+Shift+Return", "Super+Ctrl+Alt+bracketleft", "XF86AudioMute", "Super+U20AC"} {
+		mod, sym, err := ParseKeyCombo(s, xproto.ModMask4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := formatKeyCombo(mod, sym); got != s {
+			t.Errorf("formatKeyCombo = %q, want %q", got, s)
+		}
+	}
+}
+
+func TestSetupKeybindingsReportsUnmappedKeys(t *testing.T) {
+	wm, _ := newTestWM(t)
+
+	// The fake keyboard has no media playback keys
+	found := false
+	for _, k := range wm.unmappedKeys {
+		if k == "XF86AudioPlay" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("unmapped keys = %v", wm.unmappedKeys)
+	}
+
+	_, err := wm.resolveKeyCombo(xproto.ModMask4, 0x1008ffb2)
+	if err == nil || !strings.Contains(err.Error(), "XF86AudioMicMute has no keycode") {
+		t.Errorf("resolve error = %v", err)
+	}
+}
+
+func TestIPCBind(t *testing.T) {
+	wm, f := newTestWM(t)
+	ipc := &IPCServer{wm: wm}
+
+	first := mapClient(wm, f)
+	mapClient(wm, f)
+
+	resp := ipc.handleCommand("bind Super+Ctrl+bracketleft window.focus-next")
+	if !resp.Success || resp.Message != "bound Super+Ctrl+bracketleft" {
+		t.Fatalf("bind = %+v", resp)
+	}
+	wm.handleKeyPress(xproto.KeyPressEvent{
+		Detail: f.keycode(XK_bracketleft),
+		State:  xproto.ModMask4 | xproto.ModMaskControl,
+	})
+	if wm.focused == nil || wm.focused.Window != first {
+		t.Errorf("bound key did not run: focused = %v", wm.focused)
+	}
+
+	if resp := ipc.handleCommand("unbind Super+Ctrl+bracketleft"); !resp.Success {
+		t.Fatalf("unbind = %+v", resp)
+	}
+	combo := KeyCombo{Mod: xproto.ModMask4 | xproto.ModMaskControl, Keycode: f.keycode(XK_bracketleft)}
+	if _, ok := wm.config.Keybindings[combo]; ok {
+		t.Error("binding still present after unbind")
+	}
+
+	for _, cmd := range []string{
+		"bind Super+XF86AudioMicMute spawn x", // no keycode
+		"bind Super+nokey spawn x",
+		"bind Super+x windw.kill",
+		"bind Super+x",
+	} {
+		if resp := ipc.handleCommand(cmd); resp.Success {
+			t.Errorf("%q succeeded", cmd)
+		}
+	}
+}
@@ -1,5 +1,13 @@
 package main
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jezek/xgb/xproto"
+)
+
 // Common X11 keysyms (from X11/keysymdef.h)
 const (
 	XK_BackSpace = 0xff08
@@ -152,3 +160,70 @@ const (
 	XF86XK_MonBrightnessUp   = 0x1008ff02
 	XF86XK_MonBrightnessDown = 0x1008ff03
 )
+
+//go:generate go run gen_keysyms.go
+
+var (
+	keysymNames  = make(map[string]xproto.Keysym) // name -> keysym
+	keysymFolded = make(map[string]xproto.Keysym) // lowercased name -> keysym, if unambiguous
+	keysymLabels = make(map[xproto.Keysym]string) // keysym -> canonical name
+)
+
+func init() {
+	ambiguous := make(map[string]bool)
+	for _, k := range keysymTable {
+		keysymNames[k.Name] = k.Keysym
+		if _, ok := keysymLabels[k.Keysym]; !ok {
+			keysymLabels[k.Keysym] = k.Name
+		}
+
+		folded := strings.ToLower(k.Name)
+		if prev, ok := keysymFolded[folded]; ok && prev != k.Keysym {
+			ambiguous[folded] = true
+		}
+		keysymFolded[folded] = k.Keysym
+	}
+	for folded := range ambiguous {
+		delete(keysymFolded, folded)
+	}
+}
+
+// lookupKeysym finds a keysym by name. Exact matches win, then names are
+// compared case-insensitively so "return" works as well as "Return" (but
+// "A" and "a" stay distinct). Like XStringToKeysym it also accepts Unicode
+// keysyms ("U20AC") and raw values ("0x1008ff12").
+func lookupKeysym(name string) (xproto.Keysym, bool) {
+	if ks, ok := keysymNames[name]; ok {
+		return ks, true
+	}
+	if ks, ok := keysymFolded[strings.ToLower(name)]; ok {
+		return ks, true
+	}
+
+	if len(name) > 1 && (name[0] == 'U' || name[0] == 'u') {
+		code, err := strconv.ParseUint(strings.TrimPrefix(name[1:], "+"), 16, 32)
+		if err == nil && code > 0 && code <= 0x10ffff {
+			if code < 0x100 {
+				return xproto.Keysym(code), true // Latin-1 keysyms equal their code point
+			}
+			return xproto.Keysym(0x01000000 + code), true
+		}
+	}
+	if strings.HasPrefix(name, "0x") {
+		if v, err := strconv.ParseUint(name[2:], 16, 32); err == nil && v > 0 {
+			return xproto.Keysym(v), true
+		}
+	}
+	return 0, false
+}
+
+// keysymName returns the canonical name of a keysym, for messages
+func keysymName(ks xproto.Keysym) string {
+	if name, ok := keysymLabels[ks]; ok {
+		return name
+	}
+	if ks&0xff000000 == 0x01000000 {
+		return fmt.Sprintf("U%04X", uint32(ks)&0xffffff)
+	}
+	return fmt.Sprintf("%#x", uint32(ks))
+}
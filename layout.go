@@ -1,5 +1,7 @@
 package main
 
+import "strings"
+
 // Layout defines how windows are arranged in a workspace
 type Layout interface {
 	Name() string
@@ -20,4 +22,108 @@ const (
 	LayoutMsgDecMaster
 	LayoutMsgMirrorShrink
 	LayoutMsgMirrorExpand
+	LayoutMsgRotate
+	LayoutMsgFlipHorizontal
+	LayoutMsgFlipVertical
+	LayoutMsgResetWeights
+	LayoutMsgSplitShrink
+	LayoutMsgSplitExpand
+	LayoutMsgSplitMove
+	LayoutMsgMagnifyMore
+	LayoutMsgMagnifyLess
+)
+
+// FocusTracker is implemented by layouts that arrange windows around the
+// focused one. tile tells them about focus before arranging.
+type FocusTracker interface {
+	SetFocused(c *Client)
+}
+
+// WindowHider is implemented by layouts that hide some windows behind
+// others without being monocle, such as a split with a full half. tile
+// unmaps the windows it hides, looking through modifiers.
+type WindowHider interface {
+	Hidden(c *Client) bool
+}
+
+// Direction is a side of a window
+type Direction int
+
+const (
+	DirNone Direction = iota
+	DirLeft
+	DirRight
+	DirUp
+	DirDown
 )
+
+// directionNames maps the direction names used in actions
+var directionNames = map[string]Direction{
+	"left":  DirLeft,
+	"right": DirRight,
+	"up":    DirUp,
+	"down":  DirDown,
+}
+
+// defaultLayouts lists the layouts layout.next cycles through, in order
+var defaultLayouts = []string{"tall", "mirror(tall)", "full", "tabbed", "grid", "spiral", "threecol", "centered", "bsp", "split(full,grid)"}
+
+// newLayout creates a layout by name, such as "tall", "mirror(tall)" or
+// "split(full,grid)", or returns nil if there is none. The tabbed layout
+// can't be modified or split: its tab bar always sits on top of the
+// workspace.
+func newLayout(name string) Layout {
+	if mod, inner, ok := strings.Cut(name, "("); ok && strings.HasSuffix(inner, ")") {
+		inner = strings.TrimSuffix(inner, ")")
+		if mod == "split" {
+			a, b, ok := splitLayoutArgs(inner)
+			if !ok {
+				return nil
+			}
+			first, second := newLayout(a), newLayout(b)
+			if !wrappable(first) || !wrappable(second) {
+				return nil
+			}
+			return NewSplitLayout(first, second)
+		}
+
+		wrap, ok := layoutModifiers[mod]
+		if !ok {
+			return nil
+		}
+		l := newLayout(inner)
+		if !wrappable(l) {
+			return nil
+		}
+		return wrap(l)
+	}
+
+	switch name {
+	case "tall":
+		return NewTallLayout()
+	case "full":
+		return NewFullLayout()
+	case "tabbed":
+		return NewTabbedLayout()
+	case "grid":
+		return NewGridLayout()
+	case "spiral":
+		return NewSpiralLayout()
+	case "threecol":
+		return NewThreeColumnLayout()
+	case "centered":
+		return NewCenteredMasterLayout()
+	case "bsp":
+		return NewBSPLayout()
+	}
+	return nil
+}
+
+// wrappable reports whether a layout can go in a modifier or split
+func wrappable(l Layout) bool {
+	if l == nil {
+		return false
+	}
+	_, tabbed := l.(*TabbedLayout)
+	return !tabbed
+}
@@ -0,0 +1,284 @@
+package main
+
+import "github.com/jezek/xgb/xproto"
+
+// BSPLayout implements a binary space partition layout (like bspwm).
+// Windows are the leaves of a tree kept between arrangements: a new window
+// splits the focused one across its longer side, or on the preselected
+// side if there is one.
+type BSPLayout struct {
+	Ratio float64 // Share of a split kept by the window being split (default 0.5)
+
+	root    *bspNode
+	focused xproto.Window
+
+	// Preselection for the next window
+	presel       Direction
+	preselWindow xproto.Window // Window that will be split
+	preselRatio  float64       // Share of the split the new window takes
+}
+
+// bspNode is a window or a split of the BSP tree
+type bspNode struct {
+	parent        *bspNode
+	first, second *bspNode      // Children of a split; nil for a window
+	window        xproto.Window // Window of a leaf
+	vertical      bool          // Children side by side rather than stacked
+	ratio         float64       // Share of the first child
+}
+
+// NewBSPLayout creates a new BSP layout
+func NewBSPLayout() *BSPLayout {
+	return &BSPLayout{
+		Ratio: 0.5,
+	}
+}
+
+func (l *BSPLayout) Name() string {
+	return "bsp"
+}
+
+func (l *BSPLayout) Arrange(clients []*Client, area Rect) []Rect {
+	present := make(map[xproto.Window]bool, len(clients))
+	for _, c := range clients {
+		present[c.Window] = true
+	}
+
+	// Drop windows that closed, floated or left the workspace
+	for _, leaf := range l.root.leaves(nil) {
+		if !present[leaf.window] {
+			l.remove(leaf)
+		}
+	}
+
+	// New windows split the preselected or focused window. Several new
+	// windows at once, such as after switching layouts, each split the
+	// one before.
+	var target *bspNode
+	for _, c := range clients {
+		if l.find(c.Window) != nil {
+			continue
+		}
+		if target == nil {
+			target = l.find(l.preselWindow)
+			if target == nil || l.presel == DirNone {
+				target = l.find(l.focused)
+			}
+			if target == nil {
+				target = l.root.last()
+			}
+		}
+		target = l.insert(target, c.Window, area)
+	}
+
+	places := make(map[xproto.Window]Rect, len(clients))
+	l.root.place(area, places)
+	rects := make([]Rect, len(clients))
+	for i, c := range clients {
+		rects[i] = places[c.Window]
+	}
+	return rects
+}
+
+// insert adds win beside target, which may be nil in an empty tree, and
+// returns the new leaf
+func (l *BSPLayout) insert(target *bspNode, win xproto.Window, area Rect) *bspNode {
+	leaf := &bspNode{window: win}
+	if target == nil {
+		l.root = leaf
+		return leaf
+	}
+
+	dir, share := l.presel, l.preselRatio
+	if dir == DirNone || target.window != l.preselWindow {
+		places := make(map[xproto.Window]Rect)
+		l.root.place(area, places)
+		r := places[target.window]
+		dir, share = DirDown, 1-l.Ratio
+		if r.Width >= r.Height {
+			dir = DirRight
+		}
+	} else {
+		l.presel = DirNone
+	}
+
+	// The target leaf becomes the split holding the old and new windows
+	old := &bspNode{parent: target, window: target.window}
+	leaf.parent = target
+	target.window = 0
+	target.vertical = dir == DirLeft || dir == DirRight
+	if dir == DirLeft || dir == DirUp {
+		target.first, target.second, target.ratio = leaf, old, share
+	} else {
+		target.first, target.second, target.ratio = old, leaf, 1-share
+	}
+	return leaf
+}
+
+// remove takes a leaf out of the tree, giving its space to its sibling
+func (l *BSPLayout) remove(leaf *bspNode) {
+	p := leaf.parent
+	if p == nil {
+		l.root = nil
+		return
+	}
+	sibling := p.first
+	if sibling == leaf {
+		sibling = p.second
+	}
+	sibling.parent = p.parent
+	switch {
+	case p.parent == nil:
+		l.root = sibling
+	case p.parent.first == p:
+		p.parent.first = sibling
+	default:
+		p.parent.second = sibling
+	}
+}
+
+// find returns the leaf of win, or nil
+func (l *BSPLayout) find(win xproto.Window) *bspNode {
+	if win == 0 {
+		return nil
+	}
+	for _, leaf := range l.root.leaves(nil) {
+		if leaf.window == win {
+			return leaf
+		}
+	}
+	return nil
+}
+
+// SetFocused tells the layout which window new ones split. Windows not
+// yet in the tree are ignored, so a window being added splits the one
+// focused before it.
+func (l *BSPLayout) SetFocused(c *Client) {
+	if c != nil && l.find(c.Window) != nil {
+		l.focused = c.Window
+	}
+}
+
+// Preselect chooses the side of the focused window the next window goes
+// to, and the share of the space it takes. DirNone cancels.
+func (l *BSPLayout) Preselect(dir Direction, ratio float64) {
+	l.presel = dir
+	l.preselWindow = l.focused
+	l.preselRatio = ratio
+}
+
+// Preselected returns the pending preselection, or DirNone
+func (l *BSPLayout) Preselected() Direction {
+	if l.find(l.preselWindow) == nil {
+		return DirNone
+	}
+	return l.presel
+}
+
+func (l *BSPLayout) HandleMessage(msg LayoutMessage) {
+	n := l.find(l.focused)
+
+	// Rotating and flipping act on the split holding the focused window
+	subtree := l.root
+	if n != nil && n.parent != nil {
+		subtree = n.parent
+	}
+
+	switch msg {
+	case LayoutMsgShrink, LayoutMsgExpand:
+		// Move the border of the split holding the focused window
+		if n == nil || n.parent == nil {
+			return
+		}
+		delta := 0.05
+		if msg == LayoutMsgShrink {
+			delta = -delta
+		}
+		if n.parent.second == n {
+			delta = -delta
+		}
+		n.parent.ratio = min(max(n.parent.ratio+delta, 0.1), 0.9)
+	case LayoutMsgRotate:
+		subtree.rotate()
+	case LayoutMsgFlipHorizontal:
+		subtree.flip(true)
+	case LayoutMsgFlipVertical:
+		subtree.flip(false)
+	}
+}
+
+func (l *BSPLayout) IsMonocle() bool {
+	return false
+}
+
+// leaves appends the windows under n, first to second
+func (n *bspNode) leaves(out []*bspNode) []*bspNode {
+	if n == nil {
+		return out
+	}
+	if n.first == nil {
+		return append(out, n)
+	}
+	return n.second.leaves(n.first.leaves(out))
+}
+
+// last returns the bottom-right leaf under n
+func (n *bspNode) last() *bspNode {
+	for n != nil && n.second != nil {
+		n = n.second
+	}
+	return n
+}
+
+// place records the area of every window under n
+func (n *bspNode) place(area Rect, places map[xproto.Window]Rect) {
+	if n == nil {
+		return
+	}
+	if n.first == nil {
+		places[n.window] = area
+		return
+	}
+
+	first, second := area, area
+	if n.vertical {
+		first.Width = uint16(float64(area.Width) * n.ratio)
+		second.X += int16(first.Width)
+		second.Width -= first.Width
+	} else {
+		first.Height = uint16(float64(area.Height) * n.ratio)
+		second.Y += int16(first.Height)
+		second.Height -= first.Height
+	}
+	n.first.place(first, places)
+	n.second.place(second, places)
+}
+
+// rotate turns the subtree under n 90° clockwise
+func (n *bspNode) rotate() {
+	if n == nil || n.first == nil {
+		return
+	}
+	// The top window of a stack ends up on the right
+	if !n.vertical {
+		n.first, n.second = n.second, n.first
+		n.ratio = 1 - n.ratio
+	}
+	n.vertical = !n.vertical
+	n.first.rotate()
+	n.second.rotate()
+}
+
+// flip mirrors the subtree under n left to right when sideBySide is set,
+// or else top to bottom
+func (n *bspNode) flip(sideBySide bool) {
+	if n == nil || n.first == nil {
+		return
+	}
+	if n.vertical == sideBySide {
+		n.first, n.second = n.second, n.first
+		n.ratio = 1 - n.ratio
+	}
+	n.first.flip(sideBySide)
+	n.second.flip(sideBySide)
+}
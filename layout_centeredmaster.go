@@ -1,10 +1,12 @@
 package main
 
 // CenteredMasterLayout implements a centered master layout
-// Master windows are centered, stack windows fill the sides
+// Master windows are centered, stack windows fill the sides, with windows
+// sized by weight in each column
 type CenteredMasterLayout struct {
 	MasterCount int
 	MasterRatio float64 // Width ratio of master area
+	windowWeights
 }
 
 // NewCenteredMasterLayout creates a new centered master layout
@@ -24,6 +26,7 @@ func (l *CenteredMasterLayout) Arrange(clients []*Client, area Rect) []Rect {
 	if n == 0 {
 		return nil
 	}
+	l.pruneWeights(clients)
 
 	rects := make([]Rect, n)
 
@@ -47,16 +50,8 @@ func (l *CenteredMasterLayout) Arrange(clients []*Client, area Rect) []Rect {
 	if stackCount == 0 {
 		masterWidth := uint16(float64(area.Width) * l.MasterRatio)
 		offsetX := (area.Width - masterWidth) / 2
-		masterHeight := area.Height / uint16(masterCount)
-
-		for i := 0; i < masterCount; i++ {
-			rects[i] = Rect{
-				X:      area.X + int16(offsetX),
-				Y:      area.Y + int16(uint16(i)*masterHeight),
-				Width:  masterWidth,
-				Height: masterHeight,
-			}
-		}
+		master := Rect{X: area.X + int16(offsetX), Y: area.Y, Width: masterWidth, Height: area.Height}
+		l.column(clients, span(0, masterCount), master, rects)
 		return rects
 	}
 
@@ -65,15 +60,8 @@ func (l *CenteredMasterLayout) Arrange(clients []*Client, area Rect) []Rect {
 	sideWidth := (area.Width - masterWidth) / 2
 
 	// Master area (centered)
-	masterHeight := area.Height / uint16(masterCount)
-	for i := 0; i < masterCount; i++ {
-		rects[i] = Rect{
-			X:      area.X + int16(sideWidth),
-			Y:      area.Y + int16(uint16(i)*masterHeight),
-			Width:  masterWidth,
-			Height: masterHeight,
-		}
-	}
+	master := Rect{X: area.X + int16(sideWidth), Y: area.Y, Width: masterWidth, Height: area.Height}
+	l.column(clients, span(0, masterCount), master, rects)
 
 	// Stack windows alternate between left and right sides
 	leftStack := make([]int, 0)
@@ -89,34 +77,23 @@ func (l *CenteredMasterLayout) Arrange(clients []*Client, area Rect) []Rect {
 
 	// Left side stack
 	if len(leftStack) > 0 {
-		leftHeight := area.Height / uint16(len(leftStack))
-		for i, idx := range leftStack {
-			rects[idx] = Rect{
-				X:      area.X,
-				Y:      area.Y + int16(uint16(i)*leftHeight),
-				Width:  sideWidth,
-				Height: leftHeight,
-			}
-		}
+		left := Rect{X: area.X, Y: area.Y, Width: sideWidth, Height: area.Height}
+		l.column(clients, leftStack, left, rects)
 	}
 
 	// Right side stack
 	if len(rightStack) > 0 {
-		rightHeight := area.Height / uint16(len(rightStack))
-		for i, idx := range rightStack {
-			rects[idx] = Rect{
-				X:      area.X + int16(sideWidth) + int16(masterWidth),
-				Y:      area.Y + int16(uint16(i)*rightHeight),
-				Width:  sideWidth,
-				Height: rightHeight,
-			}
-		}
+		right := Rect{X: area.X + int16(sideWidth) + int16(masterWidth), Y: area.Y, Width: sideWidth, Height: area.Height}
+		l.column(clients, rightStack, right, rects)
 	}
 
 	return rects
 }
 
 func (l *CenteredMasterLayout) HandleMessage(msg LayoutMessage) {
+	if l.handleWeightMessage(msg) {
+		return
+	}
 	switch msg {
 	case LayoutMsgShrink:
 		if l.MasterRatio > 0.3 {
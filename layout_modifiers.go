@@ -0,0 +1,227 @@
+package main
+
+import "github.com/jezek/xgb/xproto"
+
+// LayoutModifier is a layout that changes how another layout arranges
+// windows, such as Mirror. Modifiers pass messages on to the layout they
+// wrap and can be stacked.
+type LayoutModifier interface {
+	Layout
+	Modifier() string // Name of the modifier, as in "mirror(tall)"
+	Unwrap() Layout
+}
+
+// layoutModifiers creates modifiers by name
+var layoutModifiers = map[string]func(Layout) Layout{
+	"mirror":   func(l Layout) Layout { return NewMirrorLayout(l) },
+	"reflectx": func(l Layout) Layout { return NewReflectLayout(l, true) },
+	"reflecty": func(l Layout) Layout { return NewReflectLayout(l, false) },
+	"magnify":  func(l Layout) Layout { return NewMagnifierLayout(l, defaultMagnifyFactor) },
+}
+
+// baseLayout returns the layout under all modifiers
+func baseLayout(l Layout) Layout {
+	for {
+		m, ok := l.(LayoutModifier)
+		if !ok {
+			return l
+		}
+		l = m.Unwrap()
+	}
+}
+
+// MirrorLayout rotates a layout by 90° (like xmonad's Mirror): Tall gets
+// its master on top and the stack below
+type MirrorLayout struct {
+	Layout
+}
+
+// NewMirrorLayout mirrors a layout
+func NewMirrorLayout(l Layout) *MirrorLayout {
+	return &MirrorLayout{Layout: l}
+}
+
+func (l *MirrorLayout) Name() string {
+	return "mirror(" + l.Layout.Name() + ")"
+}
+
+func (l *MirrorLayout) Modifier() string {
+	return "mirror"
+}
+
+func (l *MirrorLayout) Unwrap() Layout {
+	return l.Layout
+}
+
+func (l *MirrorLayout) Arrange(clients []*Client, area Rect) []Rect {
+	// Arrange in an area with X and Y swapped, then swap them back
+	rects := l.Layout.Arrange(clients, transpose(area))
+	for i, r := range rects {
+		rects[i] = transpose(r)
+	}
+	return rects
+}
+
+// SetFocused passes focus on to a layout that tracks it
+func (l *MirrorLayout) SetFocused(c *Client) {
+	if t, ok := l.Layout.(FocusTracker); ok {
+		t.SetFocused(c)
+	}
+}
+
+// transpose swaps the X and Y axes of a rectangle
+func transpose(r Rect) Rect {
+	return Rect{X: r.Y, Y: r.X, Width: r.Height, Height: r.Width}
+}
+
+// ReflectLayout flips a layout left to right or top to bottom (like
+// xmonad's ReflectX and ReflectY): Tall gets its master on the right
+type ReflectLayout struct {
+	Layout
+	Horizontal bool // Flip left to right; otherwise top to bottom
+}
+
+// NewReflectLayout reflects a layout horizontally or vertically
+func NewReflectLayout(l Layout, horizontal bool) *ReflectLayout {
+	return &ReflectLayout{Layout: l, Horizontal: horizontal}
+}
+
+func (l *ReflectLayout) Name() string {
+	return l.Modifier() + "(" + l.Layout.Name() + ")"
+}
+
+func (l *ReflectLayout) Modifier() string {
+	if l.Horizontal {
+		return "reflectx"
+	}
+	return "reflecty"
+}
+
+func (l *ReflectLayout) Unwrap() Layout {
+	return l.Layout
+}
+
+func (l *ReflectLayout) Arrange(clients []*Client, area Rect) []Rect {
+	rects := l.Layout.Arrange(clients, area)
+	for i, r := range rects {
+		if l.Horizontal {
+			rects[i].X = 2*area.X + int16(area.Width) - r.X - int16(r.Width)
+		} else {
+			rects[i].Y = 2*area.Y + int16(area.Height) - r.Y - int16(r.Height)
+		}
+	}
+	return rects
+}
+
+// SetFocused passes focus on to a layout that tracks it
+func (l *ReflectLayout) SetFocused(c *Client) {
+	if t, ok := l.Layout.(FocusTracker); ok {
+		t.SetFocused(c)
+	}
+}
+
+// MagnifierLayout enlarges the focused window (like xmonad's Magnifier),
+// so it stays usable in layouts that make windows small such as grid and
+// spiral. The magnified window overlaps its neighbours and is raised.
+type MagnifierLayout struct {
+	Layout
+	Factor float64 // Growth of the focused window
+
+	focused xproto.Window
+}
+
+// Default and limits of the magnifier factor, and the step the magnify
+// messages change it by
+const (
+	defaultMagnifyFactor = 1.5
+	minMagnifyFactor     = 1.0
+	maxMagnifyFactor     = 3.0
+	magnifyFactorStep    = 0.1
+)
+
+// NewMagnifierLayout magnifies the focused window of a layout by factor
+func NewMagnifierLayout(l Layout, factor float64) *MagnifierLayout {
+	return &MagnifierLayout{Layout: l, Factor: factor}
+}
+
+func (l *MagnifierLayout) Name() string {
+	return "magnify(" + l.Layout.Name() + ")"
+}
+
+func (l *MagnifierLayout) Modifier() string {
+	return "magnify"
+}
+
+func (l *MagnifierLayout) Unwrap() Layout {
+	return l.Layout
+}
+
+func (l *MagnifierLayout) Arrange(clients []*Client, area Rect) []Rect {
+	rects := l.Layout.Arrange(clients, area)
+	for i, c := range clients {
+		if c.Window == l.focused && len(clients) > 1 && l.Factor > 1 {
+			rects[i] = magnify(rects[i], area, l.Factor)
+		}
+	}
+	return rects
+}
+
+// magnify grows r by factor around its center, keeping it inside area
+func magnify(r, area Rect, factor float64) Rect {
+	w := min(uint16(float64(r.Width)*factor), area.Width)
+	h := min(uint16(float64(r.Height)*factor), area.Height)
+	x := int(r.X) + (int(r.Width)-int(w))/2
+	y := int(r.Y) + (int(r.Height)-int(h))/2
+	x = min(max(x, int(area.X)), int(area.X)+int(area.Width)-int(w))
+	y = min(max(y, int(area.Y)), int(area.Y)+int(area.Height)-int(h))
+	return Rect{X: int16(x), Y: int16(y), Width: w, Height: h}
+}
+
+func (l *MagnifierLayout) HandleMessage(msg LayoutMessage) {
+	switch msg {
+	case LayoutMsgMagnifyMore:
+		l.Factor = min(l.Factor+magnifyFactorStep, maxMagnifyFactor)
+	case LayoutMsgMagnifyLess:
+		l.Factor = max(l.Factor-magnifyFactorStep, minMagnifyFactor)
+	default:
+		l.Layout.HandleMessage(msg)
+	}
+}
+
+// SetFocused records the window to magnify and passes focus on
+func (l *MagnifierLayout) SetFocused(c *Client) {
+	if c != nil {
+		l.focused = c.Window
+	}
+	if t, ok := l.Layout.(FocusTracker); ok {
+		t.SetFocused(c)
+	}
+}
+
+// magnifiers returns every magnifier in a layout, including those in the
+// halves of a split
+func magnifiers(l Layout) []*MagnifierLayout {
+	switch l := l.(type) {
+	case *MagnifierLayout:
+		return append([]*MagnifierLayout{l}, magnifiers(l.Layout)...)
+	case LayoutModifier:
+		return magnifiers(l.Unwrap())
+	case *SplitLayout:
+		return append(magnifiers(l.First), magnifiers(l.Second)...)
+	}
+	return nil
+}
+
+// magnifierOf returns the magnifier among a layout's modifiers, or nil
+func magnifierOf(l Layout) *MagnifierLayout {
+	for {
+		if m, ok := l.(*MagnifierLayout); ok {
+			return m
+		}
+		m, ok := l.(LayoutModifier)
+		if !ok {
+			return nil
+		}
+		l = m.Unwrap()
+	}
+}
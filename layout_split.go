@@ -0,0 +1,206 @@
+package main
+
+import "github.com/jezek/xgb/xproto"
+
+// SplitLayout divides the area between two layouts side by side (like
+// xmonad's combineTwo): the first Count windows go to the left half and
+// the rest to the right, such as split(full,grid) for an editor beside a
+// grid of terminals. Windows keep their half once placed, until moved to
+// the other one.
+type SplitLayout struct {
+	First, Second Layout
+	Ratio         float64 // Share of the width the first half takes (default 0.5)
+	Count         int     // Windows the first half takes (default 1)
+
+	inFirst map[xproto.Window]bool // Half each window was placed in
+	focused xproto.Window
+	shown   [2]xproto.Window       // Window on top in each half
+	hidden  map[xproto.Window]bool // Windows behind another in a monocle half
+}
+
+// NewSplitLayout splits the area between two layouts
+func NewSplitLayout(first, second Layout) *SplitLayout {
+	return &SplitLayout{
+		First:  first,
+		Second: second,
+		Ratio:  0.5,
+		Count:  1,
+	}
+}
+
+func (l *SplitLayout) Name() string {
+	return "split(" + l.First.Name() + "," + l.Second.Name() + ")"
+}
+
+func (l *SplitLayout) Arrange(clients []*Client, area Rect) []Rect {
+	l.place(clients)
+
+	var halves [2][]*Client
+	var index [2][]int
+	for i, c := range clients {
+		h := l.half(c.Window)
+		halves[h] = append(halves[h], c)
+		index[h] = append(index[h], i)
+	}
+
+	// A half without windows leaves the whole area to the other
+	areas := [2]Rect{area, area}
+	if len(halves[0]) > 0 && len(halves[1]) > 0 {
+		areas[0].Width = uint16(float64(area.Width) * l.Ratio)
+		areas[1].X += int16(areas[0].Width)
+		areas[1].Width -= areas[0].Width
+	}
+
+	rects := make([]Rect, len(clients))
+	l.hidden = make(map[xproto.Window]bool)
+	for h, sub := range []Layout{l.First, l.Second} {
+		if len(halves[h]) == 0 {
+			continue
+		}
+		for j, r := range sub.Arrange(halves[h], areas[h]) {
+			rects[index[h][j]] = r
+		}
+		l.hide(h, sub, halves[h])
+	}
+	return rects
+}
+
+// place puts windows that are new to the layout in a half: the first
+// until it holds Count windows, then the second
+func (l *SplitLayout) place(clients []*Client) {
+	present := make(map[xproto.Window]bool, len(clients))
+	for _, c := range clients {
+		present[c.Window] = true
+	}
+	first := 0
+	for win, in := range l.inFirst {
+		switch {
+		case !present[win]:
+			delete(l.inFirst, win)
+		case in:
+			first++
+		}
+	}
+
+	if l.inFirst == nil {
+		l.inFirst = make(map[xproto.Window]bool)
+	}
+	for _, c := range clients {
+		if _, ok := l.inFirst[c.Window]; !ok {
+			l.inFirst[c.Window] = first < l.Count
+			if first < l.Count {
+				first++
+			}
+		}
+	}
+}
+
+// half returns 0 for a window in the first half and 1 for the second
+func (l *SplitLayout) half(win xproto.Window) int {
+	if l.inFirst[win] {
+		return 0
+	}
+	return 1
+}
+
+// hide records the windows of a half that its layout keeps out of sight:
+// all but the top one in a monocle layout, or the ones a nested split hides
+func (l *SplitLayout) hide(h int, sub Layout, clients []*Client) {
+	if hider, ok := baseLayout(sub).(WindowHider); ok {
+		for _, c := range clients {
+			l.hidden[c.Window] = hider.Hidden(c)
+		}
+		return
+	}
+	if !sub.IsMonocle() {
+		return
+	}
+
+	shown := clients[0].Window
+	for _, c := range clients {
+		if c.Window == l.shown[h] {
+			shown = c.Window
+		}
+	}
+	for _, c := range clients {
+		l.hidden[c.Window] = c.Window != shown
+	}
+}
+
+// Hidden reports whether c is behind another window in its half
+func (l *SplitLayout) Hidden(c *Client) bool {
+	return l.hidden[c.Window]
+}
+
+// SetFocused passes focus on to the layout of the focused window's half,
+// and brings that window to the top of a monocle half
+func (l *SplitLayout) SetFocused(c *Client) {
+	if c == nil {
+		return
+	}
+	l.focused = c.Window
+	if _, ok := l.inFirst[c.Window]; !ok {
+		return
+	}
+	h := l.half(c.Window)
+	l.shown[h] = c.Window
+	if t, ok := l.sub(h).(FocusTracker); ok {
+		t.SetFocused(c)
+	}
+}
+
+// sub returns the layout of half h
+func (l *SplitLayout) sub(h int) Layout {
+	if h == 0 {
+		return l.First
+	}
+	return l.Second
+}
+
+func (l *SplitLayout) HandleMessage(msg LayoutMessage) {
+	switch msg {
+	case LayoutMsgSplitShrink:
+		l.Ratio = max(l.Ratio-0.05, 0.1)
+	case LayoutMsgSplitExpand:
+		l.Ratio = min(l.Ratio+0.05, 0.9)
+	case LayoutMsgSplitMove:
+		// The first half keeps its new number of windows
+		if _, ok := l.inFirst[l.focused]; !ok {
+			return
+		}
+		l.inFirst[l.focused] = !l.inFirst[l.focused]
+		l.Count = 0
+		for _, in := range l.inFirst {
+			if in {
+				l.Count++
+			}
+		}
+		l.shown[l.half(l.focused)] = l.focused
+	default:
+		// Everything else goes to the half with focus
+		l.sub(l.half(l.focused)).HandleMessage(msg)
+	}
+}
+
+func (l *SplitLayout) IsMonocle() bool {
+	return false
+}
+
+// splitLayoutArgs splits "full,mirror(tall)" at the comma outside
+// parentheses
+func splitLayoutArgs(s string) (string, string, bool) {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				return s[:i], s[i+1:], true
+			}
+		}
+	}
+	return "", "", false
+}
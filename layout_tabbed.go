@@ -0,0 +1,45 @@
+package main
+
+// TabbedLayout shows only the focused window, below a bar with a tab for
+// every window (like i3's tabbed mode)
+type TabbedLayout struct {
+	BarHeight uint16
+}
+
+// NewTabbedLayout creates a new tabbed layout
+func NewTabbedLayout() *TabbedLayout {
+	return &TabbedLayout{
+		BarHeight: 24,
+	}
+}
+
+func (l *TabbedLayout) Name() string {
+	return "tabbed"
+}
+
+func (l *TabbedLayout) Arrange(clients []*Client, area Rect) []Rect {
+	// Windows fill the area below the tab bar
+	bar := l.Bar(area)
+	area.Y += int16(bar.Height)
+	area.Height -= bar.Height
+
+	rects := make([]Rect, len(clients))
+	for i := range clients {
+		rects[i] = area
+	}
+	return rects
+}
+
+// Bar returns where the tab bar goes in the area
+func (l *TabbedLayout) Bar(area Rect) Rect {
+	area.Height = min(l.BarHeight, area.Height/2)
+	return area
+}
+
+func (l *TabbedLayout) HandleMessage(msg LayoutMessage) {
+	// Tabbed layout doesn't respond to resize messages
+}
+
+func (l *TabbedLayout) IsMonocle() bool {
+	return true
+}
@@ -1,10 +1,12 @@
 package main
 
 // TallLayout implements a master/stack layout (like xmonad's Tall)
-// Master windows on the left, stack on the right
+// Master windows on the left, stack on the right. Windows in a column can
+// be made taller or shorter than their neighbours.
 type TallLayout struct {
 	MasterCount int
 	MasterRatio float64
+	windowWeights
 }
 
 // NewTallLayout creates a new tall layout with default settings
@@ -24,6 +26,7 @@ func (l *TallLayout) Arrange(clients []*Client, area Rect) []Rect {
 	if n == 0 {
 		return nil
 	}
+	l.pruneWeights(clients)
 
 	rects := make([]Rect, n)
 
@@ -52,35 +55,22 @@ func (l *TallLayout) Arrange(clients []*Client, area Rect) []Rect {
 	}
 
 	// Arrange master windows (left side)
-	if masterCount > 0 {
-		masterHeight := area.Height / uint16(masterCount)
-		for i := 0; i < masterCount; i++ {
-			rects[i] = Rect{
-				X:      area.X,
-				Y:      area.Y + int16(uint16(i)*masterHeight),
-				Width:  masterWidth,
-				Height: masterHeight,
-			}
-		}
-	}
+	master := Rect{X: area.X, Y: area.Y, Width: masterWidth, Height: area.Height}
+	l.column(clients, span(0, masterCount), master, rects)
 
 	// Arrange stack windows (right side)
 	if stackCount > 0 {
-		stackHeight := area.Height / uint16(stackCount)
-		for i := 0; i < stackCount; i++ {
-			rects[masterCount+i] = Rect{
-				X:      area.X + int16(masterWidth),
-				Y:      area.Y + int16(uint16(i)*stackHeight),
-				Width:  stackWidth,
-				Height: stackHeight,
-			}
-		}
+		stack := Rect{X: area.X + int16(masterWidth), Y: area.Y, Width: stackWidth, Height: area.Height}
+		l.column(clients, span(masterCount, n), stack, rects)
 	}
 
 	return rects
 }
 
 func (l *TallLayout) HandleMessage(msg LayoutMessage) {
+	if l.handleWeightMessage(msg) {
+		return
+	}
 	switch msg {
 	case LayoutMsgShrink:
 		if l.MasterRatio > 0.1 {
@@ -0,0 +1,563 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+var testArea = Rect{X: 10, Y: 20, Width: 1200, Height: 800}
+
+func testClients(n int) []*Client {
+	clients := make([]*Client, n)
+	for i := range clients {
+		clients[i] = &Client{Window: xproto.Window(i + 1)}
+	}
+	return clients
+}
+
+func contains(outer, inner Rect) bool {
+	return inner.X >= outer.X && inner.Y >= outer.Y &&
+		int(inner.X)+int(inner.Width) <= int(outer.X)+int(outer.Width) &&
+		int(inner.Y)+int(inner.Height) <= int(outer.Y)+int(outer.Height)
+}
+
+func overlaps(a, b Rect) bool {
+	return int(a.X) < int(b.X)+int(b.Width) && int(b.X) < int(a.X)+int(a.Width) &&
+		int(a.Y) < int(b.Y)+int(b.Height) && int(b.Y) < int(a.Y)+int(a.Height)
+}
+
+// TestLayoutsArrangeWithinArea checks the invariants every layout must keep:
+// one rect per client, all inside the area, and no overlap unless monocle
+func TestLayoutsArrangeWithinArea(t *testing.T) {
+	layouts := []Layout{
+		NewTallLayout(),
+		NewFullLayout(),
+		NewTabbedLayout(),
+		NewGridLayout(),
+		NewSpiralLayout(),
+		NewThreeColumnLayout(),
+		NewCenteredMasterLayout(),
+		NewBSPLayout(),
+		NewMirrorLayout(NewTallLayout()),
+		NewReflectLayout(NewCenteredMasterLayout(), true),
+		NewReflectLayout(NewSpiralLayout(), false),
+		NewSplitLayout(NewTallLayout(), NewGridLayout()),
+	}
+
+	for _, l := range layouts {
+		for n := 0; n <= 9; n++ {
+			rects := l.Arrange(testClients(n), testArea)
+			if len(rects) != n {
+				t.Fatalf("%s with %d clients: got %d rects", l.Name(), n, len(rects))
+			}
+			for i, r := range rects {
+				if r.Width == 0 || r.Height == 0 {
+					t.Errorf("%s n=%d: rect %d is empty: %+v", l.Name(), n, i, r)
+				}
+				if !contains(testArea, r) {
+					t.Errorf("%s n=%d: rect %d %+v outside area", l.Name(), n, i, r)
+				}
+				if l.IsMonocle() {
+					continue
+				}
+				for j := i + 1; j < len(rects); j++ {
+					if overlaps(r, rects[j]) {
+						t.Errorf("%s n=%d: rects %d and %d overlap: %+v %+v",
+							l.Name(), n, i, j, r, rects[j])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTallLayout(t *testing.T) {
+	l := NewTallLayout()
+
+	rects := l.Arrange(testClients(1), testArea)
+	if rects[0] != testArea {
+		t.Errorf("single window = %+v", rects[0])
+	}
+
+	rects = l.Arrange(testClients(3), testArea)
+	want := []Rect{
+		{X: 10, Y: 20, Width: 600, Height: 800},
+		{X: 610, Y: 20, Width: 600, Height: 400},
+		{X: 610, Y: 420, Width: 600, Height: 400},
+	}
+	for i := range want {
+		if rects[i] != want[i] {
+			t.Errorf("rect %d = %+v, want %+v", i, rects[i], want[i])
+		}
+	}
+
+	l.HandleMessage(LayoutMsgIncMaster)
+	rects = l.Arrange(testClients(3), testArea)
+	if rects[1].X != testArea.X || rects[2].Width != 600 || rects[2].Height != 800 {
+		t.Errorf("two masters: %+v", rects)
+	}
+
+	l.HandleMessage(LayoutMsgDecMaster)
+	l.HandleMessage(LayoutMsgDecMaster)
+	if l.MasterCount != 1 {
+		t.Errorf("master count went below 1: %d", l.MasterCount)
+	}
+
+	l.HandleMessage(LayoutMsgExpand)
+	rects = l.Arrange(testClients(2), testArea)
+	if rects[0].Width <= 600 || rects[0].Width+rects[1].Width != testArea.Width {
+		t.Errorf("expanded master: %+v", rects)
+	}
+
+	for i := 0; i < 50; i++ {
+		l.HandleMessage(LayoutMsgShrink)
+	}
+	if l.MasterRatio < 0.05 {
+		t.Errorf("master ratio shrank to %f", l.MasterRatio)
+	}
+}
+
+func TestFullLayout(t *testing.T) {
+	l := NewFullLayout()
+	if !l.IsMonocle() {
+		t.Error("full layout should be monocle")
+	}
+	for i, r := range l.Arrange(testClients(3), testArea) {
+		if r != testArea {
+			t.Errorf("rect %d = %+v", i, r)
+		}
+	}
+}
+
+func TestGridLayout(t *testing.T) {
+	rects := NewGridLayout().Arrange(testClients(4), testArea)
+	want := []Rect{
+		{X: 10, Y: 20, Width: 600, Height: 400},
+		{X: 610, Y: 20, Width: 600, Height: 400},
+		{X: 10, Y: 420, Width: 600, Height: 400},
+		{X: 610, Y: 420, Width: 600, Height: 400},
+	}
+	for i := range want {
+		if rects[i] != want[i] {
+			t.Errorf("rect %d = %+v, want %+v", i, rects[i], want[i])
+		}
+	}
+}
+
+func TestSpiralLayout(t *testing.T) {
+	l := NewSpiralLayout()
+	rects := l.Arrange(testClients(3), testArea)
+	want := []Rect{
+		{X: 10, Y: 20, Width: 600, Height: 800},
+		{X: 610, Y: 20, Width: 600, Height: 400},
+		{X: 610, Y: 420, Width: 600, Height: 400},
+	}
+	for i := range want {
+		if rects[i] != want[i] {
+			t.Errorf("rect %d = %+v, want %+v", i, rects[i], want[i])
+		}
+	}
+
+	l.HandleMessage(LayoutMsgExpand)
+	if rects := l.Arrange(testClients(2), testArea); rects[0].Width <= 600 {
+		t.Errorf("expand did not grow first window: %+v", rects[0])
+	}
+}
+
+func TestThreeColumnLayout(t *testing.T) {
+	l := NewThreeColumnLayout()
+
+	rects := l.Arrange(testClients(3), testArea)
+	// Master in the middle, one window on each side
+	if rects[0] != (Rect{X: 310, Y: 20, Width: 600, Height: 800}) {
+		t.Errorf("master = %+v", rects[0])
+	}
+	if rects[1].X != testArea.X || rects[2].X != 910 {
+		t.Errorf("side columns = %+v %+v", rects[1], rects[2])
+	}
+}
+
+func TestCenteredMasterLayout(t *testing.T) {
+	l := NewCenteredMasterLayout()
+
+	rects := l.Arrange(testClients(2), testArea)
+	if rects[0] != (Rect{X: 250, Y: 20, Width: 720, Height: 800}) {
+		t.Errorf("master = %+v", rects[0])
+	}
+	if rects[1] != (Rect{X: 10, Y: 20, Width: 240, Height: 800}) {
+		t.Errorf("first stack window should be on the left: %+v", rects[1])
+	}
+
+	l.HandleMessage(LayoutMsgShrink)
+	if rects := l.Arrange(testClients(2), testArea); rects[0].Width >= 720 {
+		t.Errorf("shrink did not shrink master: %+v", rects[0])
+	}
+}
+
+func TestWindowWeights(t *testing.T) {
+	l := NewTallLayout()
+	clients := testClients(3)
+
+	// The focused stack window grows at the expense of the one below
+	l.SetFocused(clients[1])
+	for i := 0; i < 5; i++ {
+		l.HandleMessage(LayoutMsgMirrorExpand)
+	}
+	rects := l.Arrange(clients, testArea)
+	if rects[1] != (Rect{X: 610, Y: 20, Width: 600, Height: 480}) ||
+		rects[2] != (Rect{X: 610, Y: 500, Width: 600, Height: 320}) {
+		t.Errorf("stack = %+v %+v", rects[1], rects[2])
+	}
+	if rects[0].Height != 800 {
+		t.Errorf("master = %+v", rects[0])
+	}
+
+	// Weights are kept in bounds
+	for i := 0; i < 100; i++ {
+		l.HandleMessage(LayoutMsgMirrorShrink)
+	}
+	if w := l.weight(clients[1].Window); w != minWeight {
+		t.Errorf("weight = %v, want %v", w, minWeight)
+	}
+
+	// Windows that close lose their weight
+	l.Arrange(clients[:1], testArea)
+	if len(l.weights) != 0 {
+		t.Errorf("weights of closed windows kept: %v", l.weights)
+	}
+
+	// Resetting evens every column out again
+	for _, l := range []Layout{NewTallLayout(), NewThreeColumnLayout(), NewCenteredMasterLayout()} {
+		clients := testClients(7)
+		even := l.Arrange(clients, testArea)
+		l.(FocusTracker).SetFocused(clients[3])
+		l.HandleMessage(LayoutMsgMirrorExpand)
+		if rects := l.Arrange(clients, testArea); rects[3].Height <= even[3].Height {
+			t.Errorf("%s: expanding did not grow the window: %+v", l.Name(), rects[3])
+		}
+		l.HandleMessage(LayoutMsgResetWeights)
+		if rects := l.Arrange(clients, testArea); rects[3] != even[3] {
+			t.Errorf("%s: reset = %+v, want %+v", l.Name(), rects[3], even[3])
+		}
+	}
+}
+
+func TestMagnifierLayout(t *testing.T) {
+	l := NewMagnifierLayout(NewGridLayout(), 1.5)
+	clients := testClients(4)
+
+	// Grown around its center, then moved back inside the area
+	l.SetFocused(clients[0])
+	rects := l.Arrange(clients, testArea)
+	if rects[0] != (Rect{X: 10, Y: 20, Width: 900, Height: 600}) {
+		t.Errorf("magnified top left window = %+v", rects[0])
+	}
+	if rects[3] != (Rect{X: 610, Y: 420, Width: 600, Height: 400}) {
+		t.Errorf("other window = %+v", rects[3])
+	}
+	l.SetFocused(clients[3])
+	if rects := l.Arrange(clients, testArea); rects[3] != (Rect{X: 310, Y: 220, Width: 900, Height: 600}) {
+		t.Errorf("magnified bottom right window = %+v", rects[3])
+	}
+
+	// The factor stays within bounds, and the window within the area
+	for i := 0; i < 30; i++ {
+		l.HandleMessage(LayoutMsgMagnifyMore)
+	}
+	if l.Factor != maxMagnifyFactor {
+		t.Errorf("factor = %v, want %v", l.Factor, maxMagnifyFactor)
+	}
+	if rects := l.Arrange(clients, testArea); rects[3] != testArea {
+		t.Errorf("fully magnified window = %+v", rects[3])
+	}
+	for i := 0; i < 30; i++ {
+		l.HandleMessage(LayoutMsgMagnifyLess)
+	}
+	if rects := l.Arrange(clients, testArea); rects[3] != (Rect{X: 610, Y: 420, Width: 600, Height: 400}) {
+		t.Errorf("unmagnified window = %+v", rects[3])
+	}
+}
+
+func TestMagnifierOverIPC(t *testing.T) {
+	wm, f := newTestWM(t)
+	ipc := &IPCServer{wm: wm}
+	for i := 0; i < 4; i++ {
+		mapClient(wm, f)
+	}
+	ipc.handleCommand("layout set grid")
+	clients := wm.currentWorkspace().TiledClients()
+	wm.focus(clients[0])
+	before := f.window(clients[0].Window).geom
+
+	if resp := ipc.handleCommand("layout toggle magnify"); !resp.Success {
+		t.Fatal(resp.Message)
+	}
+	if name := wm.currentWorkspace().Layout.Name(); name != "magnify(grid)" {
+		t.Errorf("layout = %s", name)
+	}
+	got := f.window(clients[0].Window).geom
+	if got.Width <= before.Width || got.Height <= before.Height {
+		t.Errorf("magnified window %+v is no bigger than %+v", got, before)
+	}
+
+	// Focus moves the magnification along and raises the window
+	wm.focus(clients[1])
+	if f.window(clients[0].Window).geom != before {
+		t.Errorf("window still magnified after losing focus: %+v", f.window(clients[0].Window).geom)
+	}
+	if f.top() != clients[1].Window {
+		t.Error("magnified window not raised")
+	}
+
+	ipc.handleCommand("layout toggle magnify")
+	if name := wm.currentWorkspace().Layout.Name(); name != "grid" {
+		t.Errorf("layout after toggling off = %s", name)
+	}
+}
+
+func TestMagnifyFactor(t *testing.T) {
+	wm, _ := newTestWM(t)
+	wm.applyConfig(mustParseConfig(t, "[appearance]\nmagnify_factor = 2\n"))
+	ws := wm.currentWorkspace()
+
+	// New magnifiers start from the config, before anything is tiled
+	ws.ToggleModifier("magnify")
+	ws.Layout.HandleMessage(LayoutMsgMagnifyMore)
+	if got := magnifierOf(ws.Layout).Factor; got != 2.1 {
+		t.Errorf("factor = %v, want 2.1", got)
+	}
+
+	// Other modifiers coming and going keep it
+	ws.ToggleModifier("mirror")
+	ws.ToggleModifier("mirror")
+	if got := magnifierOf(ws.Layout).Factor; got != 2.1 {
+		t.Errorf("factor after toggling mirror = %v, want 2.1", got)
+	}
+
+	// Reloading the config updates existing magnifiers
+	wm.applyConfig(mustParseConfig(t, "[appearance]\nmagnify_factor = 1.2\n"))
+	if got := magnifierOf(ws.Layout).Factor; got != 1.2 {
+		t.Errorf("factor after reload = %v, want 1.2", got)
+	}
+	ws.SelectLayout("split(magnify(tall),grid)")
+	if m := magnifiers(ws.Layout); len(m) != 1 || m[0].Factor != 1.2 {
+		t.Errorf("split magnifiers = %v", m)
+	}
+}
+
+func TestSplitLayout(t *testing.T) {
+	l := NewSplitLayout(NewFullLayout(), NewGridLayout())
+	clients := testClients(3)
+
+	rects := l.Arrange(clients, testArea)
+	if rects[0] != (Rect{X: 10, Y: 20, Width: 600, Height: 800}) {
+		t.Errorf("first half = %+v", rects[0])
+	}
+	for _, r := range rects[1:] {
+		if r.X < 610 || !contains(testArea, r) {
+			t.Errorf("second half window at %+v", r)
+		}
+	}
+
+	// A window moved to the full half goes on top of the one there
+	l.SetFocused(clients[2])
+	l.HandleMessage(LayoutMsgSplitMove)
+	rects = l.Arrange(clients, testArea)
+	if rects[2] != rects[0] || l.Count != 2 {
+		t.Errorf("moved window at %+v, count %d", rects[2], l.Count)
+	}
+	if !l.Hidden(clients[0]) || l.Hidden(clients[2]) {
+		t.Error("moved window is not the one shown")
+	}
+	if rects[1] != (Rect{X: 610, Y: 20, Width: 600, Height: 800}) {
+		t.Errorf("window left alone in the second half = %+v", rects[1])
+	}
+
+	// Focusing the window underneath brings it back up
+	l.SetFocused(clients[0])
+	l.Arrange(clients, testArea)
+	if l.Hidden(clients[0]) || !l.Hidden(clients[2]) {
+		t.Error("focused window is hidden")
+	}
+
+	l.HandleMessage(LayoutMsgSplitExpand)
+	if rects := l.Arrange(clients, testArea); rects[0].Width != 660 {
+		t.Errorf("expanded first half = %+v", rects[0])
+	}
+
+	// New windows fill the first half up to Count first
+	l = NewSplitLayout(NewTallLayout(), NewTallLayout())
+	l.Count = 2
+	rects = l.Arrange(testClients(3), testArea)
+	if rects[1].X >= 610 || rects[2].X != 610 {
+		t.Errorf("halves = %+v", rects)
+	}
+}
+
+func TestSplitLayoutOverIPC(t *testing.T) {
+	wm, f := newTestWM(t)
+	ipc := &IPCServer{wm: wm}
+	mapClient(wm, f)
+	mapClient(wm, f)
+
+	if resp := ipc.handleCommand("layout set split(full,grid)"); !resp.Success {
+		t.Fatal(resp.Message)
+	}
+	clients := wm.currentWorkspace().TiledClients()
+	wm.focus(clients[1])
+	ipc.handleCommand("layout split move")
+
+	// Both windows share the full half, with the focused one on top
+	if f.window(clients[0].Window).mapped || !f.window(clients[1].Window).mapped {
+		t.Error("focused window is not the one shown")
+	}
+	if got := f.window(clients[1].Window).geom; got != (Rect{X: 8, Y: 8, Width: 1900, Height: 1060}) {
+		t.Errorf("moved window geometry = %+v", got)
+	}
+
+	// Leaving the split shows every window again
+	ipc.handleCommand("layout set grid")
+	if !f.window(clients[0].Window).mapped {
+		t.Error("window still hidden after leaving the split")
+	}
+}
+
+func TestBSPLayout(t *testing.T) {
+	l := NewBSPLayout()
+	c := testClients(4)
+	check := func(rects []Rect, want ...Rect) {
+		t.Helper()
+		for i := range want {
+			if rects[i] != want[i] {
+				t.Errorf("rect %d = %+v, want %+v", i, rects[i], want[i])
+			}
+		}
+	}
+
+	// New windows split the focused one across its longer side
+	l.Arrange(c[:1], testArea)
+	l.SetFocused(c[0])
+	check(l.Arrange(c[:2], testArea),
+		Rect{X: 10, Y: 20, Width: 600, Height: 800},
+		Rect{X: 610, Y: 20, Width: 600, Height: 800})
+	l.SetFocused(c[1])
+	check(l.Arrange(c[:3], testArea),
+		Rect{X: 10, Y: 20, Width: 600, Height: 800},
+		Rect{X: 610, Y: 20, Width: 600, Height: 400},
+		Rect{X: 610, Y: 420, Width: 600, Height: 400})
+
+	// Preselection picks the window, side and share of the next split
+	l.SetFocused(c[0])
+	l.Preselect(DirDown, 0.25)
+	l.SetFocused(c[2])
+	check(l.Arrange(c, testArea),
+		Rect{X: 10, Y: 20, Width: 600, Height: 600},
+		Rect{X: 610, Y: 20, Width: 600, Height: 400},
+		Rect{X: 610, Y: 420, Width: 600, Height: 400},
+		Rect{X: 10, Y: 620, Width: 600, Height: 200})
+	if l.Preselected() != DirNone {
+		t.Error("preselection was not used up")
+	}
+
+	// A closed window's sibling takes its space
+	remaining := []*Client{c[0], c[2], c[3]}
+	check(l.Arrange(remaining, testArea),
+		Rect{X: 10, Y: 20, Width: 600, Height: 600},
+		Rect{X: 610, Y: 20, Width: 600, Height: 800},
+		Rect{X: 10, Y: 620, Width: 600, Height: 200})
+
+	// Rotating clockwise turns the left column into the top row
+	l.HandleMessage(LayoutMsgRotate)
+	check(l.Arrange(remaining, testArea),
+		Rect{X: 310, Y: 20, Width: 900, Height: 400},
+		Rect{X: 10, Y: 420, Width: 1200, Height: 400},
+		Rect{X: 10, Y: 20, Width: 300, Height: 400})
+
+	l.HandleMessage(LayoutMsgFlipHorizontal)
+	check(l.Arrange(remaining, testArea),
+		Rect{X: 10, Y: 20, Width: 900, Height: 400},
+		Rect{X: 10, Y: 420, Width: 1200, Height: 400},
+		Rect{X: 910, Y: 20, Width: 300, Height: 400})
+
+	// Shrinking moves the border of the focused window's split
+	l.HandleMessage(LayoutMsgShrink)
+	if rects := l.Arrange(remaining, testArea); rects[1] != (Rect{X: 10, Y: 460, Width: 1200, Height: 360}) {
+		t.Errorf("shrunk window = %+v", rects[1])
+	}
+}
+
+func TestBSPPreselectOverIPC(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.currentWorkspace().SetLayout(NewBSPLayout())
+	ipc := &IPCServer{wm: wm}
+
+	mapClient(wm, f)
+	right := mapClient(wm, f)
+	for _, cmd := range []string{"layout preselect left", "layout preselect ratio 25"} {
+		if resp := ipc.handleCommand(cmd); !resp.Success {
+			t.Fatalf("%s: %+v", cmd, resp)
+		}
+	}
+	win := mapClient(wm, f)
+
+	if got := f.window(right).geom; got != (Rect{X: 1203, Y: 8, Width: 705, Height: 1060}) {
+		t.Errorf("split window = %+v", got)
+	}
+	if got := f.window(win).geom; got != (Rect{X: 964, Y: 8, Width: 227, Height: 1060}) {
+		t.Errorf("new window = %+v", got)
+	}
+}
+
+func TestLayoutModifiers(t *testing.T) {
+	tests := []struct {
+		layout Layout
+		want   []Rect
+	}{
+		// Master on top, stack below
+		{NewMirrorLayout(NewTallLayout()), []Rect{
+			{X: 10, Y: 20, Width: 1200, Height: 400},
+			{X: 10, Y: 420, Width: 600, Height: 400},
+			{X: 610, Y: 420, Width: 600, Height: 400},
+		}},
+		// Master on the right
+		{NewReflectLayout(NewTallLayout(), true), []Rect{
+			{X: 610, Y: 20, Width: 600, Height: 800},
+			{X: 10, Y: 20, Width: 600, Height: 400},
+			{X: 10, Y: 420, Width: 600, Height: 400},
+		}},
+		// Spiral going up instead of down
+		{NewReflectLayout(NewSpiralLayout(), false), []Rect{
+			{X: 10, Y: 20, Width: 600, Height: 800},
+			{X: 610, Y: 420, Width: 600, Height: 400},
+			{X: 610, Y: 20, Width: 600, Height: 400},
+		}},
+	}
+	for _, tt := range tests {
+		rects := tt.layout.Arrange(testClients(3), testArea)
+		for i := range tt.want {
+			if rects[i] != tt.want[i] {
+				t.Errorf("%s: rect %d = %+v, want %+v", tt.layout.Name(), i, rects[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestNewLayoutByName(t *testing.T) {
+	for _, name := range defaultLayouts {
+		if l := newLayout(name); l == nil || l.Name() != name {
+			t.Errorf("newLayout(%q) = %v", name, l)
+		}
+	}
+	for _, name := range []string{"reflectx(mirror(tall))", "split(mirror(tall),split(full,grid))", "magnify(grid)"} {
+		if l := newLayout(name); l == nil || l.Name() != name {
+			t.Errorf("newLayout(%q) = %v", name, l)
+		}
+	}
+	for _, name := range []string{"wide", "twist(tall)", "mirror(wide)", "mirror(tall", "split(tall)", "split(tall,wide)",
+		"mirror(tabbed)", "magnify(tabbed)", "split(tabbed,grid)", "split(full,reflectx(tabbed))"} {
+		if l := newLayout(name); l != nil {
+			t.Errorf("newLayout(%q) = %s, want nil", name, l.Name())
+		}
+	}
+}
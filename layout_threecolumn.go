@@ -1,10 +1,12 @@
 package main
 
 // ThreeColumnLayout implements a three-column layout
-// Master in the center, stacks on left and right
+// Master in the center, stacks on left and right, with windows sized by
+// weight in each column
 type ThreeColumnLayout struct {
 	MasterCount int
 	MasterRatio float64 // Width ratio of center column
+	windowWeights
 }
 
 // NewThreeColumnLayout creates a new three-column layout
@@ -24,6 +26,7 @@ func (l *ThreeColumnLayout) Arrange(clients []*Client, area Rect) []Rect {
 	if n == 0 {
 		return nil
 	}
+	l.pruneWeights(clients)
 
 	rects := make([]Rect, n)
 
@@ -72,50 +75,29 @@ func (l *ThreeColumnLayout) Arrange(clients []*Client, area Rect) []Rect {
 	leftCount := stackCount / 2
 	rightCount := stackCount - leftCount
 
-	idx := 0
-
 	// Left column
 	if leftCount > 0 {
-		leftHeight := area.Height / uint16(leftCount)
-		for i := 0; i < leftCount; i++ {
-			rects[masterCount+i] = Rect{
-				X:      area.X,
-				Y:      area.Y + int16(uint16(i)*leftHeight),
-				Width:  sideWidth,
-				Height: leftHeight,
-			}
-		}
-		idx = leftCount
+		left := Rect{X: area.X, Y: area.Y, Width: sideWidth, Height: area.Height}
+		l.column(clients, span(masterCount, masterCount+leftCount), left, rects)
 	}
 
 	// Center column (master)
-	masterHeight := area.Height / uint16(masterCount)
-	for i := 0; i < masterCount; i++ {
-		rects[i] = Rect{
-			X:      area.X + int16(sideWidth),
-			Y:      area.Y + int16(uint16(i)*masterHeight),
-			Width:  masterWidth,
-			Height: masterHeight,
-		}
-	}
+	center := Rect{X: area.X + int16(sideWidth), Y: area.Y, Width: masterWidth, Height: area.Height}
+	l.column(clients, span(0, masterCount), center, rects)
 
 	// Right column
 	if rightCount > 0 {
-		rightHeight := area.Height / uint16(rightCount)
-		for i := 0; i < rightCount; i++ {
-			rects[masterCount+idx+i] = Rect{
-				X:      area.X + int16(sideWidth) + int16(masterWidth),
-				Y:      area.Y + int16(uint16(i)*rightHeight),
-				Width:  sideWidth,
-				Height: rightHeight,
-			}
-		}
+		right := Rect{X: area.X + int16(sideWidth) + int16(masterWidth), Y: area.Y, Width: sideWidth, Height: area.Height}
+		l.column(clients, span(masterCount+leftCount, n), right, rects)
 	}
 
 	return rects
 }
 
 func (l *ThreeColumnLayout) HandleMessage(msg LayoutMessage) {
+	if l.handleWeightMessage(msg) {
+		return
+	}
 	switch msg {
 	case LayoutMsgShrink:
 		if l.MasterRatio > 0.2 {
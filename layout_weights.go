@@ -0,0 +1,100 @@
+package main
+
+import "github.com/jezek/xgb/xproto"
+
+// Weights are in tenths, so they add up without rounding errors: windows
+// start at 1.0, and the mirror messages change them by 0.1 within 0.2-5.0
+const (
+	defaultWeight = 10
+	minWeight     = 2
+	maxWeight     = 50
+)
+
+// windowWeights gives the windows of a column heights in proportion to
+// their weights (like xmonad's ResizableTall). The mirror messages change
+// the focused window's weight.
+type windowWeights struct {
+	weights map[xproto.Window]int
+	focused xproto.Window
+}
+
+// SetFocused tells the layout which window the mirror messages resize
+func (w *windowWeights) SetFocused(c *Client) {
+	if c != nil {
+		w.focused = c.Window
+	}
+}
+
+// weight returns the weight of win
+func (w *windowWeights) weight(win xproto.Window) int {
+	if v, ok := w.weights[win]; ok {
+		return v
+	}
+	return defaultWeight
+}
+
+// adjustWeight changes the weight of the focused window by delta
+func (w *windowWeights) adjustWeight(delta int) {
+	if w.focused == 0 {
+		return
+	}
+	if w.weights == nil {
+		w.weights = make(map[xproto.Window]int)
+	}
+	w.weights[w.focused] = min(max(w.weight(w.focused)+delta, minWeight), maxWeight)
+}
+
+// handleWeightMessage handles the messages that change weights, and
+// reports whether msg was one of them
+func (w *windowWeights) handleWeightMessage(msg LayoutMessage) bool {
+	switch msg {
+	case LayoutMsgMirrorShrink:
+		w.adjustWeight(-1)
+	case LayoutMsgMirrorExpand:
+		w.adjustWeight(1)
+	case LayoutMsgResetWeights:
+		w.weights = nil
+	default:
+		return false
+	}
+	return true
+}
+
+// pruneWeights forgets the weights of windows that are no longer arranged
+func (w *windowWeights) pruneWeights(clients []*Client) {
+	present := make(map[xproto.Window]bool, len(clients))
+	for _, c := range clients {
+		present[c.Window] = true
+	}
+	for win := range w.weights {
+		if !present[win] {
+			delete(w.weights, win)
+		}
+	}
+}
+
+// column stacks clients top to bottom in col, sized by weight, and stores
+// their rects at the given indices. With equal weights every window gets
+// col.Height / len(indices).
+func (w *windowWeights) column(clients []*Client, indices []int, col Rect, rects []Rect) {
+	total := 0
+	for _, i := range indices {
+		total += w.weight(clients[i].Window)
+	}
+
+	y := col.Y
+	for _, i := range indices {
+		h := uint16(int(col.Height) * w.weight(clients[i].Window) / total)
+		rects[i] = Rect{X: col.X, Y: y, Width: col.Width, Height: h}
+		y += int16(h)
+	}
+}
+
+// span returns the indices from to to-1
+func span(from, to int) []int {
+	indices := make([]int, 0, to-from)
+	for i := from; i < to; i++ {
+		indices = append(indices, i)
+	}
+	return indices
+}
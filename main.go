@@ -1,14 +1,22 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/randr"
 	"github.com/jezek/xgb/xproto"
 )
 
 func main() {
+	configPath := flag.String("config", defaultConfigPath(), "path to the config file")
+	flag.Parse()
+
 	log.SetFlags(log.Ltime | log.Lshortfile)
 	log.Println("Starting gowm...")
 
@@ -25,6 +33,15 @@ func main() {
 		log.Fatalf("Failed to create window manager: %v", err)
 	}
 
+	// Load the config file, keeping the defaults if it is invalid
+	wm.configPath = *configPath
+	if cfg, err := LoadConfig(wm.configPath); err != nil {
+		log.Printf("Failed to load config: %v", err)
+		spawn("notify-send -u critical 'gowm config' %s", shellQuote(err.Error()))
+	} else {
+		wm.setConfig(cfg)
+	}
+
 	// Become the window manager
 	if err := wm.becomeWM(); err != nil {
 		log.Fatalf("Failed to become window manager: %v", err)
@@ -58,63 +75,141 @@ func main() {
 	os.Exit(0)
 }
 
-// eventLoop handles X events
+// eventLoop is the single dispatcher for everything that can touch window
+// manager state. X events arrive from pumpEvents, IPC requests carry their own
+// reply channel, and timers and signals are funneled in as well, so handlers
+// never run concurrently with each other.
 func (wm *WindowManager) eventLoop() {
+	events := make(chan xgb.Event)
+	go wm.pumpEvents(events)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
 	for wm.running {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				log.Println("X connection closed")
+				return
+			}
+			wm.handleEvent(event)
+
+		case req := <-wm.ipcRequests():
+			req.reply <- wm.ipc.handleCommand(req.cmd)
+
+		case f := <-wm.calls:
+			f()
+
+		case sig := <-signals:
+			wm.handleSignal(sig)
+		}
+	}
+}
+
+// pumpEvents reads X events off the connection and hands them to the event
+// loop. It closes events once the connection is gone.
+func (wm *WindowManager) pumpEvents(events chan<- xgb.Event) {
+	defer close(events)
+	for {
 		event, err := wm.conn.WaitForEvent()
 		if err != nil {
 			log.Printf("X error: %v", err)
 			continue
 		}
 		if event == nil {
-			log.Println("X connection closed")
 			return
 		}
+		events <- event
+	}
+}
 
-		switch e := event.(type) {
-		case xproto.MapRequestEvent:
-			wm.handleMapRequest(e)
+// ipcRequests returns the IPC request channel, or nil (which blocks forever
+// in a select) when the IPC server failed to start
+func (wm *WindowManager) ipcRequests() <-chan ipcRequest {
+	if wm.ipc == nil {
+		return nil
+	}
+	return wm.ipc.requests
+}
 
-		case xproto.UnmapNotifyEvent:
-			wm.handleUnmapNotify(e)
+// after schedules f to run on the event loop once d has elapsed
+func (wm *WindowManager) after(d time.Duration, f func()) *time.Timer {
+	return time.AfterFunc(d, func() {
+		wm.calls <- f
+	})
+}
 
-		case xproto.DestroyNotifyEvent:
-			wm.handleDestroyNotify(e)
+// handleSignal handles process signals delivered to the event loop
+func (wm *WindowManager) handleSignal(sig os.Signal) {
+	switch sig {
+	case syscall.SIGINT, syscall.SIGTERM:
+		log.Printf("Received %v", sig)
+		ActionQuit(wm)
+	case syscall.SIGHUP:
+		log.Println("Received SIGHUP, reloading config")
+		wm.reloadConfig()
+	}
+}
 
-		case xproto.ConfigureRequestEvent:
-			wm.handleConfigureRequest(e)
+// handleEvent dispatches a single X event to its handler
+func (wm *WindowManager) handleEvent(event xgb.Event) {
+	switch e := event.(type) {
+	case xproto.MapRequestEvent:
+		wm.handleMapRequest(e)
 
-		case xproto.ConfigureNotifyEvent:
-			wm.handleConfigureNotify(e)
+	case xproto.UnmapNotifyEvent:
+		wm.handleUnmapNotify(e)
 
-		case xproto.KeyPressEvent:
-			wm.handleKeyPress(e)
+	case xproto.DestroyNotifyEvent:
+		wm.handleDestroyNotify(e)
 
-		case xproto.EnterNotifyEvent:
-			wm.handleEnterNotify(e)
+	case xproto.ConfigureRequestEvent:
+		wm.handleConfigureRequest(e)
 
-		case xproto.PropertyNotifyEvent:
-			wm.handlePropertyNotify(e)
+	case xproto.ConfigureNotifyEvent:
+		wm.handleConfigureNotify(e)
 
-		case xproto.ClientMessageEvent:
-			wm.handleClientMessage(e)
+	case xproto.KeyPressEvent:
+		wm.handleKeyPress(e)
 
-		case xproto.ButtonPressEvent:
-			if !wm.gridSelect.HandleButtonPress(e) {
-				wm.handleButtonPress(e)
-			}
+	case xproto.MappingNotifyEvent:
+		wm.handleMappingNotify(e)
 
-		case xproto.ButtonReleaseEvent:
-			wm.handleButtonRelease(e)
+	case xproto.EnterNotifyEvent:
+		wm.handleEnterNotify(e)
 
-		case xproto.MotionNotifyEvent:
-			if !wm.gridSelect.HandleMotionNotify(e) {
-				wm.handleMotionNotify(e)
-			}
+	case xproto.PropertyNotifyEvent:
+		wm.handlePropertyNotify(e)
 
-		case xproto.ExposeEvent:
-			wm.gridSelect.HandleExpose(e)
+	case xproto.ClientMessageEvent:
+		wm.handleClientMessage(e)
+
+	case xproto.ButtonPressEvent:
+		if !wm.gridSelect.HandleButtonPress(e) && !wm.handleTabClick(e) {
+			wm.handleButtonPress(e)
+		}
+
+	case xproto.ButtonReleaseEvent:
+		wm.handleButtonRelease(e)
+
+	case xproto.MotionNotifyEvent:
+		if !wm.gridSelect.HandleMotionNotify(e) {
+			wm.handleMotionNotify(e)
 		}
+
+	case xproto.ExposeEvent:
+		if !wm.gridSelect.HandleExpose(e) {
+			wm.handleTabBarExpose(e)
+		}
+
+	case randr.ScreenChangeNotifyEvent:
+		wm.handleScreenChangeNotify(e)
+
+	case randr.NotifyEvent:
+		// CRTC or output changes, such as a monitor being plugged in
+		wm.handleScreenChange()
 	}
 }
 
@@ -123,7 +218,7 @@ func (wm *WindowManager) handleMapRequest(e xproto.MapRequestEvent) {
 	log.Printf("MapRequest: window=%d", e.Window)
 
 	// Check for override redirect
-	attrs, err := xproto.GetWindowAttributes(wm.conn, e.Window).Reply()
+	attrs, err := wm.x.GetWindowAttributes(e.Window)
 	if err != nil {
 		return
 	}
@@ -132,12 +227,12 @@ func (wm *WindowManager) handleMapRequest(e xproto.MapRequestEvent) {
 	}
 
 	// Map the window first
-	xproto.MapWindow(wm.conn, e.Window)
+	wm.x.MapWindow(e.Window)
 
-	// Check if it's a dock/panel - don't manage but check for struts
+	// Check if it's a dock/panel - don't manage but track its struts
 	windowType := wm.getWindowType(e.Window)
 	if windowType == WindowTypeDock {
-		log.Printf("Dock window detected: %d", e.Window)
+		wm.trackDock(e.Window)
 		wm.updateStruts()
 		wm.tile() // Retile to account for new struts
 		return
@@ -160,6 +255,11 @@ func (wm *WindowManager) handleUnmapNotify(e xproto.UnmapNotifyEvent) {
 		return
 	}
 
+	// A hidden dock no longer reserves space
+	if wm.untrackDock(e.Window) {
+		return
+	}
+
 	client, exists := wm.clients[e.Window]
 	if !exists {
 		return
@@ -173,6 +273,10 @@ func (wm *WindowManager) handleUnmapNotify(e xproto.UnmapNotifyEvent) {
 func (wm *WindowManager) handleDestroyNotify(e xproto.DestroyNotifyEvent) {
 	log.Printf("DestroyNotify: window=%d", e.Window)
 
+	if wm.untrackDock(e.Window) {
+		return
+	}
+
 	// Check if scratchpad was destroyed
 	if wm.scratchpad.window == e.Window {
 		wm.handleScratchpadDestroy(e.Window)
@@ -210,6 +314,9 @@ func (wm *WindowManager) handleConfigureRequest(e xproto.ConfigureRequestEvent)
 		if e.ValueMask&xproto.ConfigWindowBorderWidth != 0 {
 			mask |= xproto.ConfigWindowBorderWidth
 			values = append(values, uint32(e.BorderWidth))
+			if managed {
+				client.BorderWidth = e.BorderWidth
+			}
 		}
 		if e.ValueMask&xproto.ConfigWindowSibling != 0 {
 			mask |= xproto.ConfigWindowSibling
@@ -221,7 +328,7 @@ func (wm *WindowManager) handleConfigureRequest(e xproto.ConfigureRequestEvent)
 		}
 
 		if mask != 0 {
-			xproto.ConfigureWindow(wm.conn, e.Window, mask, values)
+			wm.x.ConfigureWindow(e.Window, mask, values)
 		}
 		return
 	}
@@ -240,11 +347,10 @@ func (wm *WindowManager) sendConfigureNotify(c *Client) {
 		Y:                c.Y,
 		Width:            c.Width,
 		Height:           c.Height,
-		BorderWidth:      wm.config.BorderWidth,
+		BorderWidth:      c.BorderWidth,
 		OverrideRedirect: false,
 	}
-	xproto.SendEvent(wm.conn, false, c.Window,
-		xproto.EventMaskStructureNotify, string(event.Bytes()))
+	wm.x.SendEvent(c.Window, xproto.EventMaskStructureNotify, event.Bytes())
 }
 
 // handleConfigureNotify handles configure notify events
@@ -253,8 +359,30 @@ func (wm *WindowManager) handleConfigureNotify(e xproto.ConfigureNotifyEvent) {
 	if e.Window == wm.root {
 		wm.screen.WidthInPixels = e.Width
 		wm.screen.HeightInPixels = e.Height
-		wm.tile()
+		wm.handleScreenChange()
+	}
+}
+
+// handleScreenChangeNotify handles the root window being resized by RandR
+func (wm *WindowManager) handleScreenChangeNotify(e randr.ScreenChangeNotifyEvent) {
+	width, height := e.Width, e.Height
+	if e.Rotation&(randr.RotationRotate90|randr.RotationRotate270) != 0 {
+		width, height = height, width
+	}
+	wm.screen.WidthInPixels = width
+	wm.screen.HeightInPixels = height
+	wm.handleScreenChange()
+}
+
+// handleScreenChange re-reads the monitors after one was plugged in,
+// unplugged or reconfigured, and retiles if they changed. A newly
+// connected set of monitors gets its display profile first.
+func (wm *WindowManager) handleScreenChange() {
+	wm.autoDisplayProfile()
+	if !wm.updateMonitors() {
+		return
 	}
+	wm.tile()
 }
 
 // handleKeyPress handles key press events
@@ -264,17 +392,40 @@ func (wm *WindowManager) handleKeyPress(e xproto.KeyPressEvent) {
 		return
 	}
 
-	// Clean modifier state (ignore num lock, caps lock)
-	cleanMod := e.State & (xproto.ModMask1 | xproto.ModMask4 |
-		xproto.ModMaskShift | xproto.ModMaskControl)
+	// A submap holds the keyboard until it is left
+	if wm.submap != nil {
+		wm.handleSubmapKey(e)
+		return
+	}
 
-	combo := KeyCombo{Mod: cleanMod, Keycode: e.Detail}
+	combo := KeyCombo{Mod: keyMods(e.State), Keycode: e.Detail}
 
 	if action, ok := wm.config.Keybindings[combo]; ok {
 		action(wm)
 	}
 }
 
+// keyMods cleans a key event's modifier state, ignoring num lock (Mod2) and
+// caps lock
+func keyMods(state uint16) uint16 {
+	return state & (xproto.ModMask1 | xproto.ModMask3 | xproto.ModMask4 |
+		xproto.ModMask5 | xproto.ModMaskShift | xproto.ModMaskControl)
+}
+
+// handleMappingNotify reloads the keymap after a layout change (setxkbmap,
+// xmodmap) and re-grabs every binding against the new keycodes
+func (wm *WindowManager) handleMappingNotify(e xproto.MappingNotifyEvent) {
+	if e.Request != xproto.MappingKeyboard && e.Request != xproto.MappingModifier {
+		return
+	}
+
+	log.Println("Keyboard mapping changed, re-grabbing keys")
+	wm.exitSubmap()
+	wm.initKeyboardMapping()
+	wm.SetupKeybindings()
+	wm.grabKeys()
+}
+
 // handleEnterNotify handles pointer entering a window
 func (wm *WindowManager) handleEnterNotify(e xproto.EnterNotifyEvent) {
 	if !wm.config.FocusFollowsMouse {
@@ -291,8 +442,8 @@ func (wm *WindowManager) handleEnterNotify(e xproto.EnterNotifyEvent) {
 		return
 	}
 
-	// Only focus if on current workspace
-	if client.Workspace == wm.current {
+	// Only focus if on a visible workspace
+	if wm.isVisible(client.Workspace) {
 		wm.focus(client)
 	}
 }
@@ -310,6 +461,20 @@ func (wm *WindowManager) handlePropertyNotify(e xproto.PropertyNotifyEvent) {
 			wm.handleUrgentHint(e.Window)
 		}
 	}
+
+	// Docks may change their struts at any time
+	wm.handleDockProperty(e)
+
+	// Keep the cached names current, and report title changes to IPC
+	// subscribers
+	c, managed := wm.clients[e.Window]
+	if managed && (wm.isTitleAtom(e.Atom) || e.Atom == xproto.AtomWmClass) {
+		wm.updateClientNames(c)
+		if wm.isTitleAtom(e.Atom) {
+			wm.emitWindow("title", "", c)
+			wm.redrawTabBar(c.Workspace)
+		}
+	}
 }
 
 // handleClientMessage handles client messages (EWMH requests)
@@ -326,7 +491,7 @@ func (wm *WindowManager) handleClientMessage(e xproto.ClientMessageEvent) {
 	case wm.atoms.NET_ACTIVE_WINDOW:
 		// Focus requested window
 		if client, exists := wm.clients[e.Window]; exists {
-			if client.Workspace != wm.current {
+			if !wm.isVisible(client.Workspace) {
 				wm.switchToWorkspace(client.Workspace)
 			}
 			wm.focus(client)
@@ -348,6 +513,24 @@ func (wm *WindowManager) handleClientMessage(e xproto.ClientMessageEvent) {
 	}
 }
 
+// enterFullscreen makes a client cover its monitor: floating, no border,
+// raised and focused
+func (wm *WindowManager) enterFullscreen(client *Client) {
+	g := wm.monitorFor(client).Geometry
+	client.Floating = true
+	client.X, client.Y = g.X, g.Y
+	client.Width, client.Height = g.Width, g.Height
+	client.BorderWidth = 0
+	wm.setFullscreenState(client.Window, true)
+	wm.x.ConfigureWindow(client.Window,
+		xproto.ConfigWindowX|xproto.ConfigWindowY|
+			xproto.ConfigWindowWidth|xproto.ConfigWindowHeight|
+			xproto.ConfigWindowBorderWidth|xproto.ConfigWindowStackMode,
+		[]uint32{uint32(client.X), uint32(client.Y), uint32(client.Width), uint32(client.Height),
+			0, xproto.StackModeAbove})
+	wm.focus(client)
+}
+
 // handleNetWMState handles _NET_WM_STATE client messages
 func (wm *WindowManager) handleNetWMState(e xproto.ClientMessageEvent) {
 	client, exists := wm.clients[e.Window]
@@ -373,43 +556,18 @@ func (wm *WindowManager) handleNetWMState(e xproto.ClientMessageEvent) {
 				client.Floating = false
 				// Restore border and clear fullscreen state
 				wm.setFullscreenState(client.Window, false)
-				xproto.ConfigureWindow(wm.conn, client.Window,
-					xproto.ConfigWindowBorderWidth, []uint32{uint32(wm.config.BorderWidth)})
+				wm.restoreBorder(client)
 				wm.tile()
 			case _NET_WM_STATE_ADD:
-				client.Floating = true
-				// Make fullscreen - no border, full screen, raised
-				client.X = 0
-				client.Y = 0
-				client.Width = wm.screen.WidthInPixels
-				client.Height = wm.screen.HeightInPixels
-				wm.setFullscreenState(client.Window, true)
-				xproto.ConfigureWindow(wm.conn, client.Window,
-					xproto.ConfigWindowX|xproto.ConfigWindowY|
-						xproto.ConfigWindowWidth|xproto.ConfigWindowHeight|
-						xproto.ConfigWindowBorderWidth|xproto.ConfigWindowStackMode,
-					[]uint32{0, 0, uint32(client.Width), uint32(client.Height), 0, xproto.StackModeAbove})
-				wm.focus(client)
+				wm.enterFullscreen(client)
 			case _NET_WM_STATE_TOGGLE:
 				client.Floating = !client.Floating
 				if client.Floating {
-					// Going fullscreen
-					client.X = 0
-					client.Y = 0
-					client.Width = wm.screen.WidthInPixels
-					client.Height = wm.screen.HeightInPixels
-					wm.setFullscreenState(client.Window, true)
-					xproto.ConfigureWindow(wm.conn, client.Window,
-						xproto.ConfigWindowX|xproto.ConfigWindowY|
-							xproto.ConfigWindowWidth|xproto.ConfigWindowHeight|
-							xproto.ConfigWindowBorderWidth|xproto.ConfigWindowStackMode,
-						[]uint32{0, 0, uint32(client.Width), uint32(client.Height), 0, xproto.StackModeAbove})
-					wm.focus(client)
+					wm.enterFullscreen(client)
 				} else {
 					// Leaving fullscreen
 					wm.setFullscreenState(client.Window, false)
-					xproto.ConfigureWindow(wm.conn, client.Window,
-						xproto.ConfigWindowBorderWidth, []uint32{uint32(wm.config.BorderWidth)})
+					wm.restoreBorder(client)
 					wm.tile()
 				}
 			}
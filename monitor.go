@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// Monitor is a screen area showing one workspace. Each monitor tiles its
+// workspace independently, xmonad-style: switching to a workspace that is
+// visible on another monitor swaps the two.
+type Monitor struct {
+	Name      string
+	Primary   bool
+	Geometry  Rect      // Position and size in root window coordinates
+	Workspace int       // Index of the workspace shown here
+	Struts    [4]uint32 // Space reserved by docks: left, right, top, bottom
+}
+
+// MonitorSplit carves a physical monitor, such as an ultrawide, into
+// virtual monitors side by side. Each part shows its own workspace.
+type MonitorSplit struct {
+	Monitor string // RandR monitor name
+	Sizes   []int  // Percent of the width of each part, left to right
+}
+
+// split divides m into the parts, named "DP-1:1", "DP-1:2" and so on. The
+// widest part stands in for a primary monitor.
+func (s *MonitorSplit) split(m *Monitor) []*Monitor {
+	g := m.Geometry
+	widest := 0
+	var parts []*Monitor
+	percent := 0
+	for i, size := range s.Sizes {
+		x0 := int(g.Width) * percent / 100
+		percent += size
+		x1 := int(g.Width) * percent / 100
+		if i == len(s.Sizes)-1 {
+			x1 = int(g.Width)
+		}
+		if size > s.Sizes[widest] {
+			widest = i
+		}
+		parts = append(parts, &Monitor{
+			Name:     fmt.Sprintf("%s:%d", m.Name, i+1),
+			Geometry: Rect{X: g.X + int16(x0), Y: g.Y, Width: uint16(x1 - x0), Height: g.Height},
+		})
+	}
+	parts[widest].Primary = m.Primary
+	return parts
+}
+
+// Contains reports whether the point x, y lies on the monitor
+func (m *Monitor) Contains(x, y int16) bool {
+	g := m.Geometry
+	return int(x) >= int(g.X) && int(x) < int(g.X)+int(g.Width) &&
+		int(y) >= int(g.Y) && int(y) < int(g.Y)+int(g.Height)
+}
+
+// queryMonitors asks the backend for the monitor layout, falling back to a
+// single monitor called "screen" covering the root window, and splits
+// monitors into virtual ones as configured. Monitors are ordered primary
+// first, then left to right and top to bottom, so the primary monitor
+// starts on workspace 1.
+func (wm *WindowManager) queryMonitors() []*Monitor {
+	monitors, err := wm.x.Monitors()
+	if err != nil || len(monitors) == 0 {
+		if err != nil {
+			log.Printf("Monitor detection failed, using the whole screen: %v", err)
+		}
+		monitors = []*Monitor{{
+			Name:     "screen",
+			Primary:  true,
+			Geometry: Rect{Width: wm.screen.WidthInPixels, Height: wm.screen.HeightInPixels},
+		}}
+	}
+
+	// Carve up monitors that are split into virtual ones
+	var split []*Monitor
+	for _, m := range monitors {
+		split = append(split, m)
+		for i := range wm.config.MonitorSplits {
+			if s := &wm.config.MonitorSplits[i]; s.Monitor == m.Name {
+				split = append(split[:len(split)-1], s.split(m)...)
+				break
+			}
+		}
+	}
+	monitors = split
+
+	sort.SliceStable(monitors, func(i, j int) bool {
+		a, b := monitors[i], monitors[j]
+		if a.Primary != b.Primary {
+			return a.Primary
+		}
+		if a.Geometry.X != b.Geometry.X {
+			return a.Geometry.X < b.Geometry.X
+		}
+		return a.Geometry.Y < b.Geometry.Y
+	})
+
+	// Every monitor needs a workspace of its own
+	if len(monitors) > len(wm.workspaces) {
+		log.Printf("%d monitors but only %d workspaces, ignoring the rest",
+			len(monitors), len(wm.workspaces))
+		monitors = monitors[:len(wm.workspaces)]
+	}
+	return monitors
+}
+
+// updateMonitors re-reads the monitor layout. Monitors keep their
+// workspace by name. Workspaces whose monitor went away move to new
+// monitors first, and new monitors then show the lowest hidden workspaces.
+// The current workspace always stays visible, taking over the primary
+// monitor if its own was unplugged, so its windows and focus survive.
+// It reports whether the monitors changed; if not, nothing is touched.
+func (wm *WindowManager) updateMonitors() bool {
+	monitors := wm.queryMonitors()
+	if sameMonitors(monitors, wm.monitors) {
+		return false
+	}
+
+	old := make(map[string]*Monitor)
+	for _, m := range wm.monitors {
+		old[m.Name] = m
+	}
+
+	shown := make(map[int]bool)
+	for _, m := range monitors {
+		m.Workspace = -1
+		if prev, ok := old[m.Name]; ok {
+			m.Workspace = prev.Workspace
+			shown[m.Workspace] = true
+		}
+	}
+
+	// Workspaces left without a monitor, the current one first
+	var orphans []int
+	for _, prev := range wm.monitors {
+		switch {
+		case shown[prev.Workspace]:
+		case prev.Workspace == wm.current:
+			orphans = append([]int{prev.Workspace}, orphans...)
+		default:
+			orphans = append(orphans, prev.Workspace)
+		}
+	}
+
+	for _, m := range monitors {
+		if m.Workspace >= 0 {
+			continue
+		}
+		if len(orphans) > 0 {
+			m.Workspace, orphans = orphans[0], orphans[1:]
+		} else {
+			m.Workspace = 0
+			for shown[m.Workspace] {
+				m.Workspace++
+			}
+		}
+		shown[m.Workspace] = true
+	}
+	if !shown[wm.current] {
+		delete(shown, monitors[0].Workspace)
+		monitors[0].Workspace = wm.current
+		shown[wm.current] = true
+	}
+
+	// Hide workspaces that are no longer shown
+	for _, prev := range wm.monitors {
+		if !shown[prev.Workspace] {
+			for _, c := range wm.workspaces[prev.Workspace].Clients {
+				wm.x.UnmapWindow(c.Window)
+			}
+		}
+	}
+
+	// Place windows while the old monitors are still known, so floating
+	// windows keep their spot relative to the monitor they were on
+	for _, m := range monitors {
+		wm.showWorkspace(m)
+		log.Printf("Monitor %s: %dx%d+%d+%d, workspace %d", m.Name,
+			m.Geometry.Width, m.Geometry.Height, m.Geometry.X, m.Geometry.Y, m.Workspace+1)
+	}
+	wm.monitors = monitors
+	wm.updateStruts()
+	return true
+}
+
+// sameMonitors reports whether two monitor lists have the same names,
+// geometry and primary monitor, in the same order
+func sameMonitors(a, b []*Monitor) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Geometry != b[i].Geometry || a[i].Primary != b[i].Primary {
+			return false
+		}
+	}
+	return true
+}
+
+// monitorShowing returns the monitor showing workspace index, or nil if the
+// workspace is hidden
+func (wm *WindowManager) monitorShowing(index int) *Monitor {
+	for _, m := range wm.monitors {
+		if m.Workspace == index {
+			return m
+		}
+	}
+	return nil
+}
+
+// isVisible reports whether a workspace is shown on some monitor
+func (wm *WindowManager) isVisible(index int) bool {
+	return wm.monitorShowing(index) != nil
+}
+
+// currentMonitor returns the monitor showing the current workspace
+func (wm *WindowManager) currentMonitor() *Monitor {
+	if m := wm.monitorShowing(wm.current); m != nil {
+		return m
+	}
+	return wm.monitors[0]
+}
+
+// monitorFor returns the monitor a client is shown on, or the current
+// monitor if its workspace is hidden
+func (wm *WindowManager) monitorFor(c *Client) *Monitor {
+	if m := wm.monitorShowing(c.Workspace); m != nil {
+		return m
+	}
+	return wm.currentMonitor()
+}
+
+// monitorAt returns the monitor containing a point, or nil
+func (wm *WindowManager) monitorAt(x, y int16) *Monitor {
+	for _, m := range wm.monitors {
+		if m.Contains(x, y) {
+			return m
+		}
+	}
+	return nil
+}
+
+// relativeMonitor returns the monitor delta places after the current one,
+// wrapping around
+func (wm *WindowManager) relativeMonitor(delta int) *Monitor {
+	n := len(wm.monitors)
+	for i, m := range wm.monitors {
+		if m == wm.currentMonitor() {
+			return wm.monitors[((i+delta)%n+n)%n]
+		}
+	}
+	return wm.monitors[0]
+}
+
+// focusMonitor makes the workspace shown on m current and focuses its
+// last focused window
+func (wm *WindowManager) focusMonitor(m *Monitor) {
+	if m.Workspace == wm.current {
+		return
+	}
+	wm.current = m.Workspace
+	wm.focusCurrent()
+	wm.updateCurrentDesktop()
+	log.Printf("Focused monitor %s", m.Name)
+}
+
+// moveToMonitor moves a client to the workspace shown on m, where it is
+// tiled within that monitor
+func (wm *WindowManager) moveToMonitor(c *Client, m *Monitor) {
+	wm.moveToWorkspace(c, m.Workspace)
+}
+
+// swapMonitors swaps the workspaces shown on two monitors. The current
+// workspace stays current, so focus moves along with it.
+func (wm *WindowManager) swapMonitors(a, b *Monitor) {
+	if a == b {
+		return
+	}
+	a.Workspace, b.Workspace = b.Workspace, a.Workspace
+	wm.showWorkspace(a)
+	wm.showWorkspace(b)
+	wm.tile()
+	wm.updateCurrentDesktop()
+	log.Printf("Swapped monitors %s and %s", a.Name, b.Name)
+}
+
+// showWorkspace maps the windows of the workspace shown on m and moves its
+// floating and fullscreen windows onto the monitor
+func (wm *WindowManager) showWorkspace(m *Monitor) {
+	for _, c := range wm.workspaces[m.Workspace].Clients {
+		wm.placeOnMonitor(c, m)
+		wm.x.MapWindow(c.Window)
+	}
+}
+
+// placeOnMonitor moves a floating window that lies on another monitor to
+// the same spot on m, and resizes fullscreen windows to cover m. Tiled
+// windows are placed by tile.
+func (wm *WindowManager) placeOnMonitor(c *Client, m *Monitor) {
+	if !c.Floating {
+		return
+	}
+
+	g := m.Geometry
+	if wm.hasFullscreenState(c.Window) {
+		c.X, c.Y, c.Width, c.Height = g.X, g.Y, g.Width, g.Height
+	} else {
+		cx := c.X + int16(c.Width/2)
+		cy := c.Y + int16(c.Height/2)
+		if m.Contains(cx, cy) {
+			return
+		}
+		if from := wm.monitorAt(cx, cy); from != nil {
+			c.X += g.X - from.Geometry.X
+			c.Y += g.Y - from.Geometry.Y
+		} else {
+			c.X = g.X + int16(g.Width/2) - int16(c.Width/2)
+			c.Y = g.Y + int16(g.Height/2) - int16(c.Height/2)
+		}
+	}
+
+	wm.x.ConfigureWindow(c.Window,
+		xproto.ConfigWindowX|xproto.ConfigWindowY|
+			xproto.ConfigWindowWidth|xproto.ConfigWindowHeight,
+		[]uint32{uint32(c.X), uint32(c.Y), uint32(c.Width), uint32(c.Height)})
+}
+
+// monitorStruts works out how much of m the docks reserve
+func (wm *WindowManager) monitorStruts(m *Monitor) [4]uint32 {
+	var reserved [4]uint32
+	for _, d := range wm.docks {
+		edges := wm.strutOn(d.Strut, m)
+		for i := range reserved {
+			reserved[i] = max(reserved[i], edges[i])
+		}
+	}
+	return reserved
+}
+
+// strutOn works out how much of m a strut reserves on each edge. Struts
+// are measured from the edges of the root window and only count where
+// their range runs along m, so a top bar on a monitor below another, or on
+// the monitor beside it, reserves nothing here.
+func (wm *WindowManager) strutOn(s Strut, m *Monitor) [4]uint32 {
+	g := m.Geometry
+	x0, x1 := int(g.X), int(g.X)+int(g.Width)-1
+	y0, y1 := int(g.Y), int(g.Y)+int(g.Height)-1
+	rootW := int(wm.screen.WidthInPixels)
+	rootH := int(wm.screen.HeightInPixels)
+
+	overlaps := func(start, end uint32, lo, hi int) bool {
+		return int(start) <= hi && int(end) >= lo
+	}
+	clamp := func(v, max int) uint32 {
+		if v < 0 {
+			return 0
+		}
+		if v > max {
+			return uint32(max)
+		}
+		return uint32(v)
+	}
+
+	var edges [4]uint32
+	if s.Left > 0 && overlaps(s.LeftStartY, s.LeftEndY, y0, y1) {
+		edges[0] = clamp(int(s.Left)-x0, int(g.Width))
+	}
+	if s.Right > 0 && overlaps(s.RightStartY, s.RightEndY, y0, y1) {
+		edges[1] = clamp(x1+1-(rootW-int(s.Right)), int(g.Width))
+	}
+	if s.Top > 0 && overlaps(s.TopStartX, s.TopEndX, x0, x1) {
+		edges[2] = clamp(int(s.Top)-y0, int(g.Height))
+	}
+	if s.Bottom > 0 && overlaps(s.BottomStartX, s.BottomEndX, x0, x1) {
+		edges[3] = clamp(y1+1-(rootH-int(s.Bottom)), int(g.Height))
+	}
+	return edges
+}
+
+// workArea returns the part of m not reserved by docks
+func (wm *WindowManager) workArea(m *Monitor) Rect {
+	g := m.Geometry
+	var left, right, top, bottom uint32
+	if wm.strutsEnabled {
+		left, right, top, bottom = m.Struts[0], m.Struts[1], m.Struts[2], m.Struts[3]
+	}
+
+	// Docks reserving more than the whole monitor leave it empty
+	left = min(left, uint32(g.Width))
+	right = min(right, uint32(g.Width)-left)
+	top = min(top, uint32(g.Height))
+	bottom = min(bottom, uint32(g.Height)-top)
+
+	return Rect{
+		X:      g.X + int16(left),
+		Y:      g.Y + int16(top),
+		Width:  g.Width - uint16(left+right),
+		Height: g.Height - uint16(top+bottom),
+	}
+}
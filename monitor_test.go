@@ -0,0 +1,368 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/randr"
+)
+
+// newDualMonitorWM starts a window manager on a 1920x1080 primary monitor
+// with a 1280x1024 monitor to its right
+func newDualMonitorWM(t *testing.T) (*WindowManager, *fakeBackend) {
+	t.Helper()
+
+	f := newFakeBackend(3200, 1080)
+	f.monitors = []Monitor{
+		{Name: "HDMI-1", Geometry: Rect{X: 1920, Width: 1280, Height: 1024}},
+		{Name: "DP-1", Primary: true, Geometry: Rect{Width: 1920, Height: 1080}},
+	}
+	return startTestWM(t, f)
+}
+
+func TestMonitorsGetOwnWorkspaces(t *testing.T) {
+	wm, _ := newDualMonitorWM(t)
+
+	if len(wm.monitors) != 2 {
+		t.Fatalf("got %d monitors, want 2", len(wm.monitors))
+	}
+	// Primary first, so it starts on workspace 1
+	if m := wm.monitors[0]; m.Name != "DP-1" || m.Workspace != 0 {
+		t.Errorf("first monitor = %s on workspace %d", m.Name, m.Workspace+1)
+	}
+	if m := wm.monitors[1]; m.Name != "HDMI-1" || m.Workspace != 1 {
+		t.Errorf("second monitor = %s on workspace %d", m.Name, m.Workspace+1)
+	}
+}
+
+func TestMonitorFallsBackToScreen(t *testing.T) {
+	wm, _ := newTestWM(t)
+
+	if len(wm.monitors) != 1 || wm.monitors[0].Geometry != (Rect{Width: 1920, Height: 1080}) {
+		t.Errorf("monitors without RandR = %+v", wm.monitors)
+	}
+}
+
+func TestMonitorsTileIndependently(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	left := mapClient(wm, f)
+	right := mapClient(wm, f)
+	wm.moveToWorkspace(wm.clients[right], 1)
+
+	if got := f.window(left).geom; got != (Rect{X: 8, Y: 8, Width: 1900, Height: 1060}) {
+		t.Errorf("window on DP-1 = %+v", got)
+	}
+	if got := f.window(right).geom; got != (Rect{X: 1928, Y: 8, Width: 1260, Height: 1004}) {
+		t.Errorf("window on HDMI-1 = %+v", got)
+	}
+	if !f.window(right).mapped {
+		t.Error("window moved to a visible workspace was unmapped")
+	}
+}
+
+func TestSwitchToVisibleWorkspaceSwaps(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	win := mapClient(wm, f)
+	wm.moveToWorkspace(wm.clients[win], 1)
+	wm.switchToWorkspace(1)
+
+	if wm.monitors[0].Workspace != 1 || wm.monitors[1].Workspace != 0 {
+		t.Errorf("workspaces after swap: DP-1 %d, HDMI-1 %d",
+			wm.monitors[0].Workspace+1, wm.monitors[1].Workspace+1)
+	}
+	if got := f.window(win).geom; got.X != 8 || got.Width != 1900 {
+		t.Errorf("window not retiled on DP-1: %+v", got)
+	}
+	if !f.window(win).mapped {
+		t.Error("window unmapped by swap")
+	}
+}
+
+func TestFocusOnOtherMonitorChangesCurrent(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	mapClient(wm, f)
+	win := mapClient(wm, f)
+	wm.moveToWorkspace(wm.clients[win], 1)
+	wm.focus(wm.clients[win])
+
+	if wm.current != 1 {
+		t.Errorf("current workspace = %d, want 2", wm.current+1)
+	}
+	if wm.currentMonitor().Name != "HDMI-1" {
+		t.Errorf("current monitor = %s, want HDMI-1", wm.currentMonitor().Name)
+	}
+}
+
+func TestIPCQueryMonitors(t *testing.T) {
+	wm, _ := newDualMonitorWM(t)
+	ipc := &IPCServer{wm: wm}
+
+	resp := ipc.handleCommand("query monitors")
+	if !resp.Success {
+		t.Fatalf("query monitors failed: %s", resp.Message)
+	}
+	monitors := resp.Data.([]MonitorInfo)
+	if len(monitors) != 2 {
+		t.Fatalf("got %d monitors", len(monitors))
+	}
+	if m := monitors[1]; m.Name != "HDMI-1" || m.X != 1920 || m.Workspace != 2 || m.Focused {
+		t.Errorf("HDMI-1 = %+v", m)
+	}
+	if !monitors[0].Focused {
+		t.Error("primary monitor not focused")
+	}
+}
+
+func TestUnplugKeepsCurrentWorkspace(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	hidden := mapClient(wm, f)
+	first := mapClient(wm, f)
+	second := mapClient(wm, f)
+	wm.moveToWorkspace(wm.clients[first], 1)
+	wm.moveToWorkspace(wm.clients[second], 1)
+	wm.focus(wm.clients[first])
+
+	// Unplug HDMI-1, which shows the current workspace
+	f.monitors = f.monitors[1:]
+	wm.handleEvent(randr.ScreenChangeNotifyEvent{Width: 1920, Height: 1080})
+
+	if len(wm.monitors) != 1 || wm.monitors[0].Workspace != 1 {
+		t.Fatalf("DP-1 shows workspace %d, want 2", wm.monitors[0].Workspace+1)
+	}
+	if wm.current != 1 || wm.focused != wm.clients[first] {
+		t.Errorf("current = %d, focused = %v", wm.current+1, wm.focused)
+	}
+	clients := wm.workspaces[1].Clients
+	if len(clients) != 2 || clients[0].Window != first || clients[1].Window != second {
+		t.Errorf("client order changed")
+	}
+	if got := f.window(first).geom; got != (Rect{X: 8, Y: 8, Width: 944, Height: 1060}) {
+		t.Errorf("window not retiled on DP-1: %+v", got)
+	}
+	if f.window(hidden).mapped {
+		t.Error("window of the displaced workspace still mapped")
+	}
+
+	// Plugging it back in shows the lowest hidden workspace there
+	f.monitors = []Monitor{
+		{Name: "DP-1", Primary: true, Geometry: Rect{Width: 1920, Height: 1080}},
+		{Name: "HDMI-1", Geometry: Rect{X: 1920, Width: 1280, Height: 1024}},
+	}
+	wm.handleEvent(randr.NotifyEvent{})
+
+	if wm.monitors[1].Workspace != 0 || !f.window(hidden).mapped {
+		t.Errorf("HDMI-1 shows workspace %d, want 1", wm.monitors[1].Workspace+1)
+	}
+	if wm.current != 1 {
+		t.Errorf("current = %d after plugging in, want 2", wm.current+1)
+	}
+}
+
+func TestUnplugOtherMonitorHidesItsWorkspace(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	win := mapClient(wm, f)
+	wm.moveToWorkspace(wm.clients[win], 1)
+
+	f.monitors = f.monitors[1:]
+	wm.handleScreenChange()
+
+	if wm.current != 0 || wm.monitors[0].Workspace != 0 {
+		t.Errorf("current workspace moved to %d", wm.current+1)
+	}
+	if f.window(win).mapped {
+		t.Error("window on the unplugged monitor still mapped")
+	}
+}
+
+func TestUnchangedScreenLeavesWindowsAlone(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+	win := mapClient(wm, f)
+	monitors := wm.monitors
+
+	// A window that moved itself stays put when RandR repeats itself
+	f.window(win).geom = Rect{X: 100, Y: 100, Width: 50, Height: 50}
+	wm.handleEvent(randr.NotifyEvent{})
+	if got := f.window(win).geom; got != (Rect{X: 100, Y: 100, Width: 50, Height: 50}) {
+		t.Errorf("window retiled to %+v without a monitor change", got)
+	}
+	if &wm.monitors[0] != &monitors[0] {
+		t.Error("monitors replaced without a change")
+	}
+}
+
+func TestScreenChangeNotifyResizesRoot(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	if !f.monitorEvents {
+		t.Error("RandR notifications not selected")
+	}
+	wm.handleEvent(randr.ScreenChangeNotifyEvent{
+		Width: 1080, Height: 1920, Rotation: randr.RotationRotate90,
+	})
+	if wm.screen.WidthInPixels != 1920 || wm.screen.HeightInPixels != 1080 {
+		t.Errorf("root size = %dx%d", wm.screen.WidthInPixels, wm.screen.HeightInPixels)
+	}
+}
+
+func TestFocusNextMonitor(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	mapClient(wm, f)
+	ActionFocusNextMonitor(wm)
+
+	if wm.current != 1 || wm.focused != nil {
+		t.Errorf("current = %d, focused = %v; want empty workspace 2", wm.current+1, wm.focused)
+	}
+	ActionFocusNextMonitor(wm)
+	if wm.current != 0 || wm.focused == nil {
+		t.Errorf("focus did not wrap back to DP-1")
+	}
+}
+
+func TestMoveToNextMonitor(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	stay := mapClient(wm, f)
+	move := mapClient(wm, f)
+	ActionMoveToNextMonitor(wm)
+
+	if wm.clients[move].Workspace != 1 {
+		t.Fatalf("window on workspace %d, want 2", wm.clients[move].Workspace+1)
+	}
+	if got := f.window(move).geom; got != (Rect{X: 1928, Y: 8, Width: 1260, Height: 1004}) {
+		t.Errorf("moved window = %+v, want tiled on HDMI-1", got)
+	}
+	if wm.focused != wm.clients[stay] || f.focus != stay {
+		t.Error("focus did not stay on DP-1")
+	}
+}
+
+func TestSwapMonitors(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	win := mapClient(wm, f)
+	ipc := &IPCServer{wm: wm}
+	if resp := ipc.handleCommand("monitor swap"); !resp.Success {
+		t.Fatalf("monitor swap failed: %s", resp.Message)
+	}
+
+	if wm.monitors[0].Workspace != 1 || wm.monitors[1].Workspace != 0 {
+		t.Errorf("workspaces not swapped")
+	}
+	if wm.current != 0 || wm.currentMonitor().Name != "HDMI-1" || wm.focused != wm.clients[win] {
+		t.Error("focus did not follow the workspace")
+	}
+	if got := f.window(win).geom; got.X != 1928 {
+		t.Errorf("window not retiled on HDMI-1: %+v", got)
+	}
+}
+
+func TestStrutPartialOnlyReservesItsMonitor(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	// Top bar across DP-1, bottom bar across HDMI-1, which is shorter
+	// than the root window
+	mapDock(wm, f, 0, 0, 30, 0, 0, 0, 0, 0, 0, 1919, 0, 0)
+	mapDock(wm, f, 0, 0, 0, 80, 0, 0, 0, 0, 0, 0, 1920, 3199)
+
+	if got := wm.monitors[0].Struts; got != [4]uint32{0, 0, 30, 0} {
+		t.Errorf("DP-1 struts = %v", got)
+	}
+	if got := wm.monitors[1].Struts; got != [4]uint32{0, 0, 0, 24} {
+		t.Errorf("HDMI-1 struts = %v", got)
+	}
+
+	win := mapClient(wm, f)
+	wm.moveToWorkspace(wm.clients[win], 1)
+	if got := f.window(win).geom; got != (Rect{X: 1928, Y: 8, Width: 1260, Height: 980}) {
+		t.Errorf("window on HDMI-1 = %+v", got)
+	}
+}
+
+func TestStrutWithoutRangeCoversEveryMonitor(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	mapDock(wm, f, 0, 0, 30, 0)
+
+	for _, m := range wm.monitors {
+		if m.Struts != [4]uint32{0, 0, 30, 0} {
+			t.Errorf("%s struts = %v", m.Name, m.Struts)
+		}
+	}
+}
+
+func TestOversizedStrutsLeaveEmptyWorkArea(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	mapDock(wm, f, 1500, 1500, 700, 700)
+	if got := wm.workArea(wm.monitors[0]); got != (Rect{X: 1500, Y: 700, Width: 0, Height: 0}) {
+		t.Errorf("work area = %+v, want an empty one", got)
+	}
+
+	mapDock(wm, f, 0, 0, 0, 5000)
+	if got := wm.workArea(wm.monitors[0]); got.Width > 1920 || got.Height > 1080 {
+		t.Errorf("work area = %+v wraps around", got)
+	}
+}
+
+func TestWorkareaFollowsMonitors(t *testing.T) {
+	wm, f := newDualMonitorWM(t)
+
+	mapDock(wm, f, 0, 0, 30, 0, 0, 0, 0, 0, 0, 1919, 0, 0)
+
+	prop, ok := f.window(f.root).props[f.atom("_NET_WORKAREA")]
+	if !ok {
+		t.Fatal("_NET_WORKAREA not set")
+	}
+	got := decodeCardinals(prop.data)
+	if len(got) != 4*len(wm.workspaces) {
+		t.Fatalf("_NET_WORKAREA has %d values", len(got))
+	}
+	want := [][]uint32{
+		{0, 30, 1920, 1050},   // Workspace 1 on DP-1
+		{1920, 0, 1280, 1024}, // Workspace 2 on HDMI-1
+		{0, 30, 1920, 1050},   // Hidden workspaces use the primary
+	}
+	for i, w := range want {
+		for j := range w {
+			if got[i*4+j] != w[j] {
+				t.Errorf("workarea of desktop %d = %v, want %v", i+1, got[i*4:i*4+4], w)
+				break
+			}
+		}
+	}
+}
+
+func TestSplitMonitor(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.applyConfig(mustParseConfig(t, "[[split]]\nmonitor = \"screen\"\nsizes = [25, 50, 25]"))
+
+	want := []struct {
+		name    string
+		geom    Rect
+		primary bool
+	}{
+		{"screen:2", Rect{X: 480, Width: 960, Height: 1080}, true},
+		{"screen:1", Rect{Width: 480, Height: 1080}, false},
+		{"screen:3", Rect{X: 1440, Width: 480, Height: 1080}, false},
+	}
+	if len(wm.monitors) != len(want) {
+		t.Fatalf("got %d monitors, want %d", len(wm.monitors), len(want))
+	}
+	for i, w := range want {
+		m := wm.monitors[i]
+		if m.Name != w.name || m.Geometry != w.geom || m.Primary != w.primary || m.Workspace != i {
+			t.Errorf("monitor %d = %+v, want %s %+v on workspace %d", i, m, w.name, w.geom, i+1)
+		}
+	}
+
+	// Windows tile inside their part only
+	win := mapClient(wm, f)
+	if got := f.window(win).geom; got != (Rect{X: 488, Y: 8, Width: 940, Height: 1060}) {
+		t.Errorf("window on the middle part = %+v", got)
+	}
+}
@@ -19,71 +19,192 @@ type DragState struct {
 	IsResize bool // true for resize, false for move
 }
 
-// grabMouseButtons sets up mouse button grabs for window operations
+// MouseTarget is where a mouse binding applies
+type MouseTarget int
+
+const (
+	// MouseOnClient buttons are grabbed on every managed window
+	MouseOnClient MouseTarget = iota
+	// MouseOnRoot buttons act on the desktop background. With a modifier
+	// they are grabbed on the root window and work anywhere on screen.
+	MouseOnRoot
+)
+
+// MouseAction handles a bound button press. client is the window under the
+// pointer, or nil on the desktop background.
+type MouseAction func(wm *WindowManager, client *Client, e xproto.ButtonPressEvent)
+
+// MouseButton identifies a bound button: modifiers, button and target
+type MouseButton struct {
+	Mod    uint16
+	Button byte
+	Target MouseTarget
+}
+
+// MouseBinding binds a modifier mask and button to a mouse action. A nil
+// Action removes an earlier binding for the same button.
+type MouseBinding struct {
+	Mod    uint16
+	Button byte
+	Target MouseTarget
+	Action MouseAction
+}
+
+// DefaultMouseBindings returns the built-in mouse bindings for cfg's
+// modifier key. Later entries override earlier ones for the same button.
+func DefaultMouseBindings(cfg *Config) []MouseBinding {
+	mod := cfg.ModKey
+
+	return []MouseBinding{
+		// Drag floating windows, floating tiled ones first
+		{mod, xproto.ButtonIndex1, MouseOnClient, MouseMove},
+		{mod, xproto.ButtonIndex3, MouseOnClient, MouseResize},
+
+		// Scroll on the desktop to cycle workspaces
+		{0, xproto.ButtonIndex4, MouseOnRoot, MouseRun(ActionPrevWorkspace)},
+		{0, xproto.ButtonIndex5, MouseOnRoot, MouseRun(ActionNextWorkspace)},
+	}
+}
+
+// MouseMove starts dragging the clicked window
+func MouseMove(wm *WindowManager, client *Client, e xproto.ButtonPressEvent) {
+	wm.startDrag(client, e, false)
+}
+
+// MouseResize starts resizing the clicked window from its bottom-right corner
+func MouseResize(wm *WindowManager, client *Client, e xproto.ButtonPressEvent) {
+	wm.startDrag(client, e, true)
+}
+
+// MouseRun returns a mouse action that focuses the clicked window, if any,
+// and runs a keyboard action
+func MouseRun(action Action) MouseAction {
+	return func(wm *WindowManager, client *Client, e xproto.ButtonPressEvent) {
+		if client != nil {
+			wm.focus(client)
+		}
+		action(wm)
+	}
+}
+
+// resolveMouseBindings builds the lookup table for button presses, letting
+// later bindings replace or remove earlier ones
+func resolveMouseBindings(bindings []MouseBinding) map[MouseButton]MouseAction {
+	resolved := make(map[MouseButton]MouseAction)
+	for _, b := range bindings {
+		button := MouseButton{Mod: b.Mod, Button: b.Button, Target: b.Target}
+		if b.Action == nil {
+			delete(resolved, button)
+		} else {
+			resolved[button] = b.Action
+		}
+	}
+	return resolved
+}
+
+// grabMouseButtons grabs the client mouse bindings on a managed window
 func (wm *WindowManager) grabMouseButtons(win xproto.Window) {
-	// Grab Super+Button1 for move
-	xproto.GrabButton(wm.conn, true, win,
-		xproto.EventMaskButtonPress|xproto.EventMaskButtonRelease|xproto.EventMaskPointerMotion,
-		xproto.GrabModeAsync, xproto.GrabModeAsync,
-		wm.root, 0,
-		xproto.ButtonIndex1, xproto.ModMask4)
-
-	// Grab Super+Button3 for resize
-	xproto.GrabButton(wm.conn, true, win,
-		xproto.EventMaskButtonPress|xproto.EventMaskButtonRelease|xproto.EventMaskPointerMotion,
-		xproto.GrabModeAsync, xproto.GrabModeAsync,
-		wm.root, 0,
-		xproto.ButtonIndex3, xproto.ModMask4)
+	for button := range wm.mouseBindings {
+		if button.Target != MouseOnClient {
+			continue
+		}
+		for _, mod := range lockModifiers {
+			wm.x.GrabButton(win, button.Mod|mod, button.Button)
+		}
+	}
+}
+
+// grabRootButtons grabs the root mouse bindings that carry a modifier.
+// Unmodified ones are not grabbed, which would steal the button from every
+// application; they arrive through the root window's event mask when
+// clicking the desktop.
+func (wm *WindowManager) grabRootButtons() {
+	wm.x.UngrabAllButtons(wm.root)
+	for button := range wm.mouseBindings {
+		if button.Target != MouseOnRoot || button.Mod == 0 {
+			continue
+		}
+		for _, mod := range lockModifiers {
+			wm.x.GrabButton(wm.root, button.Mod|mod, button.Button)
+		}
+	}
+}
+
+// regrabMouseButtons replaces every button grab after the bindings changed
+func (wm *WindowManager) regrabMouseButtons() {
+	wm.grabRootButtons()
+	for win := range wm.clients {
+		wm.x.UngrabAllButtons(win)
+		wm.grabMouseButtons(win)
+	}
 }
 
-// handleButtonPress handles mouse button press events
+// handleButtonPress runs the mouse binding for a button press
 func (wm *WindowManager) handleButtonPress(e xproto.ButtonPressEvent) {
+	button := MouseButton{Mod: keyMods(e.State), Button: byte(e.Detail), Target: MouseOnClient}
+
 	client, exists := wm.clients[e.Event]
-	if !exists {
+	if e.Event == wm.root {
+		// Unmodified presses that bubbled up from a client window are
+		// not desktop clicks
+		if button.Mod == 0 && e.Child != 0 {
+			return
+		}
+		button.Target = MouseOnRoot
+		client = wm.clients[e.Child]
+	} else if !exists {
+		return
+	}
+
+	action, ok := wm.mouseBindings[button]
+	if !ok {
+		return
+	}
+	action(wm, client, e)
+}
+
+// startDrag begins moving or resizing a client with the mouse. Tiled
+// windows are floated first.
+func (wm *WindowManager) startDrag(client *Client, e xproto.ButtonPressEvent, resize bool) {
+	if client == nil {
 		return
 	}
 
-	// Only allow move/resize on floating windows
 	if !client.Floating {
-		// Make window floating first if trying to move/resize tiled window
 		client.Floating = true
+		wm.restoreBorder(client)
 		wm.tile()
 	}
 
 	// Focus the window
 	wm.focus(client)
 
-	// Check for Super modifier (Mod4)
-	if e.State&xproto.ModMask4 == 0 {
-		return
-	}
-
 	// Get current window geometry
-	geom, err := xproto.GetGeometry(wm.conn, xproto.Drawable(e.Event)).Reply()
+	geom, err := wm.x.GetGeometry(client.Window)
 	if err != nil {
 		return
 	}
 
 	wm.drag = DragState{
 		Active:   true,
-		Window:   e.Event,
+		Window:   client.Window,
 		StartX:   e.RootX,
 		StartY:   e.RootY,
 		WinX:     geom.X,
 		WinY:     geom.Y,
 		WinW:     geom.Width,
 		WinH:     geom.Height,
-		IsResize: e.Detail == xproto.ButtonIndex3, // Button3 = resize
+		IsResize: resize,
 	}
 
 	// Raise window to top
-	xproto.ConfigureWindow(wm.conn, e.Event,
+	wm.x.ConfigureWindow(client.Window,
 		xproto.ConfigWindowStackMode, []uint32{xproto.StackModeAbove})
 
 	if wm.drag.IsResize {
-		log.Printf("Starting resize on window %d", e.Event)
+		log.Printf("Starting resize on window %d", client.Window)
 	} else {
-		log.Printf("Starting move on window %d", e.Event)
+		log.Printf("Starting move on window %d", client.Window)
 	}
 }
 
@@ -95,7 +216,7 @@ func (wm *WindowManager) handleButtonRelease(e xproto.ButtonReleaseEvent) {
 
 	// Update client geometry
 	if client, exists := wm.clients[wm.drag.Window]; exists {
-		geom, err := xproto.GetGeometry(wm.conn, xproto.Drawable(wm.drag.Window)).Reply()
+		geom, err := wm.x.GetGeometry(wm.drag.Window)
 		if err == nil {
 			client.X = geom.X
 			client.Y = geom.Y
@@ -131,7 +252,7 @@ func (wm *WindowManager) handleMotionNotify(e xproto.MotionNotifyEvent) {
 			newH = 100
 		}
 
-		xproto.ConfigureWindow(wm.conn, wm.drag.Window,
+		wm.x.ConfigureWindow(wm.drag.Window,
 			xproto.ConfigWindowWidth|xproto.ConfigWindowHeight,
 			[]uint32{uint32(newW), uint32(newH)})
 	} else {
@@ -139,7 +260,7 @@ func (wm *WindowManager) handleMotionNotify(e xproto.MotionNotifyEvent) {
 		newX := int32(wm.drag.WinX) + int32(dx)
 		newY := int32(wm.drag.WinY) + int32(dy)
 
-		xproto.ConfigureWindow(wm.conn, wm.drag.Window,
+		wm.x.ConfigureWindow(wm.drag.Window,
 			xproto.ConfigWindowX|xproto.ConfigWindowY,
 			[]uint32{uint32(newX), uint32(newY)})
 	}
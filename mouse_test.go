@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// hasButtonGrab reports whether button is grabbed on win with mod
+func hasButtonGrab(f *fakeBackend, win xproto.Window, mod uint16, button byte) bool {
+	for _, g := range f.buttonGrabs {
+		if g == (fakeGrab{win: win, mod: mod, detail: button}) {
+			return true
+		}
+	}
+	return false
+}
+
+// mustParseConfig parses config file contents or fails the test
+func mustParseConfig(t *testing.T, src string) *Config {
+	t.Helper()
+	cfg, err := parseConfig("test.toml", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestMouseGrabsFollowModKey(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.applyConfig(mustParseConfig(t, "[behavior]\nmod_key = \"Alt\"\n"))
+	win := mapClient(wm, f)
+
+	if !hasButtonGrab(f, win, xproto.ModMask1|xproto.ModMask2, xproto.ButtonIndex1) {
+		t.Error("Alt+NumLock+Button1 not grabbed")
+	}
+	if hasButtonGrab(f, win, xproto.ModMask4, xproto.ButtonIndex1) {
+		t.Error("Super+Button1 still grabbed")
+	}
+	// Unmodified desktop scrolling must not be grabbed
+	for _, g := range f.buttonGrabs {
+		if g.win == f.root {
+			t.Errorf("root grab %+v", g)
+		}
+	}
+}
+
+func TestMouseMoveFloatsWindow(t *testing.T) {
+	wm, f := newTestWM(t)
+	win := mapClient(wm, f)
+	mapClient(wm, f)
+
+	wm.handleButtonPress(xproto.ButtonPressEvent{
+		Event: win, Detail: xproto.ButtonIndex1, State: xproto.ModMask4 | xproto.ModMask2,
+		RootX: 100, RootY: 100,
+	})
+	c := wm.clients[win]
+	if !c.Floating || !wm.drag.Active || wm.drag.IsResize || wm.focused != c {
+		t.Fatalf("floating = %v, drag = %+v", c.Floating, wm.drag)
+	}
+	startX, startY := wm.drag.WinX, wm.drag.WinY
+
+	wm.handleMotionNotify(xproto.MotionNotifyEvent{RootX: 150, RootY: 120})
+	wm.handleButtonRelease(xproto.ButtonReleaseEvent{})
+	if c.X != startX+50 || c.Y != startY+20 || wm.drag.Active {
+		t.Errorf("client at %d,%d, want %d,%d", c.X, c.Y, startX+50, startY+20)
+	}
+}
+
+func TestUnboundButtonDoesNotFloat(t *testing.T) {
+	wm, f := newTestWM(t)
+	win := mapClient(wm, f)
+
+	wm.handleButtonPress(xproto.ButtonPressEvent{Event: win, Detail: xproto.ButtonIndex2, State: xproto.ModMask4})
+	if wm.clients[win].Floating || wm.drag.Active {
+		t.Error("unbound button floated the window")
+	}
+}
+
+func TestRootScrollCyclesWorkspaces(t *testing.T) {
+	wm, f := newTestWM(t)
+	win := mapClient(wm, f)
+
+	wm.handleButtonPress(xproto.ButtonPressEvent{Event: f.root, Detail: xproto.ButtonIndex4})
+	if wm.current != 8 {
+		t.Fatalf("scroll up from 1 went to %d", wm.current+1)
+	}
+	wm.handleButtonPress(xproto.ButtonPressEvent{Event: f.root, Detail: xproto.ButtonIndex5})
+	if wm.current != 0 {
+		t.Fatalf("scroll down from 9 went to %d", wm.current+1)
+	}
+
+	// Scrolling inside a window that lets the event bubble up is ignored
+	wm.handleButtonPress(xproto.ButtonPressEvent{Event: f.root, Child: win, Detail: xproto.ButtonIndex5})
+	if wm.current != 0 {
+		t.Errorf("scroll over a window switched to %d", wm.current+1)
+	}
+}
+
+func TestMouseBindingsReload(t *testing.T) {
+	wm, f := newTestWM(t)
+	win := mapClient(wm, f)
+
+	wm.applyConfig(mustParseConfig(t, `
+[mouse.client]
+"Mod+Button1" = "none"
+"Mod+Button2" = "window.kill"
+
+[mouse.root]
+"Mod+ScrollUp" = "workspace.next"
+`))
+
+	if hasButtonGrab(f, win, xproto.ModMask4, xproto.ButtonIndex1) {
+		t.Error("removed binding still grabbed")
+	}
+	if !hasButtonGrab(f, win, xproto.ModMask4, xproto.ButtonIndex2) {
+		t.Error("new client binding not grabbed on existing window")
+	}
+	if !hasButtonGrab(f, f.root, xproto.ModMask4|xproto.ModMaskLock, xproto.ButtonIndex4) {
+		t.Error("modified root binding not grabbed")
+	}
+
+	// A root grab reports the window under the pointer as the child
+	wm.handleButtonPress(xproto.ButtonPressEvent{Event: f.root, Child: win, Detail: xproto.ButtonIndex4, State: xproto.ModMask4})
+	if wm.current != 1 {
+		t.Errorf("Mod+scroll over a window went to %d", wm.current+1)
+	}
+}
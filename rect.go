@@ -6,12 +6,14 @@ type Rect struct {
 	Width, Height uint16
 }
 
-// Shrink returns a new Rect reduced by the given amount on all sides
+// Shrink returns a new Rect reduced by the given amount on all sides, down
+// to nothing when the amount is too large
 func (r Rect) Shrink(amount uint16) Rect {
+	dx, dy := min(amount, r.Width/2), min(amount, r.Height/2)
 	return Rect{
-		X:      r.X + int16(amount),
-		Y:      r.Y + int16(amount),
-		Width:  r.Width - 2*amount,
-		Height: r.Height - 2*amount,
+		X:      r.X + int16(dx),
+		Y:      r.Y + int16(dy),
+		Width:  r.Width - 2*dx,
+		Height: r.Height - 2*dy,
 	}
 }
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ArgKind is the type of argument a named action takes
+type ArgKind int
+
+const (
+	ArgNone      ArgKind = iota
+	ArgWorkspace         // Workspace number 1-9
+	ArgName              // A single word, such as a submap name
+	ArgCommand           // The rest of the line, such as a shell command
+	ArgDirection         // left, right, up or down
+	ArgPercent           // Percentage 1-99
+	ArgSize              // Size in pixels: N, +N, -N or toggle
+)
+
+// String returns the argument placeholder shown in help and listings
+func (k ArgKind) String() string {
+	switch k {
+	case ArgWorkspace:
+		return "<1-9>"
+	case ArgName:
+		return "<name>"
+	case ArgCommand:
+		return "<command>"
+	case ArgDirection:
+		return "<left|right|up|down>"
+	case ArgPercent:
+		return "<1-99>"
+	case ArgSize:
+		return "<N|+N|-N|toggle>"
+	}
+	return ""
+}
+
+// ActionArg is a parsed action argument
+type ActionArg struct {
+	Int       int
+	String    string
+	Direction Direction
+	Size      Adjustment
+}
+
+// ActionSpec describes a named action that can be bound in the config,
+// run over IPC or listed in GridSelect
+type ActionSpec struct {
+	Name   string
+	Arg    ArgKind
+	Window bool // Acts on the focused window
+	Help   string
+	New    func(ActionArg) Action
+}
+
+// actionRegistry holds every named action by name. It is filled in init
+// because wm.reload reaches the config parser, which reads the registry.
+var actionRegistry map[string]*ActionSpec
+
+// actionAliases are alternative names kept for older configs and scripts
+var actionAliases = map[string]string{
+	"window.close": "window.kill",
+}
+
+func init() {
+	actionRegistry = make(map[string]*ActionSpec)
+	for _, spec := range []*ActionSpec{
+		// Windows
+		{Name: "window.kill", Window: true, Help: "Close the focused window", New: fixed(ActionKill)},
+		{Name: "window.kill-all", Help: "Close every window on the workspace", New: fixed(ActionKillAll)},
+		{Name: "window.focus-next", Help: "Focus the next window", New: fixed(ActionFocusNext)},
+		{Name: "window.focus-prev", Help: "Focus the previous window", New: fixed(ActionFocusPrev)},
+		{Name: "window.focus-master", Help: "Focus the master window", New: fixed(ActionFocusMaster)},
+		{Name: "window.swap-next", Window: true, Help: "Swap the focused window with the next", New: fixed(ActionSwapNext)},
+		{Name: "window.swap-prev", Window: true, Help: "Swap the focused window with the previous", New: fixed(ActionSwapPrev)},
+		{Name: "window.swap-master", Window: true, Help: "Swap the focused window with the master", New: fixed(ActionSwapMaster)},
+		{Name: "window.float", Window: true, Help: "Float the focused window", New: fixed(ActionFloat)},
+		{Name: "window.sink", Window: true, Help: "Sink the focused window back into the layout", New: fixed(ActionSink)},
+		{Name: "window.toggle-float", Window: true, Help: "Toggle the focused window floating", New: fixed(ActionToggleFloat)},
+
+		// Layouts
+		{Name: "layout.next", Help: "Cycle to the next layout", New: fixed(ActionNextLayout)},
+		{Name: "layout.set", Arg: ArgName, Help: "Switch to a layout by name, e.g. split(full,grid)", New: func(a ActionArg) Action {
+			return ActionSetLayout(a.String)
+		}},
+		{Name: "layout.split-shrink", Help: "Narrow the first half of the split layout", New: fixed(ActionSplitShrink)},
+		{Name: "layout.split-expand", Help: "Widen the first half of the split layout", New: fixed(ActionSplitExpand)},
+		{Name: "layout.split-move", Window: true, Help: "Move the focused window to the other half of the split layout", New: fixed(ActionSplitMove)},
+		{Name: "layout.reset", Help: "Reset the workspace to the tall layout", New: fixed(ActionResetLayout)},
+		{Name: "layout.shrink", Help: "Shrink the master area", New: fixed(ActionShrink)},
+		{Name: "layout.expand", Help: "Expand the master area", New: fixed(ActionExpand)},
+		{Name: "layout.shrink-window", Help: "Make the focused window shorter than the rest of its column", New: fixed(ActionShrinkWindow)},
+		{Name: "layout.expand-window", Help: "Make the focused window taller than the rest of its column", New: fixed(ActionExpandWindow)},
+		{Name: "layout.reset-weights", Help: "Give the windows of each column equal heights again", New: fixed(ActionResetWeights)},
+		{Name: "layout.inc-master", Help: "Add a window to the master area", New: fixed(ActionIncMaster)},
+		{Name: "layout.dec-master", Help: "Remove a window from the master area", New: fixed(ActionDecMaster)},
+		{Name: "layout.toggle-mirror", Help: "Rotate the layout by 90°, or undo it", New: fixed(ActionToggleModifier("mirror"))},
+		{Name: "layout.toggle-reflect-x", Help: "Flip the layout left to right, or undo it", New: fixed(ActionToggleModifier("reflectx"))},
+		{Name: "layout.toggle-reflect-y", Help: "Flip the layout top to bottom, or undo it", New: fixed(ActionToggleModifier("reflecty"))},
+		{Name: "layout.toggle-magnify", Help: "Enlarge the focused window, or stop enlarging it", New: fixed(ActionToggleModifier("magnify"))},
+		{Name: "layout.magnify-more", Help: "Enlarge the focused window more (magnify)", New: fixed(ActionMagnifyMore)},
+		{Name: "layout.magnify-less", Help: "Enlarge the focused window less (magnify)", New: fixed(ActionMagnifyLess)},
+		{Name: "layout.rotate", Help: "Rotate the split holding the focused window clockwise", New: fixed(ActionRotate)},
+		{Name: "layout.flip-horizontal", Help: "Mirror the split holding the focused window left to right", New: fixed(ActionFlipHorizontal)},
+		{Name: "layout.flip-vertical", Help: "Mirror the split holding the focused window top to bottom", New: fixed(ActionFlipVertical)},
+		{Name: "layout.preselect", Arg: ArgDirection, Help: "Open the next window on this side of the focused one (bsp)", New: func(a ActionArg) Action {
+			return ActionPreselect(a.Direction)
+		}},
+		{Name: "layout.preselect-ratio", Arg: ArgPercent, Help: "Set the share of the space the preselected window takes (bsp)", New: func(a ActionArg) Action {
+			return ActionPreselectRatio(a.Int)
+		}},
+		{Name: "layout.preselect-cancel", Help: "Cancel the preselection (bsp)", New: fixed(ActionPreselectCancel)},
+
+		// Gaps and borders
+		{Name: "gaps.outer", Arg: ArgSize, Help: "Set or change the gap at the screen edge", New: spacingAction(SpacingOuterGap, false)},
+		{Name: "gaps.inner", Arg: ArgSize, Help: "Set or change the gap between windows", New: spacingAction(SpacingInnerGap, false)},
+		{Name: "gaps.toggle", Help: "Turn the gaps off, or back on", New: fixed(ActionToggleGaps(false))},
+		{Name: "gaps.workspace-outer", Arg: ArgSize, Help: "Set or change the gap at the screen edge on this workspace", New: spacingAction(SpacingOuterGap, true)},
+		{Name: "gaps.workspace-inner", Arg: ArgSize, Help: "Set or change the gap between windows on this workspace", New: spacingAction(SpacingInnerGap, true)},
+		{Name: "gaps.workspace-toggle", Help: "Turn the gaps on this workspace off, or back on", New: fixed(ActionToggleGaps(true))},
+		{Name: "border.width", Arg: ArgSize, Help: "Set or change the border width", New: spacingAction(SpacingBorder, false)},
+		{Name: "border.workspace-width", Arg: ArgSize, Help: "Set or change the border width on this workspace", New: spacingAction(SpacingBorder, true)},
+
+		// Workspaces
+		{Name: "workspace.switch", Arg: ArgWorkspace, Help: "Switch to a workspace", New: func(a ActionArg) Action {
+			return ActionSwitchWorkspace(a.Int - 1)
+		}},
+		{Name: "workspace.move", Arg: ArgWorkspace, Window: true, Help: "Move the focused window to a workspace", New: func(a ActionArg) Action {
+			return ActionMoveToWorkspace(a.Int - 1)
+		}},
+		{Name: "workspace.next", Help: "Switch to the next workspace", New: fixed(ActionNextWorkspace)},
+		{Name: "workspace.prev", Help: "Switch to the previous workspace", New: fixed(ActionPrevWorkspace)},
+
+		// Monitors
+		{Name: "monitor.focus-next", Help: "Focus the next monitor", New: fixed(ActionFocusNextMonitor)},
+		{Name: "monitor.focus-prev", Help: "Focus the previous monitor", New: fixed(ActionFocusPrevMonitor)},
+		{Name: "monitor.move-next", Window: true, Help: "Move the focused window to the next monitor", New: fixed(ActionMoveToNextMonitor)},
+		{Name: "monitor.move-prev", Window: true, Help: "Move the focused window to the previous monitor", New: fixed(ActionMoveToPrevMonitor)},
+		{Name: "monitor.swap", Help: "Swap the workspaces of this and the next monitor", New: fixed(ActionSwapMonitors)},
+
+		// Display profiles
+		{Name: "display.profile", Arg: ArgName, Help: "Apply a display profile", New: func(a ActionArg) Action {
+			return ActionDisplayProfile(a.String)
+		}},
+		{Name: "display.auto", Help: "Apply the display profile matching the connected outputs", New: fixed(ActionDisplayAuto)},
+
+		// Scratchpad, GridSelect and struts
+		{Name: "scratchpad.toggle", Help: "Show or hide the scratchpad", New: fixed(ActionToggleScratchpad)},
+		{Name: "gridselect.windows", Help: "Pick a window from a grid", New: fixed(ActionGridSelect)},
+		{Name: "gridselect.workspaces", Help: "Pick a workspace from a grid", New: fixed(ActionGridSelectWorkspaces)},
+		{Name: "gridselect.spawn", Help: "Pick an application to launch", New: fixed(ActionGridSelectSpawn)},
+		{Name: "struts.toggle", Help: "Toggle space reserved for panels", New: fixed(ActionToggleStruts)},
+
+		// Key submaps
+		{Name: "submap", Arg: ArgName, Help: "Enter a key submap", New: func(a ActionArg) Action {
+			return ActionEnterSubmap(a.String)
+		}},
+		{Name: "submap.exit", Help: "Leave the active submap", New: fixed(ActionExitSubmap)},
+
+		// Programs and the window manager
+		{Name: "spawn", Arg: ArgCommand, Help: "Run a shell command", New: func(a ActionArg) Action {
+			return ActionSpawn(a.String)
+		}},
+		{Name: "wm.reload", Help: "Reload the config file", New: fixed(ActionReloadConfig)},
+		{Name: "wm.restart", Help: "Restart gowm in place", New: fixed(ActionRestart)},
+		{Name: "wm.quit", Help: "Quit gowm", New: fixed(ActionQuit)},
+	} {
+		actionRegistry[spec.Name] = spec
+	}
+}
+
+// fixed wraps an action that takes no argument
+func fixed(action Action) func(ActionArg) Action {
+	return func(ActionArg) Action { return action }
+}
+
+// spacingAction creates the actions changing a gap or the border width
+func spacingAction(s Spacing, workspace bool) func(ActionArg) Action {
+	return func(a ActionArg) Action { return ActionAdjustSpacing(s, workspace, a.Size) }
+}
+
+// lookupAction finds a named action, following aliases
+func lookupAction(name string) (*ActionSpec, bool) {
+	if alias, ok := actionAliases[name]; ok {
+		name = alias
+	}
+	spec, ok := actionRegistry[name]
+	return spec, ok
+}
+
+// actionSpecs returns every registered action sorted by name
+func actionSpecs() []*ActionSpec {
+	specs := make([]*ActionSpec, 0, len(actionRegistry))
+	for _, spec := range actionRegistry {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// splitAction splits "workspace.switch 3" into the action name and the
+// rest of the line
+func splitAction(s string) (string, string) {
+	name, arg := strings.TrimSpace(s), ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, arg = name[:i], strings.TrimSpace(name[i+1:])
+	}
+	return name, arg
+}
+
+// parseActionSpec parses an action such as "window.kill", "spawn kitty" or
+// "workspace.switch 3" into its spec and argument
+func parseActionSpec(s string) (*ActionSpec, ActionArg, error) {
+	name, arg := splitAction(s)
+	spec, ok := lookupAction(name)
+	if !ok {
+		return nil, ActionArg{}, fmt.Errorf("unknown action %q", name)
+	}
+
+	var a ActionArg
+	switch spec.Arg {
+	case ArgNone:
+		if arg != "" {
+			return nil, a, fmt.Errorf("%s takes no arguments", spec.Name)
+		}
+	case ArgWorkspace:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > 9 {
+			return nil, a, fmt.Errorf("%s needs a workspace number 1-9", spec.Name)
+		}
+		a.Int = n
+	case ArgName:
+		if arg == "" || strings.ContainsAny(arg, " \t") {
+			return nil, a, fmt.Errorf("%s needs a name", spec.Name)
+		}
+		a.String = arg
+	case ArgCommand:
+		if arg == "" {
+			return nil, a, fmt.Errorf("%s needs a command", spec.Name)
+		}
+		a.String = arg
+	case ArgDirection:
+		dir, ok := directionNames[arg]
+		if !ok {
+			return nil, a, fmt.Errorf("%s needs a direction: left, right, up or down", spec.Name)
+		}
+		a.Direction = dir
+	case ArgPercent:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > 99 {
+			return nil, a, fmt.Errorf("%s needs a percentage 1-99", spec.Name)
+		}
+		a.Int = n
+	case ArgSize:
+		adj, ok := parseAdjustment(arg)
+		if !ok {
+			return nil, a, fmt.Errorf("%s needs a size: N, +N, -N or toggle", spec.Name)
+		}
+		a.Size = adj
+	}
+	return spec, a, nil
+}
+
+// parseAction parses an action string into a runnable Action. "none"
+// returns a nil Action, which unbinds the keys.
+func parseAction(s string) (Action, error) {
+	if strings.TrimSpace(s) == "none" {
+		return nil, nil
+	}
+	spec, arg, err := parseActionSpec(s)
+	if err != nil {
+		return nil, err
+	}
+	return spec.New(arg), nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestActionRegistryIsComplete(t *testing.T) {
+	for _, spec := range actionSpecs() {
+		if spec.Help == "" || spec.New == nil {
+			t.Errorf("%s: missing help or constructor", spec.Name)
+		}
+	}
+	for alias, name := range actionAliases {
+		if _, ok := actionRegistry[name]; !ok {
+			t.Errorf("alias %s points at unknown action %s", alias, name)
+		}
+	}
+}
+
+func TestParseActionArguments(t *testing.T) {
+	valid := []string{"window.kill", "window.close", "workspace.switch 3", "spawn kitty --class x", "submap resize", "layout.preselect left", "layout.preselect-ratio 30", "gaps.inner +2", "border.width toggle", "none"}
+	for _, s := range valid {
+		if _, err := parseAction(s); err != nil {
+			t.Errorf("parseAction(%q): %v", s, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"window.explode":             `unknown action "window.explode"`,
+		"window.kill now":            "window.kill takes no arguments",
+		"workspace.move 10":          "workspace.move needs a workspace number 1-9",
+		"workspace.switch":           "workspace.switch needs a workspace number 1-9",
+		"spawn":                      "spawn needs a command",
+		"submap launch keys":         "submap needs a name",
+		"layout.preselect north":     "layout.preselect needs a direction: left, right, up or down",
+		"layout.preselect-ratio 100": "layout.preselect-ratio needs a percentage 1-99",
+		"gaps.outer wide":            "gaps.outer needs a size: N, +N, -N or toggle",
+	}
+	for s, want := range invalid {
+		if _, err := parseAction(s); err == nil || err.Error() != want {
+			t.Errorf("parseAction(%q) = %v, want %q", s, err, want)
+		}
+	}
+}
+
+func TestIPCRunsRegistryActions(t *testing.T) {
+	wm, f := newTestWM(t)
+	first := mapClient(wm, f)
+	mapClient(wm, f)
+	ipc := &IPCServer{wm: wm}
+
+	// Previously unreachable from gowmctl
+	if resp := ipc.handleCommand("action window.toggle-float"); !resp.Success || !wm.focused.Floating {
+		t.Fatalf("toggle-float: %+v", resp)
+	}
+	if resp := ipc.handleCommand("window swap master"); !resp.Success {
+		t.Errorf("window swap master: %+v", resp)
+	}
+
+	// The original grouped commands still work
+	if resp := ipc.handleCommand("window focus next"); !resp.Success || wm.focused.Window != first {
+		t.Errorf("window focus next: %+v", resp)
+	}
+	if resp := ipc.handleCommand("workspace switch 3"); !resp.Success || wm.current != 2 {
+		t.Errorf("workspace switch 3: %+v, current = %d", resp, wm.current)
+	}
+	if resp := ipc.handleCommand("window close"); resp.Success || resp.Message != "no focused window" {
+		t.Errorf("window close without focus: %+v", resp)
+	}
+	if resp := ipc.handleCommand("action quit"); !resp.Success || wm.running {
+		t.Errorf("legacy action name: %+v", resp)
+	}
+	if resp := ipc.handleCommand("layout explode"); resp.Success {
+		t.Errorf("unknown layout command succeeded: %+v", resp)
+	}
+
+	resp := ipc.handleCommand("actions")
+	actions, ok := resp.Data.([]ActionInfo)
+	if !ok || len(actions) != len(actionRegistry) {
+		t.Fatalf("actions: %+v", resp)
+	}
+	for _, a := range actions {
+		if a.Name == "workspace.switch" && a.Arg != "<1-9>" {
+			t.Errorf("workspace.switch arg = %q", a.Arg)
+		}
+	}
+}
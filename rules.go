@@ -136,8 +136,8 @@ func (wm *WindowManager) matchRule(rule WindowRule, class, instance, title strin
 
 // getWMInstance returns the WM_CLASS instance name
 func (wm *WindowManager) getWMInstance(win xproto.Window) string {
-	reply, err := xproto.GetProperty(wm.conn, false, win,
-		xproto.AtomWmClass, xproto.AtomString, 0, 256).Reply()
+	reply, err := wm.x.GetProperty(win,
+		xproto.AtomWmClass, xproto.AtomString, 0, 256)
 	if err != nil || reply == nil || len(reply.Value) == 0 {
 		return ""
 	}
@@ -153,15 +153,15 @@ func (wm *WindowManager) getWMInstance(win xproto.Window) string {
 // getWindowTitle returns the window title
 func (wm *WindowManager) getWindowTitle(win xproto.Window) string {
 	// Try _NET_WM_NAME first
-	reply, err := xproto.GetProperty(wm.conn, false, win,
-		wm.atoms.NET_WM_NAME, wm.atoms.UTF8_STRING, 0, 1024).Reply()
+	reply, err := wm.x.GetProperty(win,
+		wm.atoms.NET_WM_NAME, wm.atoms.UTF8_STRING, 0, 1024)
 	if err == nil && reply != nil && len(reply.Value) > 0 {
 		return string(reply.Value)
 	}
 
 	// Fall back to WM_NAME
-	reply, err = xproto.GetProperty(wm.conn, false, win,
-		xproto.AtomWmName, xproto.AtomString, 0, 1024).Reply()
+	reply, err = wm.x.GetProperty(win,
+		xproto.AtomWmName, xproto.AtomString, 0, 1024)
 	if err == nil && reply != nil && len(reply.Value) > 0 {
 		return string(reply.Value)
 	}
@@ -37,7 +37,7 @@ func (wm *WindowManager) ToggleScratchpad() {
 	// If we have a window, toggle visibility
 	if sp.window != 0 {
 		// Check if window still exists
-		_, err := xproto.GetWindowAttributes(wm.conn, sp.window).Reply()
+		_, err := wm.x.GetWindowAttributes(sp.window)
 		if err != nil {
 			// Window was destroyed, reset
 			sp.window = 0
@@ -48,7 +48,7 @@ func (wm *WindowManager) ToggleScratchpad() {
 	if sp.window != 0 {
 		if sp.visible {
 			// Hide it
-			xproto.UnmapWindow(wm.conn, sp.window)
+			wm.x.UnmapWindow(sp.window)
 			sp.visible = false
 			log.Println("Scratchpad hidden")
 		} else {
@@ -77,21 +77,27 @@ func (wm *WindowManager) spawnScratchpad() {
 func (wm *WindowManager) showScratchpad() {
 	sp := wm.scratchpad
 
-	// Calculate centered position
-	w := uint16(float64(wm.screen.WidthInPixels) * float64(sp.Width) / 100)
-	h := uint16(float64(wm.screen.HeightInPixels) * float64(sp.Height) / 100)
-	x := int16((wm.screen.WidthInPixels - w) / 2)
-	y := int16((wm.screen.HeightInPixels-h)/2) + int16(wm.struts[2]) // Account for top bar
+	// Calculate centered position on the focused monitor
+	mon := wm.currentMonitor()
+	g := mon.Geometry
+	w := uint16(float64(g.Width) * float64(sp.Width) / 100)
+	h := uint16(float64(g.Height) * float64(sp.Height) / 100)
+	x := g.X + int16((g.Width-w)/2)
+	y := g.Y + int16((g.Height-h)/2) + int16(mon.Struts[2]) // Account for top bar
 
 	// Configure and map
-	xproto.ConfigureWindow(wm.conn, sp.window,
+	wm.x.ConfigureWindow(sp.window,
 		xproto.ConfigWindowX|xproto.ConfigWindowY|
 			xproto.ConfigWindowWidth|xproto.ConfigWindowHeight|
 			xproto.ConfigWindowStackMode,
 		[]uint32{uint32(x), uint32(y), uint32(w), uint32(h), xproto.StackModeAbove})
 
-	xproto.MapWindow(wm.conn, sp.window)
-	wm.focus(wm.clients[sp.window])
+	wm.x.MapWindow(sp.window)
+	if c := wm.clients[sp.window]; c != nil {
+		// Follow the focused monitor rather than the one it was opened on
+		c.Workspace = wm.current
+		wm.focus(c)
+	}
 	sp.visible = true
 	log.Println("Scratchpad shown")
 }
@@ -109,7 +115,7 @@ func (wm *WindowManager) handleScratchpadMap(win xproto.Window) {
 	sp.visible = true
 
 	// Create client but mark as floating
-	geom, err := xproto.GetGeometry(wm.conn, xproto.Drawable(win)).Reply()
+	geom, err := wm.x.GetGeometry(win)
 	if err != nil {
 		return
 	}
@@ -126,9 +132,10 @@ func (wm *WindowManager) handleScratchpadMap(win xproto.Window) {
 	}
 
 	wm.clients[win] = client
+	wm.updateClientNames(client)
 
 	// Subscribe to events
-	xproto.ChangeWindowAttributes(wm.conn, win,
+	wm.x.ChangeWindowAttributes(win,
 		xproto.CwEventMask, []uint32{
 			xproto.EventMaskEnterWindow |
 				xproto.EventMaskStructureNotify |
@@ -136,10 +143,9 @@ func (wm *WindowManager) handleScratchpadMap(win xproto.Window) {
 		})
 
 	// Set border
-	xproto.ChangeWindowAttributes(wm.conn, win,
+	wm.x.ChangeWindowAttributes(win,
 		xproto.CwBorderPixel, []uint32{wm.config.FocusedBorderColor})
-	xproto.ConfigureWindow(wm.conn, win,
-		xproto.ConfigWindowBorderWidth, []uint32{uint32(wm.config.BorderWidth)})
+	wm.restoreBorder(client)
 
 	// Position and show
 	wm.showScratchpad()
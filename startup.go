@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -58,7 +59,7 @@ func DefaultStartupConfig() *StartupConfig {
 
 // runStartup runs startup commands
 func (wm *WindowManager) runStartup() {
-	cfg := DefaultStartupConfig()
+	cfg := wm.config.Startup
 
 	log.Println("Running startup...")
 
@@ -95,12 +96,9 @@ func (wm *WindowManager) runStartup() {
 		spawn("xrdb -load ~/.Xresources")
 	}
 
-	// Display settings (xrandr) - using your config
-	spawn("xrandr --output DP-2 --primary --mode 1920x1080 --rate 165.00")
-
 	// Background
 	if cfg.WallpaperCommand != "" {
-		spawn(cfg.WallpaperCommand)
+		spawn("%s", cfg.WallpaperCommand)
 	}
 
 	// Kill and restart always-run apps
@@ -145,6 +143,11 @@ func spawn(format string, args ...interface{}) {
 	}
 }
 
+// shellQuote quotes s as a single sh word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // spawnOnce spawns a command that should keep running
 func spawnOnce(cmd string) {
 	c := exec.Command("sh", "-c", cmd)
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// Submap is a keymap entered from a prefix key, for chords like Super+a
+// then f, or modes like resize where h/l keep resizing until Escape.
+// While a submap is active the keyboard is grabbed so its keys need no
+// modifier.
+type Submap struct {
+	Name     string
+	Bindings []KeyBinding
+	Sticky   bool          // Stay in the submap after running an action
+	Timeout  time.Duration // Leave after this long without a key press (0 = never)
+}
+
+// activeSubmap is the submap currently receiving keys
+type activeSubmap struct {
+	submap *Submap
+	keys   map[KeyCombo]Action
+	timer  *time.Timer
+}
+
+// DefaultSubmaps returns the built-in submaps
+func DefaultSubmaps() map[string]*Submap {
+	return map[string]*Submap{
+		// Resize mode: Super+Ctrl+r, then h/l and j/k until Escape
+		"resize": {
+			Name:   "resize",
+			Sticky: true,
+			Bindings: []KeyBinding{
+				{0, XK_h, ActionShrink},
+				{0, XK_l, ActionExpand},
+				{0, XK_j, ActionShrinkWindow},
+				{0, XK_k, ActionExpandWindow},
+				{0, XK_r, ActionResetWeights},
+				{0, XK_comma, ActionIncMaster},
+				{0, XK_period, ActionDecMaster},
+				{0, XK_Return, ActionExitSubmap},
+			},
+		},
+	}
+}
+
+// ActionEnterSubmap returns an action that enters the named submap
+func ActionEnterSubmap(name string) Action {
+	return func(wm *WindowManager) {
+		wm.enterSubmap(name)
+	}
+}
+
+// ActionExitSubmap leaves the active submap
+func ActionExitSubmap(wm *WindowManager) {
+	wm.exitSubmap()
+}
+
+// currentMode returns the name of the active submap, or "default"
+func (wm *WindowManager) currentMode() string {
+	if wm.submap == nil {
+		return "default"
+	}
+	return wm.submap.submap.Name
+}
+
+// enterSubmap activates a submap and grabs the keyboard for it
+func (wm *WindowManager) enterSubmap(name string) {
+	sm, ok := wm.config.Submaps[name]
+	if !ok {
+		log.Printf("Unknown submap %q", name)
+		return
+	}
+
+	if wm.submap != nil {
+		wm.exitSubmap()
+	}
+
+	if err := wm.x.GrabKeyboard(wm.root); err != nil {
+		log.Printf("Cannot enter submap %q: %v", name, err)
+		return
+	}
+
+	active := &activeSubmap{submap: sm, keys: make(map[KeyCombo]Action)}
+	for _, b := range sm.Bindings {
+		combos, err := wm.resolveKeyCombo(b.Mod, b.Keysym)
+		if err != nil {
+			log.Printf("Submap %s: binding ignored: %v", name, err)
+			continue
+		}
+		for _, combo := range combos {
+			active.keys[combo] = b.Action
+		}
+	}
+
+	wm.submap = active
+	wm.resetSubmapTimer()
+	wm.emit(IPCEvent{Event: "mode", Mode: name})
+	log.Printf("Entered submap %q", name)
+}
+
+// exitSubmap leaves the active submap and releases the keyboard
+func (wm *WindowManager) exitSubmap() {
+	if wm.submap == nil {
+		return
+	}
+	if wm.submap.timer != nil {
+		wm.submap.timer.Stop()
+	}
+	log.Printf("Left submap %q", wm.submap.submap.Name)
+
+	wm.submap = nil
+	wm.x.UngrabKeyboard()
+	wm.emit(IPCEvent{Event: "mode", Mode: wm.currentMode()})
+}
+
+// resetSubmapTimer restarts the inactivity timeout of the active submap
+func (wm *WindowManager) resetSubmapTimer() {
+	active := wm.submap
+	if active.timer != nil {
+		active.timer.Stop()
+	}
+	if active.submap.Timeout <= 0 {
+		return
+	}
+	active.timer = wm.after(active.submap.Timeout, func() {
+		// The submap may have been left or replaced in the meantime
+		if wm.submap == active {
+			wm.exitSubmap()
+		}
+	})
+}
+
+// handleSubmapKey handles a key press while a submap is active. Escape
+// leaves the submap unless the submap binds it, and in a non-sticky submap
+// any unbound key aborts the chord.
+func (wm *WindowManager) handleSubmapKey(e xproto.KeyPressEvent) {
+	active := wm.submap
+	keysym := wm.keycodeToKeysym(e.Detail)
+
+	// Modifiers on their own just build up the next combo
+	if isModifierKeysym(keysym) {
+		return
+	}
+
+	action, ok := active.keys[KeyCombo{Mod: keyMods(e.State), Keycode: e.Detail}]
+	if !ok {
+		if keysym == XK_Escape || !active.submap.Sticky {
+			wm.exitSubmap()
+		}
+		return
+	}
+
+	if active.submap.Sticky {
+		wm.resetSubmapTimer()
+	} else {
+		wm.exitSubmap()
+	}
+	if action != nil {
+		action(wm)
+	}
+}
+
+// isModifierKeysym reports whether keysym is a modifier key such as
+// Shift_L or ISO_Level3_Shift
+func isModifierKeysym(keysym xproto.Keysym) bool {
+	return keysym >= XK_Shift_L && keysym <= 0xffee || // Shift_L..Hyper_R
+		keysym >= 0xfe01 && keysym <= 0xfe13 // ISO_Lock..ISO_Level5_Lock
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// pressKey sends a key press for keysym with the given modifiers
+func pressKey(wm *WindowManager, f *fakeBackend, mod uint16, keysym xproto.Keysym) {
+	wm.handleKeyPress(xproto.KeyPressEvent{Detail: f.keycode(keysym), State: mod})
+}
+
+func TestResizeSubmapIsSticky(t *testing.T) {
+	wm, f := newTestWM(t)
+	mapClient(wm, f)
+	mapClient(wm, f)
+	tall := wm.currentWorkspace().Layout.(*TallLayout)
+	ratio := tall.MasterRatio
+
+	pressKey(wm, f, xproto.ModMask4|xproto.ModMaskControl, XK_r)
+	if wm.currentMode() != "resize" || !f.keyboardGrabbed {
+		t.Fatalf("mode = %s, keyboard grabbed = %v", wm.currentMode(), f.keyboardGrabbed)
+	}
+
+	pressKey(wm, f, 0, XK_h)
+	pressKey(wm, f, 0, XK_h)
+	pressKey(wm, f, 0, XK_x) // unbound keys are ignored in a sticky submap
+	if tall.MasterRatio >= ratio {
+		t.Errorf("h did not shrink the master: %f", tall.MasterRatio)
+	}
+	if wm.currentMode() != "resize" {
+		t.Fatalf("left resize mode early")
+	}
+
+	// Super+j is not bound inside the submap
+	focused := wm.focused
+	pressKey(wm, f, xproto.ModMask4, XK_j)
+	if wm.focused != focused {
+		t.Error("root binding ran inside the submap")
+	}
+
+	pressKey(wm, f, 0, XK_Escape)
+	if wm.currentMode() != "default" || f.keyboardGrabbed {
+		t.Errorf("Escape did not leave: mode = %s, grabbed = %v", wm.currentMode(), f.keyboardGrabbed)
+	}
+}
+
+func TestChordSubmap(t *testing.T) {
+	wm, f := newTestWM(t)
+	first := mapClient(wm, f)
+	mapClient(wm, f)
+
+	wm.config.Submaps["go"] = &Submap{
+		Name:     "go",
+		Bindings: []KeyBinding{{0, XK_j, ActionFocusNext}},
+	}
+	wm.config.Bindings = append(wm.config.Bindings, KeyBinding{xproto.ModMask4, XK_a, ActionEnterSubmap("go")})
+	wm.SetupKeybindings()
+
+	pressKey(wm, f, xproto.ModMask4, XK_a)
+	pressKey(wm, f, 0, XK_Shift_L) // modifiers alone don't end the chord
+	if wm.currentMode() != "go" {
+		t.Fatalf("mode = %s", wm.currentMode())
+	}
+	pressKey(wm, f, 0, XK_j)
+	if wm.focused == nil || wm.focused.Window != first {
+		t.Errorf("chord action did not run: %v", wm.focused)
+	}
+	if wm.currentMode() != "default" || f.keyboardGrabbed {
+		t.Error("non-sticky submap stayed active")
+	}
+
+	// Any other key aborts the chord without running anything
+	pressKey(wm, f, xproto.ModMask4, XK_a)
+	pressKey(wm, f, 0, XK_k)
+	if wm.currentMode() != "default" || wm.focused.Window != first {
+		t.Error("unbound key did not abort the chord")
+	}
+}
+
+func TestSubmapTimeout(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.config.Submaps["quick"] = &Submap{Name: "quick", Timeout: time.Millisecond}
+
+	wm.enterSubmap("quick")
+	select {
+	case fn := <-wm.calls:
+		fn()
+	case <-time.After(time.Second):
+		t.Fatal("timeout never fired")
+	}
+	if wm.currentMode() != "default" || f.keyboardGrabbed {
+		t.Error("submap did not time out")
+	}
+}
+
+func TestSubmapNeedsKeyboardGrab(t *testing.T) {
+	wm, f := newTestWM(t)
+	f.keyboardGrabFail = true
+
+	wm.enterSubmap("resize")
+	if wm.submap != nil {
+		t.Error("entered a submap without the keyboard")
+	}
+}
+
+func TestQueryMode(t *testing.T) {
+	wm, _ := newTestWM(t)
+	ipc := &IPCServer{wm: wm}
+
+	if resp := ipc.handleCommand("query mode"); resp.Data != "default" {
+		t.Errorf("mode = %v", resp.Data)
+	}
+	wm.enterSubmap("resize")
+	if resp := ipc.handleCommand("query mode"); resp.Data != "resize" {
+		t.Errorf("mode = %v", resp.Data)
+	}
+}
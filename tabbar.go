@@ -0,0 +1,215 @@
+package main
+
+import (
+	"log"
+	"unicode/utf8"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// TabBar is the row of tabs drawn above a workspace in the tabbed layout.
+// Each workspace gets its own bar window, which is unmapped while the
+// workspace is hidden or uses another layout.
+type TabBar struct {
+	Window    xproto.Window
+	Workspace int
+	Geometry  Rect
+	Tabs      []Tab
+	mapped    bool
+	draw      *xftDraw // nil until first drawn, or without Xft
+}
+
+// Tab is the tab of one window
+type Tab struct {
+	Client *Client
+	Title  string
+	X      int16 // Offset in the bar
+	Width  uint16
+}
+
+// Tab bar font, smaller than GridSelect's to fit the bar
+const tabFontName = "Vanilla Caramel:size=10"
+
+var tabFontFallbacks = []string{
+	"DejaVu Sans:size=10",
+	"Liberation Sans:size=10",
+	"Sans:size=10",
+}
+
+// updateTabBar shows the tab bar of ws at r, with a tab per client
+func (wm *WindowManager) updateTabBar(ws *Workspace, clients []*Client, r Rect) {
+	tb := wm.tabBars[ws.ID]
+	if tb == nil {
+		win, err := wm.x.CreateWindow(wm.root, r, 0, xproto.WindowClassInputOutput,
+			xproto.CwBackPixel|xproto.CwOverrideRedirect|xproto.CwEventMask,
+			[]uint32{
+				wm.config.UnfocusedBorderColor,
+				1, // override redirect - bypass WM
+				xproto.EventMaskExposure | xproto.EventMaskButtonPress,
+			})
+		if err != nil {
+			log.Printf("Cannot create tab bar: %v", err)
+			return
+		}
+		tb = &TabBar{Window: win, Workspace: ws.ID, Geometry: r}
+		wm.tabBars[ws.ID] = tb
+	}
+
+	if tb.Geometry != r {
+		tb.Geometry = r
+		wm.x.ConfigureWindow(tb.Window,
+			xproto.ConfigWindowX|xproto.ConfigWindowY|
+				xproto.ConfigWindowWidth|xproto.ConfigWindowHeight,
+			[]uint32{uint32(r.X), uint32(r.Y), uint32(r.Width), uint32(r.Height)})
+	}
+
+	// Tabs share the width, the last one taking what is left over
+	tb.Tabs = tb.Tabs[:0]
+	width := r.Width / uint16(len(clients))
+	for i, c := range clients {
+		tab := Tab{Client: c, X: int16(uint16(i) * width), Width: width}
+		if i == len(clients)-1 {
+			tab.Width = r.Width - uint16(i)*width
+		}
+		tb.Tabs = append(tb.Tabs, tab)
+	}
+
+	if !tb.mapped {
+		wm.x.MapWindow(tb.Window)
+		tb.mapped = true
+	}
+	wm.redrawTabBar(ws.ID)
+}
+
+// hideTabBars unmaps the tab bars of workspaces that are hidden, empty or
+// no longer tabbed
+func (wm *WindowManager) hideTabBars() {
+	for id, tb := range wm.tabBars {
+		ws := wm.workspaces[id]
+		_, tabbed := ws.Layout.(*TabbedLayout)
+		if tb.mapped && (!tabbed || !wm.isVisible(id) || len(ws.TiledClients()) == 0) {
+			wm.x.UnmapWindow(tb.Window)
+			tb.mapped = false
+		}
+	}
+}
+
+// redrawTabBar refreshes the titles of a workspace's tabs and draws them,
+// after a title, focus or urgency change
+func (wm *WindowManager) redrawTabBar(workspace int) {
+	tb := wm.tabBars[workspace]
+	if tb == nil || !tb.mapped {
+		return
+	}
+	for i := range tb.Tabs {
+		tb.Tabs[i].Title = tabTitle(tb.Tabs[i].Client)
+	}
+
+	// Drawing needs Xft on a real display
+	if wm.tabFont == nil {
+		return
+	}
+	if tb.draw == nil {
+		// Sync xgb connection to ensure window is visible to Xlib
+		wm.conn.Sync()
+		tb.draw = wm.tabFont.newDraw(tb.Window)
+	}
+
+	focused := wm.workspaces[workspace].Focused
+	for _, tab := range tb.Tabs {
+		bg, fg := wm.config.UnfocusedBorderColor, gridColors.Text
+		switch {
+		case tab.Client == focused:
+			bg, fg = wm.config.FocusedBorderColor, gridColors.Crust
+		case tab.Client.Urgent:
+			bg, fg = wm.config.UrgentBorderColor, gridColors.Crust
+		}
+
+		r := Rect{X: tab.X, Width: tab.Width, Height: tb.Geometry.Height}
+		tb.draw.Rect(r, bg)
+		if tab.X > 0 {
+			tb.draw.Rect(Rect{X: tab.X, Width: 1, Height: r.Height}, gridColors.Base)
+		}
+
+		// Center the title, shortened to fit
+		const padding = 8
+		title := wm.fitTabTitle(tab.Title, int(tab.Width)-2*padding)
+		x := int(tab.X) + (int(tab.Width)-wm.tabFont.Width(title))/2
+		y := (int(r.Height)-wm.tabFont.Height())/2 + wm.tabFont.Ascent()
+		tb.draw.String(title, x, y, fg)
+	}
+}
+
+// tabTitle returns the text shown on a window's tab, from the names cached
+// on the client
+func tabTitle(c *Client) string {
+	if c.Title != "" {
+		return c.Title
+	}
+	if c.Class != "" {
+		return c.Class
+	}
+	return "Unknown"
+}
+
+// fitTabTitle shortens title with an ellipsis until it fits in width
+// pixels
+func (wm *WindowManager) fitTabTitle(title string, width int) string {
+	if wm.tabFont.Width(title) <= width {
+		return title
+	}
+	for title != "" {
+		_, size := utf8.DecodeLastRuneInString(title)
+		title = title[:len(title)-size]
+		if wm.tabFont.Width(title+"…") <= width {
+			break
+		}
+	}
+	return title + "…"
+}
+
+// tabBarFor returns the tab bar whose window is win, or nil
+func (wm *WindowManager) tabBarFor(win xproto.Window) *TabBar {
+	for _, tb := range wm.tabBars {
+		if tb.Window == win {
+			return tb
+		}
+	}
+	return nil
+}
+
+// handleTabClick focuses the window whose tab was clicked, and cycles tabs
+// when scrolling over the bar. It reports whether the press was on a tab
+// bar.
+func (wm *WindowManager) handleTabClick(e xproto.ButtonPressEvent) bool {
+	tb := wm.tabBarFor(e.Event)
+	if tb == nil {
+		return false
+	}
+	ws := wm.workspaces[tb.Workspace]
+
+	var c *Client
+	switch e.Detail {
+	case xproto.ButtonIndex1:
+		for _, tab := range tb.Tabs {
+			if e.EventX >= tab.X && int(e.EventX) < int(tab.X)+int(tab.Width) {
+				c = tab.Client
+			}
+		}
+	case xproto.ButtonIndex4:
+		c = ws.FocusPrev()
+	case xproto.ButtonIndex5:
+		c = ws.FocusNext()
+	}
+	if c != nil {
+		wm.focus(c)
+	}
+	return true
+}
+
+// handleTabBarExpose redraws a tab bar that was uncovered
+func (wm *WindowManager) handleTabBarExpose(e xproto.ExposeEvent) {
+	if tb := wm.tabBarFor(e.Window); tb != nil && e.Count == 0 {
+		wm.redrawTabBar(tb.Workspace)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// mapTitledClient maps a client window with a WM_NAME
+func mapTitledClient(wm *WindowManager, f *fakeBackend, title string) xproto.Window {
+	win := f.addWindow(Rect{X: 10, Y: 10, Width: 400, Height: 300})
+	f.ChangeProperty(win, xproto.AtomWmName, xproto.AtomString, 8, []byte(title))
+	wm.handleMapRequest(xproto.MapRequestEvent{Parent: f.root, Window: win})
+	return win
+}
+
+func TestTabbedLayoutShowsTabBar(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.currentWorkspace().SetLayout(NewTabbedLayout())
+
+	vim := mapTitledClient(wm, f, "vim")
+	shell := mapTitledClient(wm, f, "shell")
+
+	tb := wm.tabBars[0]
+	if tb == nil || !f.window(tb.Window).mapped || !f.window(tb.Window).overrideRedirect {
+		t.Fatalf("tab bar not shown: %+v", tb)
+	}
+	if tb.Geometry != (Rect{X: 4, Y: 4, Width: 1912, Height: 24}) {
+		t.Errorf("tab bar = %+v", tb.Geometry)
+	}
+	if len(tb.Tabs) != 2 || tb.Tabs[0].Title != "vim" || tb.Tabs[1].Title != "shell" || tb.Tabs[1].X != 956 {
+		t.Errorf("tabs = %+v", tb.Tabs)
+	}
+
+	// Only the focused window shows, below the bar
+	if got := f.window(shell).geom; got != (Rect{X: 4, Y: 28, Width: 1908, Height: 1044}) {
+		t.Errorf("focused window = %+v", got)
+	}
+	if f.window(vim).mapped {
+		t.Error("unfocused window is mapped")
+	}
+}
+
+func TestTabClickFocusesWindow(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.currentWorkspace().SetLayout(NewTabbedLayout())
+	vim := mapTitledClient(wm, f, "vim")
+	shell := mapTitledClient(wm, f, "shell")
+	tb := wm.tabBars[0]
+
+	wm.handleEvent(xproto.ButtonPressEvent{Event: tb.Window, Detail: xproto.ButtonIndex1, EventX: 100})
+	if wm.focused.Window != vim || !f.window(vim).mapped || f.window(shell).mapped {
+		t.Errorf("after clicking the first tab: focused %d, vim mapped %v, shell mapped %v",
+			wm.focused.Window, f.window(vim).mapped, f.window(shell).mapped)
+	}
+
+	// Scrolling cycles through the tabs
+	wm.handleEvent(xproto.ButtonPressEvent{Event: tb.Window, Detail: xproto.ButtonIndex5})
+	if wm.focused.Window != shell || !f.window(shell).mapped {
+		t.Errorf("after scrolling: focused %d", wm.focused.Window)
+	}
+}
+
+func TestTabBarFollowsTitlesAndHides(t *testing.T) {
+	wm, f := newTestWM(t)
+	wm.currentWorkspace().SetLayout(NewTabbedLayout())
+	win := mapTitledClient(wm, f, "vim")
+	tb := wm.tabBars[0]
+
+	f.ChangeProperty(win, xproto.AtomWmName, xproto.AtomString, 8, []byte("vim - main.go"))
+	wm.handlePropertyNotify(xproto.PropertyNotifyEvent{Window: win, Atom: xproto.AtomWmName})
+	if tb.Tabs[0].Title != "vim - main.go" {
+		t.Errorf("title = %q", tb.Tabs[0].Title)
+	}
+
+	// Redraws use the cached title until the next PropertyNotify
+	f.ChangeProperty(win, xproto.AtomWmName, xproto.AtomString, 8, []byte("unannounced"))
+	wm.redrawTabBar(0)
+	if tb.Tabs[0].Title != "vim - main.go" {
+		t.Errorf("title after redraw = %q", tb.Tabs[0].Title)
+	}
+
+	wm.switchToWorkspace(1)
+	if f.window(tb.Window).mapped {
+		t.Error("tab bar of a hidden workspace is mapped")
+	}
+	wm.switchToWorkspace(0)
+	if !f.window(tb.Window).mapped {
+		t.Error("tab bar not shown again")
+	}
+
+	wm.currentWorkspace().SetLayout(NewTallLayout())
+	wm.tile()
+	if f.window(tb.Window).mapped {
+		t.Error("tab bar shown in the tall layout")
+	}
+}
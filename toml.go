@@ -0,0 +1,519 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A small TOML parser covering what the config file needs: tables, arrays of
+// tables, dotted table headers, single-line strings, integers, floats,
+// booleans, arrays and inline tables. Every value remembers the line it came
+// from so config errors can point at it. Dates and times, multi-line strings
+// and dotted keys are rejected with an error saying so; the README lists the
+// same subset.
+
+// tomlValue is a parsed value and the line it was defined on. Value holds a
+// string, int64, float64, bool, []*tomlValue, *tomlTable or []*tomlTable.
+type tomlValue struct {
+	Line  int
+	Value interface{}
+}
+
+// tomlTable is a table with its keys in definition order
+type tomlTable struct {
+	Line    int
+	Keys    []string
+	Values  map[string]*tomlValue
+	defined bool // false when only created implicitly by a dotted header
+}
+
+func newTOMLTable(line int) *tomlTable {
+	return &tomlTable{Line: line, Values: make(map[string]*tomlValue)}
+}
+
+// tomlError is a syntax error at a specific line
+type tomlError struct {
+	Line int
+	Msg  string
+}
+
+func (e *tomlError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+// parseTOML parses a document into its root table
+func parseTOML(src string) (*tomlTable, error) {
+	p := &tomlParser{src: src, line: 1}
+	root := newTOMLTable(1)
+	current := root
+
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return root, nil
+		}
+
+		var err error
+		if p.peek() == '[' {
+			current, err = p.parseHeader(root)
+		} else {
+			err = p.parseKeyValue(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Anything after a statement must be a comment or the end of the line
+		p.skipBlank(false)
+		if !p.eof() && p.peek() != '\n' {
+			return nil, p.errorf("unexpected %q after value", p.peek())
+		}
+	}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return &tomlError{Line: p.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// skipBlank skips spaces, tabs and comments, and newlines if newlines is set
+func (p *tomlParser) skipBlank(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && newlines:
+			p.pos++
+			p.line++
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// parseHeader parses [table] or [[array]] and returns the table it opens
+func (p *tomlParser) parseHeader(root *tomlTable) (*tomlTable, error) {
+	line := p.line
+	p.pos++ // [
+	array := !p.eof() && p.peek() == '['
+	if array {
+		p.pos++
+	}
+
+	path, err := p.parseKeyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	p.skipBlank(false)
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return nil, p.errorf("expected %q to close table header", closing)
+	}
+	p.pos += len(closing)
+
+	// Walk to the parent, creating intermediate tables as needed
+	parent := root
+	for _, key := range path[:len(path)-1] {
+		if parent, err = p.descend(parent, key, line); err != nil {
+			return nil, err
+		}
+	}
+
+	last := path[len(path)-1]
+	existing, exists := parent.Values[last]
+
+	if array {
+		table := newTOMLTable(line)
+		table.defined = true
+		if !exists {
+			parent.set(last, &tomlValue{Line: line, Value: []*tomlTable{table}})
+			return table, nil
+		}
+		tables, ok := existing.Value.([]*tomlTable)
+		if !ok {
+			return nil, &tomlError{Line: line, Msg: fmt.Sprintf("%q is not an array of tables", strings.Join(path, "."))}
+		}
+		existing.Value = append(tables, table)
+		return table, nil
+	}
+
+	if !exists {
+		table := newTOMLTable(line)
+		table.defined = true
+		parent.set(last, &tomlValue{Line: line, Value: table})
+		return table, nil
+	}
+	table, ok := existing.Value.(*tomlTable)
+	if !ok || table.defined {
+		return nil, &tomlError{Line: line, Msg: fmt.Sprintf("table [%s] defined twice", strings.Join(path, "."))}
+	}
+	table.defined = true
+	table.Line = line
+	return table, nil
+}
+
+// descend returns the table named key inside parent, creating it implicitly.
+// For arrays of tables the most recently added table is used.
+func (p *tomlParser) descend(parent *tomlTable, key string, line int) (*tomlTable, error) {
+	v, ok := parent.Values[key]
+	if !ok {
+		table := newTOMLTable(line)
+		parent.set(key, &tomlValue{Line: line, Value: table})
+		return table, nil
+	}
+	switch t := v.Value.(type) {
+	case *tomlTable:
+		return t, nil
+	case []*tomlTable:
+		return t[len(t)-1], nil
+	}
+	return nil, &tomlError{Line: line, Msg: fmt.Sprintf("%q is already defined as a value", key)}
+}
+
+// set adds a key to a table, keeping definition order
+func (t *tomlTable) set(key string, v *tomlValue) {
+	if _, exists := t.Values[key]; !exists {
+		t.Keys = append(t.Keys, key)
+	}
+	t.Values[key] = v
+}
+
+// parseKeyPath parses a possibly dotted key: a.b."c d"
+func (p *tomlParser) parseKeyPath() ([]string, error) {
+	var path []string
+	for {
+		p.skipBlank(false)
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, key)
+		p.skipBlank(false)
+		if p.eof() || p.peek() != '.' {
+			return path, nil
+		}
+		p.pos++
+	}
+}
+
+// parseKey parses a bare or quoted key
+func (p *tomlParser) parseKey() (string, error) {
+	if p.eof() {
+		return "", p.errorf("expected a key")
+	}
+	switch p.peek() {
+	case '"':
+		return p.parseBasicString()
+	case '\'':
+		return p.parseLiteralString()
+	}
+
+	start := p.pos
+	for !p.eof() && isBareKeyChar(p.peek()) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("expected a key, found %q", p.peek())
+	}
+	return p.src[start:p.pos], nil
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// parseKeyValue parses key = value into table
+func (p *tomlParser) parseKeyValue(table *tomlTable) error {
+	line := p.line
+	key, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipBlank(false)
+	if !p.eof() && p.peek() == '.' {
+		return p.errorf("dotted keys are not supported, use a [table] header")
+	}
+	if p.eof() || p.peek() != '=' {
+		return p.errorf("expected '=' after key %q", key)
+	}
+	p.pos++
+	p.skipBlank(false)
+
+	if _, exists := table.Values[key]; exists {
+		return &tomlError{Line: line, Msg: fmt.Sprintf("key %q defined twice", key)}
+	}
+	v, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	table.set(key, v)
+	return nil
+}
+
+// parseValue parses any value
+func (p *tomlParser) parseValue() (*tomlValue, error) {
+	if p.eof() || p.peek() == '\n' {
+		return nil, p.errorf("expected a value")
+	}
+	line := p.line
+
+	switch c := p.peek(); {
+	case strings.HasPrefix(p.src[p.pos:], `"""`) || strings.HasPrefix(p.src[p.pos:], "'''"):
+		return nil, p.errorf("multi-line strings are not supported")
+	case c == '"':
+		s, err := p.parseBasicString()
+		return &tomlValue{Line: line, Value: s}, err
+	case c == '\'':
+		s, err := p.parseLiteralString()
+		return &tomlValue{Line: line, Value: s}, err
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	}
+
+	// Bare word: boolean or number
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	word := p.src[start:p.pos]
+
+	switch word {
+	case "true":
+		return &tomlValue{Line: line, Value: true}, nil
+	case "false":
+		return &tomlValue{Line: line, Value: false}, nil
+	}
+
+	if isDateTime(word) {
+		return nil, &tomlError{Line: line, Msg: fmt.Sprintf("dates and times are not supported, quote %q as a string", word)}
+	}
+	if v, ok := parseNumber(word); ok {
+		return &tomlValue{Line: line, Value: v}, nil
+	}
+	if digits := strings.TrimLeft(word, "+-"); len(digits) > 1 && digits[0] == '0' && isDigit(digits[1]) {
+		return nil, &tomlError{Line: line, Msg: fmt.Sprintf("invalid number %q (no leading zeros)", word)}
+	}
+	if c := word[0]; isDigit(c) || c == '+' || c == '-' || c == '_' {
+		return nil, &tomlError{Line: line, Msg: fmt.Sprintf("invalid number %q", word)}
+	}
+	return nil, &tomlError{Line: line, Msg: fmt.Sprintf("invalid value %q (strings must be quoted)", word)}
+}
+
+// TOML number syntax: underscores only between digits, base prefixes only
+// on unsigned integers, and lowercase inf and nan
+var (
+	tomlDecimal  = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	tomlFloat    = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+	tomlPrefixed = map[string]*regexp.Regexp{
+		"0x": regexp.MustCompile(`^[0-9a-fA-F](_?[0-9a-fA-F])*$`),
+		"0o": regexp.MustCompile(`^[0-7](_?[0-7])*$`),
+		"0b": regexp.MustCompile(`^[01](_?[01])*$`),
+	}
+)
+
+// parseNumber parses a TOML integer or float, returning an int64 or a
+// float64
+func parseNumber(word string) (interface{}, bool) {
+	switch strings.TrimLeft(word, "+-") {
+	case "inf", "nan":
+		if len(word) > 4 {
+			return nil, false
+		}
+		f, err := strconv.ParseFloat(word, 64)
+		return f, err == nil
+	}
+
+	clean := strings.ReplaceAll(word, "_", "")
+	if len(word) > 2 {
+		if digits, ok := tomlPrefixed[word[:2]]; ok {
+			if !digits.MatchString(word[2:]) {
+				return nil, false
+			}
+			i, err := strconv.ParseInt(clean, 0, 64)
+			return i, err == nil
+		}
+	}
+	if tomlDecimal.MatchString(word) {
+		i, err := strconv.ParseInt(clean, 10, 64)
+		return i, err == nil
+	}
+	if tomlFloat.MatchString(word) {
+		f, err := strconv.ParseFloat(clean, 64)
+		return f, err == nil
+	}
+	return nil, false
+}
+
+// isDateTime reports whether a bare word is a TOML date or time, such as
+// 1979-05-27 or 07:32:00
+func isDateTime(word string) bool {
+	return len(word) >= 5 && isDigit(word[0]) && isDigit(word[1]) &&
+		(word[2] == ':' || isDigit(word[2]) && isDigit(word[3]) && word[4] == '-')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parseBasicString parses a double-quoted string with escapes
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++ // "
+	var sb strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		p.pos++
+		switch c {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated string")
+			}
+			esc := p.peek()
+			p.pos++
+			switch esc {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '"', '\\':
+				sb.WriteByte(esc)
+			case 'u', 'U':
+				n := 4
+				if esc == 'U' {
+					n = 8
+				}
+				if p.pos+n > len(p.src) {
+					return "", p.errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(code)) {
+					return "", p.errorf("invalid unicode escape")
+				}
+				sb.WriteRune(rune(code))
+				p.pos += n
+			default:
+				return "", p.errorf("invalid escape \\%c", esc)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+}
+
+// parseLiteralString parses a single-quoted string (no escapes)
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++ // '
+	start := p.pos
+	for !p.eof() && p.peek() != '\'' {
+		if p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		p.pos++
+	}
+	if p.eof() {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.src[start:p.pos]
+	p.pos++
+	return s, nil
+}
+
+// parseArray parses [a, b, c], which may span several lines
+func (p *tomlParser) parseArray() (*tomlValue, error) {
+	line := p.line
+	p.pos++ // [
+	var items []*tomlValue
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return nil, &tomlError{Line: line, Msg: "unterminated array"}
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return &tomlValue{Line: line, Value: items}, nil
+		}
+
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		p.skipBlank(true)
+		if p.eof() {
+			return nil, &tomlError{Line: line, Msg: "unterminated array"}
+		}
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// parseInlineTable parses { key = value, ... } on a single line
+func (p *tomlParser) parseInlineTable() (*tomlValue, error) {
+	line := p.line
+	p.pos++ // {
+	table := newTOMLTable(line)
+	table.defined = true
+
+	p.skipBlank(false)
+	if !p.eof() && p.peek() == '}' {
+		p.pos++
+		return &tomlValue{Line: line, Value: table}, nil
+	}
+	for {
+		p.skipBlank(false)
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		if p.eof() || p.peek() == '\n' {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return &tomlValue{Line: line, Value: table}, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
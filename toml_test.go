@@ -0,0 +1,173 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseTOMLValues(t *testing.T) {
+	root, err := parseTOML(`
+# comment
+name = "gowm" # trailing comment
+literal = 'C:\path'
+escaped = "tab\there \"q\" \u00e9"
+count = 1_000
+hex = 0xff
+ratio = 0.55
+on = true
+list = [
+  "a",  # first
+  "b",
+]
+inline = { x = 1, y = "two" }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"name":    "gowm",
+		"literal": `C:\path`,
+		"escaped": "tab\there \"q\" é",
+		"count":   int64(1000),
+		"hex":     int64(255),
+		"ratio":   0.55,
+		"on":      true,
+	}
+	for key, value := range want {
+		if got := root.Values[key].Value; got != value {
+			t.Errorf("%s = %#v, want %#v", key, got, value)
+		}
+	}
+
+	list := root.Values["list"].Value.([]*tomlValue)
+	if len(list) != 2 || list[1].Value != "b" || list[1].Line != 12 {
+		t.Errorf("list = %+v", list)
+	}
+
+	inline := root.Values["inline"].Value.(*tomlTable)
+	if inline.Values["y"].Value != "two" {
+		t.Errorf("inline = %+v", inline.Values)
+	}
+
+	if strings.Join(root.Keys, ",") != "name,literal,escaped,count,hex,ratio,on,list,inline" {
+		t.Errorf("key order = %v", root.Keys)
+	}
+}
+
+func TestParseTOMLNumbers(t *testing.T) {
+	valid := map[string]interface{}{
+		"0":           int64(0),
+		"-17":         int64(-17),
+		"1_000_000":   int64(1000000),
+		"0xdead_BEEF": int64(0xdeadbeef),
+		"0o17":        int64(15),
+		"0b1010":      int64(10),
+		"-0.5":        -0.5,
+		"6.02e2_3":    6.02e23,
+		"1E-2":        0.01,
+		"+inf":        math.Inf(1),
+		"-inf":        math.Inf(-1),
+	}
+	for word, want := range valid {
+		root, err := parseTOML("a = " + word)
+		if err != nil {
+			t.Errorf("%s: %v", word, err)
+			continue
+		}
+		if got := root.Values["a"].Value; got != want {
+			t.Errorf("%s = %#v, want %#v", word, got, want)
+		}
+	}
+	if root, err := parseTOML("a = nan"); err != nil || !math.IsNaN(root.Values["a"].Value.(float64)) {
+		t.Errorf("nan: %v", err)
+	}
+
+	for _, word := range []string{
+		"_8", "1__0", "8_", "1_.5", "1._5", "0x_1", "+0x10", "-0o7", "0x1p-2",
+		"Infinity", "INF", "NaN", "+NAN", "++inf", ".5", "1.", "1e", "0b102",
+	} {
+		if _, err := parseTOML("a = " + word); err == nil {
+			t.Errorf("%s: expected an error", word)
+		} else if !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("%s: error = %v", word, err)
+		}
+	}
+}
+
+func TestParseTOMLTables(t *testing.T) {
+	root, err := parseTOML(`
+[a]
+x = 1
+
+[b.c]
+y = 2
+
+[[item]]
+name = "first"
+
+[[item]]
+name = "second"
+
+[item.sub]
+z = 3
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root.Values["a"].Value.(*tomlTable).Values["x"].Value != int64(1) {
+		t.Error("[a] not parsed")
+	}
+	b := root.Values["b"].Value.(*tomlTable)
+	if b.Values["c"].Value.(*tomlTable).Values["y"].Value != int64(2) {
+		t.Error("[b.c] not parsed")
+	}
+
+	items := root.Values["item"].Value.([]*tomlTable)
+	if len(items) != 2 || items[1].Values["name"].Value != "second" || items[1].Line != 11 {
+		t.Fatalf("items = %+v", items)
+	}
+	// [item.sub] belongs to the most recent [[item]]
+	if _, ok := items[1].Values["sub"]; !ok {
+		t.Error("[item.sub] not attached to last item")
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		line int
+		msg  string
+	}{
+		{"a = 1\nb = nope", 2, "strings must be quoted"},
+		{"a = 1\na = 2", 2, "defined twice"},
+		{"[x]\n[x]", 2, "defined twice"},
+		{"a = \"open", 1, "unterminated string"},
+		{"a = [1, 2", 1, "unterminated array"},
+		{"a = 1 2", 1, "after value"},
+		{"\n\n[x", 3, "close table header"},
+		{"a.b = 1", 1, "dotted keys"},
+		{"a = 1\n[[a]]", 2, "not an array of tables"},
+		{`a = "\q"`, 1, "invalid escape"},
+		{"a = \"\"\"\ntext\"\"\"", 1, "multi-line strings are not supported"},
+		{"a = '''text'''", 1, "multi-line strings are not supported"},
+		{"a = 1979-05-27", 1, "dates and times are not supported"},
+		{"a = 1979-05-27T07:32:00Z", 1, "dates and times are not supported"},
+		{"a = 07:32:00", 1, "dates and times are not supported"},
+		{"a = 010", 1, "no leading zeros"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseTOML(tt.src)
+		te, ok := err.(*tomlError)
+		if !ok {
+			t.Errorf("%q: error = %v", tt.src, err)
+			continue
+		}
+		if te.Line != tt.line || !strings.Contains(te.Msg, tt.msg) {
+			t.Errorf("%q: got line %d %q, want line %d containing %q", tt.src, te.Line, te.Msg, tt.line, tt.msg)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"encoding/binary"
 	"log"
-	"time"
 
 	"github.com/jezek/xgb/xproto"
 )
@@ -16,8 +15,8 @@ const (
 // checkUrgentHint checks if a window has the urgent hint set
 func (wm *WindowManager) checkUrgentHint(win xproto.Window) bool {
 	// Get WM_HINTS property
-	reply, err := xproto.GetProperty(wm.conn, false, win,
-		xproto.AtomWmHints, xproto.AtomWmHints, 0, 9).Reply()
+	reply, err := wm.x.GetProperty(win,
+		xproto.AtomWmHints, xproto.AtomWmHints, 0, 9)
 	if err != nil || reply == nil || reply.ValueLen < 1 {
 		return false
 	}
@@ -47,27 +46,31 @@ func (wm *WindowManager) handleUrgentHint(win xproto.Window) {
 	if urgent && !client.Urgent {
 		client.Urgent = true
 		wm.setUrgentBorder(client)
+		wm.emitWindow("urgent", "", client)
+		wm.redrawTabBar(client.Workspace)
 		log.Printf("Window %d marked urgent", win)
 	} else if !urgent && client.Urgent {
 		client.Urgent = false
 		wm.setNormalBorder(client)
+		wm.emitWindow("urgent", "", client)
+		wm.redrawTabBar(client.Workspace)
 		log.Printf("Window %d urgency cleared", win)
 	}
 }
 
 // setUrgentBorder sets the urgent border color on a window
 func (wm *WindowManager) setUrgentBorder(c *Client) {
-	xproto.ChangeWindowAttributes(wm.conn, c.Window,
+	wm.x.ChangeWindowAttributes(c.Window,
 		xproto.CwBorderPixel, []uint32{wm.config.UrgentBorderColor})
 }
 
 // setNormalBorder sets the normal (unfocused) border color on a window
 func (wm *WindowManager) setNormalBorder(c *Client) {
 	if c == wm.focused {
-		xproto.ChangeWindowAttributes(wm.conn, c.Window,
+		wm.x.ChangeWindowAttributes(c.Window,
 			xproto.CwBorderPixel, []uint32{wm.config.FocusedBorderColor})
 	} else {
-		xproto.ChangeWindowAttributes(wm.conn, c.Window,
+		wm.x.ChangeWindowAttributes(c.Window,
 			xproto.CwBorderPixel, []uint32{wm.config.UnfocusedBorderColor})
 	}
 }
@@ -78,6 +81,7 @@ func (wm *WindowManager) clearUrgent(c *Client) {
 		c.Urgent = false
 		// Clear the WM_HINTS urgency flag
 		wm.clearUrgentHint(c.Window)
+		wm.emitWindow("urgent", "", c)
 		log.Printf("Window %d urgency cleared on focus", c.Window)
 	}
 }
@@ -85,8 +89,8 @@ func (wm *WindowManager) clearUrgent(c *Client) {
 // clearUrgentHint clears the urgent hint in WM_HINTS
 func (wm *WindowManager) clearUrgentHint(win xproto.Window) {
 	// Get current WM_HINTS
-	reply, err := xproto.GetProperty(wm.conn, false, win,
-		xproto.AtomWmHints, xproto.AtomWmHints, 0, 9).Reply()
+	reply, err := wm.x.GetProperty(win,
+		xproto.AtomWmHints, xproto.AtomWmHints, 0, 9)
 	if err != nil || reply == nil || reply.ValueLen < 1 {
 		return
 	}
@@ -104,34 +108,14 @@ func (wm *WindowManager) clearUrgentHint(win xproto.Window) {
 	copy(newValue, reply.Value)
 	binary.LittleEndian.PutUint32(newValue, flags)
 
-	xproto.ChangeProperty(wm.conn, xproto.PropModeReplace, win,
-		xproto.AtomWmHints, xproto.AtomWmHints, 32,
-		uint32(len(newValue)/4), newValue)
-}
-
-// flashUrgent flashes the urgent border (optional visual effect)
-func (wm *WindowManager) flashUrgent(c *Client) {
-	if !c.Urgent {
-		return
-	}
-
-	// Flash effect: alternate colors
-	go func() {
-		for i := 0; i < 3; i++ {
-			xproto.ChangeWindowAttributes(wm.conn, c.Window,
-				xproto.CwBorderPixel, []uint32{wm.config.FocusedBorderColor})
-			time.Sleep(100 * time.Millisecond)
-			xproto.ChangeWindowAttributes(wm.conn, c.Window,
-				xproto.CwBorderPixel, []uint32{wm.config.UrgentBorderColor})
-			time.Sleep(100 * time.Millisecond)
-		}
-	}()
+	wm.x.ChangeProperty(win,
+		xproto.AtomWmHints, xproto.AtomWmHints, 32, newValue)
 }
 
 // checkNetWMStateDemandsAttention checks _NET_WM_STATE for demands attention
 func (wm *WindowManager) checkNetWMStateDemandsAttention(win xproto.Window) bool {
-	reply, err := xproto.GetProperty(wm.conn, false, win,
-		wm.atoms.NET_WM_STATE, xproto.AtomAtom, 0, 32).Reply()
+	reply, err := wm.x.GetProperty(win,
+		wm.atoms.NET_WM_STATE, xproto.AtomAtom, 0, 32)
 	if err != nil || reply == nil {
 		return false
 	}
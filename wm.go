@@ -11,7 +11,8 @@ import (
 
 // WindowManager is the main window manager struct
 type WindowManager struct {
-	conn   *xgb.Conn
+	conn   *xgb.Conn // nil when running against a fake backend
+	x      Backend
 	root   xproto.Window
 	screen *xproto.ScreenInfo
 
@@ -21,8 +22,9 @@ type WindowManager struct {
 	clients    map[xproto.Window]*Client
 	focused    *Client
 	config     *Config
+	configPath string
 	atoms      Atoms
-	layouts    []Layout
+	layouts    []string // Layout names layout.next cycles through
 	running    bool
 	wmCheckWin xproto.Window
 
@@ -31,17 +33,36 @@ type WindowManager struct {
 	maxKeycode     xproto.Keycode
 	keysymsPerCode int
 	keysyms        []xproto.Keysym
+	unmappedKeys   []string // bindings whose keysym has no keycode
 
-	// Struts (reserved space for panels/bars)
-	// [left, right, top, bottom]
-	struts        [4]uint32
-	strutsEnabled bool // Whether to respect struts when tiling
+	// Active key submap (nil in the default keymap)
+	submap *activeSubmap
+
+	// Panels and bars, whose struts reserve space
+	docks         map[xproto.Window]*Dock
+	strutsEnabled bool   // Whether to respect struts when tiling
+	workarea      []byte // Last _NET_WORKAREA published
+
+	// Gaps and border widths toggled off, to toggle back on
+	toggledSpacing map[spacingKey]uint16
 
 	// Scratchpad
 	scratchpad *Scratchpad
 
-	// Mouse drag state
-	drag DragState
+	// Last window reported in _NET_ACTIVE_WINDOW
+	activeWindow xproto.Window
+
+	// Monitors, each showing one workspace. current is the workspace of
+	// the monitor with focus.
+	monitors []*Monitor
+
+	// Display profile last applied, and the monitors connected then
+	displayProfile string
+	outputsKey     string
+
+	// Mouse bindings resolved from the config, and drag state
+	mouseBindings map[MouseButton]MouseAction
+	drag          DragState
 
 	// Window rules
 	rules []WindowRule
@@ -49,52 +70,74 @@ type WindowManager struct {
 	// IPC server
 	ipc *IPCServer
 
+	// Functions queued to run on the event loop (timers, etc.)
+	calls chan func()
+
 	// Grid select
 	gridSelect *GridSelect
+
+	// Tab bars of tabbed workspaces, by workspace index
+	tabBars map[int]*TabBar
+	tabFont *xftFont // nil without a display for Xft
 }
 
-// NewWindowManager creates a new window manager
+// NewWindowManager creates a new window manager on an X connection
 func NewWindowManager(conn *xgb.Conn) (*WindowManager, error) {
 	setup := xproto.Setup(conn)
 	screen := setup.DefaultScreen(conn)
 
+	wm := newWindowManager(NewXBackend(conn, screen.Root), screen,
+		setup.MinKeycode, setup.MaxKeycode)
+	wm.conn = conn
+
+	// Initialize grid select and the tab font (need a real display for Xft)
+	wm.gridSelect = NewGridSelect(wm)
+	wm.tabFont = openXftFont("Tabs", append([]string{tabFontName}, tabFontFallbacks...))
+
+	return wm, nil
+}
+
+// newWindowManager creates a window manager on top of any backend
+func newWindowManager(x Backend, screen *xproto.ScreenInfo, minKeycode, maxKeycode xproto.Keycode) *WindowManager {
 	wm := &WindowManager{
-		conn:          conn,
-		root:          screen.Root,
-		screen:        screen,
-		clients:       make(map[xproto.Window]*Client),
-		config:        DefaultConfig(),
-		running:       true,
-		minKeycode:    setup.MinKeycode,
-		maxKeycode:    setup.MaxKeycode,
-		strutsEnabled: true,
+		x:              x,
+		root:           screen.Root,
+		screen:         screen,
+		clients:        make(map[xproto.Window]*Client),
+		docks:          make(map[xproto.Window]*Dock),
+		tabBars:        make(map[int]*TabBar),
+		toggledSpacing: make(map[spacingKey]uint16),
+		running:        true,
+		calls:          make(chan func(), 16),
+		minKeycode:     minKeycode,
+		maxKeycode:     maxKeycode,
+		strutsEnabled:  true,
 	}
 
 	// Initialize layouts
-	wm.layouts = []Layout{
-		NewTallLayout(),
-		NewFullLayout(),
-		NewGridLayout(),
-		NewSpiralLayout(),
-		NewThreeColumnLayout(),
-		NewCenteredMasterLayout(),
-	}
+	wm.layouts = defaultLayouts
 
 	// Create 9 workspaces
 	for i := 1; i <= 9; i++ {
 		wm.workspaces = append(wm.workspaces, NewWorkspace(i-1, fmt.Sprintf("%d", i)))
 	}
 
-	// Initialize scratchpad
-	wm.scratchpad = DefaultScratchpad()
-
-	// Initialize grid select
-	wm.gridSelect = NewGridSelect(wm)
+	wm.setConfig(DefaultConfig())
 
-	// Initialize window rules
-	wm.rules = DefaultRules()
+	return wm
+}
 
-	return wm, nil
+// setConfig installs a configuration along with the rules and scratchpad it
+// carries. Keybindings are resolved separately by SetupKeybindings.
+func (wm *WindowManager) setConfig(cfg *Config) {
+	wm.config = cfg
+	for _, ws := range wm.workspaces {
+		ws.Settings = cfg.WorkspaceSettings[ws.ID]
+		ws.SetMagnifyFactor(cfg.MagnifyFactor)
+	}
+	wm.rules = cfg.Rules
+	wm.scratchpad = cfg.Scratchpad
+	wm.mouseBindings = resolveMouseBindings(cfg.MouseBindings)
 }
 
 // becomeWM requests window management control from X
@@ -108,8 +151,8 @@ func (wm *WindowManager) becomeWM() error {
 			xproto.EventMaskButtonPress,
 	)
 
-	err := xproto.ChangeWindowAttributesChecked(wm.conn, wm.root,
-		xproto.CwEventMask, []uint32{mask}).Check()
+	err := wm.x.ChangeWindowAttributesChecked(wm.root,
+		xproto.CwEventMask, []uint32{mask})
 
 	if err != nil {
 		return fmt.Errorf("another window manager is running: %v", err)
@@ -132,22 +175,32 @@ func (wm *WindowManager) init() error {
 	// Setup keybindings
 	wm.SetupKeybindings()
 
-	// Grab keys
+	// Grab keys and root mouse buttons
 	wm.grabKeys()
+	wm.grabRootButtons()
+
+	// Set the outputs up, then find the monitors and give each a workspace
+	wm.autoDisplayProfile()
+	wm.updateMonitors()
+	if err := wm.x.SelectMonitorEvents(); err != nil {
+		log.Printf("Monitor hotplug disabled: %v", err)
+	}
 
 	// Scan for existing windows
 	wm.scan()
 
-	// Update struts from any existing panels/bars
+	// Update struts from any existing panels/bars and tile around them,
+	// which also publishes _NET_WORKAREA
 	wm.updateStruts()
+	wm.tile()
 
 	return nil
 }
 
 // initKeyboardMapping loads the keyboard mapping from X
 func (wm *WindowManager) initKeyboardMapping() {
-	mapping, err := xproto.GetKeyboardMapping(wm.conn, wm.minKeycode,
-		byte(wm.maxKeycode-wm.minKeycode+1)).Reply()
+	mapping, err := wm.x.GetKeyboardMapping(wm.minKeycode,
+		byte(wm.maxKeycode-wm.minKeycode+1))
 	if err != nil {
 		log.Printf("Failed to get keyboard mapping: %v", err)
 		return
@@ -157,17 +210,29 @@ func (wm *WindowManager) initKeyboardMapping() {
 	wm.keysyms = mapping.Keysyms
 }
 
-// keysymToKeycode converts a keysym to a keycode
+// keysymToKeycode converts a keysym to the first keycode producing it
 func (wm *WindowManager) keysymToKeycode(keysym xproto.Keysym) xproto.Keycode {
+	if codes := wm.keysymToKeycodes(keysym); len(codes) > 0 {
+		return codes[0]
+	}
+	return 0
+}
+
+// keysymToKeycodes returns every keycode that produces a keysym. Layouts
+// often put the same keysym on several keys, and each of them should
+// trigger a binding.
+func (wm *WindowManager) keysymToKeycodes(keysym xproto.Keysym) []xproto.Keycode {
+	var codes []xproto.Keycode
 	for i := int(wm.minKeycode); i <= int(wm.maxKeycode); i++ {
 		for j := 0; j < wm.keysymsPerCode; j++ {
 			idx := (i-int(wm.minKeycode))*wm.keysymsPerCode + j
 			if idx < len(wm.keysyms) && wm.keysyms[idx] == keysym {
-				return xproto.Keycode(i)
+				codes = append(codes, xproto.Keycode(i))
+				break
 			}
 		}
 	}
-	return 0
+	return codes
 }
 
 // keycodeToKeysym converts a keycode to a keysym
@@ -179,41 +244,35 @@ func (wm *WindowManager) keycodeToKeysym(keycode xproto.Keycode) xproto.Keysym {
 	return 0
 }
 
+// lockModifiers are grabbed alongside every binding so Num Lock and Caps
+// Lock don't break them
+var lockModifiers = []uint16{0, xproto.ModMask2, xproto.ModMaskLock, xproto.ModMask2 | xproto.ModMaskLock}
+
 // grabKeys grabs all configured keybindings
 func (wm *WindowManager) grabKeys() {
 	// Ungrab all first
-	xproto.UngrabKey(wm.conn, xproto.GrabAny, wm.root, xproto.ModMaskAny)
-
-	// Modifiers to try (for num lock, caps lock combinations)
-	modifiers := []uint16{0, xproto.ModMask2, xproto.ModMaskLock, xproto.ModMask2 | xproto.ModMaskLock}
+	wm.x.UngrabAllKeys(wm.root)
 
 	for combo := range wm.config.Keybindings {
 		if combo.Keycode == 0 {
 			continue
 		}
-		for _, mod := range modifiers {
-			xproto.GrabKey(wm.conn,
-				true,
-				wm.root,
-				combo.Mod|mod,
-				combo.Keycode,
-				xproto.GrabModeAsync,
-				xproto.GrabModeAsync,
-			)
+		for _, mod := range lockModifiers {
+			wm.x.GrabKey(wm.root, combo.Mod|mod, combo.Keycode)
 		}
 	}
 }
 
 // scan looks for existing windows to manage
 func (wm *WindowManager) scan() {
-	tree, err := xproto.QueryTree(wm.conn, wm.root).Reply()
+	children, err := wm.x.QueryTree(wm.root)
 	if err != nil {
 		log.Printf("Failed to query tree: %v", err)
 		return
 	}
 
-	for _, win := range tree.Children {
-		attrs, err := xproto.GetWindowAttributes(wm.conn, win).Reply()
+	for _, win := range children {
+		attrs, err := wm.x.GetWindowAttributes(win)
 		if err != nil {
 			continue
 		}
@@ -223,6 +282,12 @@ func (wm *WindowManager) scan() {
 			continue
 		}
 
+		// Panels and bars are tracked, not managed
+		if wm.getWindowType(win) == WindowTypeDock {
+			wm.trackDock(win)
+			continue
+		}
+
 		wm.manageWindow(win)
 	}
 }
@@ -239,7 +304,7 @@ func (wm *WindowManager) manageWindow(win xproto.Window) {
 	}
 
 	// Get geometry
-	geom, err := xproto.GetGeometry(wm.conn, xproto.Drawable(win)).Reply()
+	geom, err := wm.x.GetGeometry(win)
 	if err != nil {
 		log.Printf("Failed to get geometry for window %d: %v", win, err)
 		return
@@ -283,16 +348,16 @@ func (wm *WindowManager) manageWindow(win xproto.Window) {
 
 	// Handle initial fullscreen
 	if wantsFullscreen {
-		client.X = 0
-		client.Y = 0
-		client.Width = wm.screen.WidthInPixels
-		client.Height = wm.screen.HeightInPixels
+		g := wm.monitorFor(client).Geometry
+		client.X, client.Y = g.X, g.Y
+		client.Width, client.Height = g.Width, g.Height
 	}
 
 	wm.clients[win] = client
+	wm.updateClientNames(client)
 
 	// Subscribe to events on this window
-	xproto.ChangeWindowAttributes(wm.conn, win,
+	wm.x.ChangeWindowAttributes(win,
 		xproto.CwEventMask, []uint32{
 			xproto.EventMaskEnterWindow |
 				xproto.EventMaskStructureNotify |
@@ -303,16 +368,16 @@ func (wm *WindowManager) manageWindow(win xproto.Window) {
 	if wantsFullscreen {
 		// Ensure fullscreen state is set (eww bar will check this)
 		wm.setFullscreenState(win, true)
-		xproto.ConfigureWindow(wm.conn, win,
+		wm.x.ConfigureWindow(win,
 			xproto.ConfigWindowX|xproto.ConfigWindowY|
 				xproto.ConfigWindowWidth|xproto.ConfigWindowHeight|
 				xproto.ConfigWindowBorderWidth|xproto.ConfigWindowStackMode,
-			[]uint32{0, 0, uint32(client.Width), uint32(client.Height), 0, xproto.StackModeAbove})
+			[]uint32{uint32(client.X), uint32(client.Y), uint32(client.Width), uint32(client.Height),
+				0, xproto.StackModeAbove})
 	} else {
-		xproto.ChangeWindowAttributes(wm.conn, win,
+		wm.x.ChangeWindowAttributes(win,
 			xproto.CwBorderPixel, []uint32{wm.config.UnfocusedBorderColor})
-		xproto.ConfigureWindow(wm.conn, win,
-			xproto.ConfigWindowBorderWidth, []uint32{uint32(wm.config.BorderWidth)})
+		wm.restoreBorder(client)
 	}
 
 	// Setup mouse button grabs for move/resize
@@ -321,13 +386,13 @@ func (wm *WindowManager) manageWindow(win xproto.Window) {
 	// Add to target workspace (may differ from current if rule-assigned)
 	wm.workspaces[targetWorkspace].Add(client)
 
-	// If window goes to a different workspace, unmap it
-	if targetWorkspace != wm.current {
-		xproto.UnmapWindow(wm.conn, win)
+	// If window goes to a hidden workspace, unmap it
+	if !wm.isVisible(targetWorkspace) {
+		wm.x.UnmapWindow(win)
 		client.Mapped = false
 	} else {
 		// Ensure window is mapped if on current workspace
-		xproto.MapWindow(wm.conn, win)
+		wm.x.MapWindow(win)
 		client.Mapped = true
 	}
 
@@ -343,13 +408,14 @@ func (wm *WindowManager) manageWindow(win xproto.Window) {
 	if targetWorkspace == wm.current {
 		wm.focus(client)
 		if wantsFullscreen {
-			xproto.ConfigureWindow(wm.conn, win,
+			wm.x.ConfigureWindow(win,
 				xproto.ConfigWindowStackMode, []uint32{xproto.StackModeAbove})
 		}
 	}
 
 	// Update EWMH
 	wm.updateClientList()
+	wm.emitWindow("window", "manage", client)
 }
 
 // unmanageWindow removes a window from management
@@ -379,6 +445,7 @@ func (wm *WindowManager) unmanageWindow(win xproto.Window) {
 
 	// Update EWMH
 	wm.updateClientList()
+	wm.emitWindow("window", "unmanage", client)
 }
 
 // focus sets input focus to a client
@@ -387,6 +454,12 @@ func (wm *WindowManager) focus(c *Client) {
 		return
 	}
 
+	// Focusing a window on another monitor makes that monitor current
+	if c.Workspace != wm.current && wm.isVisible(c.Workspace) {
+		wm.current = c.Workspace
+		wm.updateCurrentDesktop()
+	}
+
 	// Unfocus previous
 	if wm.focused != nil && wm.focused != c {
 		// Use urgent color if urgent, otherwise unfocused color
@@ -394,97 +467,119 @@ func (wm *WindowManager) focus(c *Client) {
 		if wm.focused.Urgent {
 			borderColor = wm.config.UrgentBorderColor
 		}
-		xproto.ChangeWindowAttributes(wm.conn, wm.focused.Window,
+		wm.x.ChangeWindowAttributes(wm.focused.Window,
 			xproto.CwBorderPixel, []uint32{borderColor})
 	}
 
 	// Clear urgent status on focus
 	wm.clearUrgent(c)
 
-	// Focus new - use RevertToParent which is safer
-	xproto.SetInputFocus(wm.conn,
-		xproto.InputFocusParent,
-		c.Window,
-		xproto.TimeCurrentTime,
-	)
+	// Focus new
+	wm.x.SetInputFocus(c.Window)
 
-	xproto.ChangeWindowAttributes(wm.conn, c.Window,
+	wm.x.ChangeWindowAttributes(c.Window,
 		xproto.CwBorderPixel, []uint32{wm.config.FocusedBorderColor})
 
 	// Raise window
-	xproto.ConfigureWindow(wm.conn, c.Window,
+	wm.x.ConfigureWindow(c.Window,
 		xproto.ConfigWindowStackMode, []uint32{xproto.StackModeAbove})
 
 	wm.focused = c
-	wm.currentWorkspace().Focused = c
+	ws := wm.workspaces[c.Workspace]
+	ws.Focused = c
+
+	// Monocle layouts only show the focused window, and the tab bar
+	// highlights it. Layouts hiding windows may bring it to the top, and
+	// the magnifier enlarges it.
+	_, hides := baseLayout(ws.Layout).(WindowHider)
+	refocus := ws.Layout.IsMonocle() || hides || magnifierOf(ws.Layout) != nil
+	if !c.Floating && refocus && wm.isVisible(c.Workspace) {
+		wm.tileMonitor(wm.monitorShowing(c.Workspace))
+	}
 
 	// Update EWMH
 	wm.updateActiveWindow()
 }
 
+// focusCurrent focuses the last focused window of the current workspace,
+// or clears the focus if the workspace is empty
+func (wm *WindowManager) focusCurrent() {
+	ws := wm.currentWorkspace()
+	if ws.Focused != nil {
+		wm.focus(ws.Focused)
+	} else if len(ws.Clients) > 0 {
+		wm.focus(ws.Clients[0])
+	} else {
+		wm.focused = nil
+		wm.updateActiveWindow()
+	}
+}
+
 // mapAllTiledWindows maps all tiled windows on the current workspace
 // Used when switching from monocle to other layouts
 func (wm *WindowManager) mapAllTiledWindows() {
 	ws := wm.currentWorkspace()
 	for _, client := range ws.TiledClients() {
-		xproto.MapWindow(wm.conn, client.Window)
+		wm.x.MapWindow(client.Window)
 	}
 }
 
-// tile arranges windows according to the current layout
+// tile arranges the visible workspace of every monitor
 func (wm *WindowManager) tile() {
-	ws := wm.currentWorkspace()
+	for _, m := range wm.monitors {
+		wm.tileMonitor(m)
+	}
+	wm.hideTabBars()
+	wm.updateWorkarea()
+}
+
+// tileMonitor arranges the windows of the workspace shown on a monitor
+// according to its layout
+func (wm *WindowManager) tileMonitor(m *Monitor) {
+	ws := wm.workspaces[m.Workspace]
 	clients := ws.TiledClients()
 
 	if len(clients) == 0 {
 		return
 	}
 
-	// Calculate usable area accounting for struts (panels/bars)
-	outerGap := wm.config.OuterGap
-	innerGap := wm.config.InnerGap
-
-	// Only apply struts if enabled
-	var left, right, top, bottom uint16
-	if wm.strutsEnabled {
-		left = uint16(wm.struts[0])
-		right = uint16(wm.struts[1])
-		top = uint16(wm.struts[2])
-		bottom = uint16(wm.struts[3])
-	}
+	// Usable area of this monitor, accounting for struts (panels/bars),
+	// with gaps and borders unless smart gaps and borders leave them out
+	outerGap, innerGap, bw := wm.tileStyle(ws, len(clients))
+	area := wm.workArea(m).Shrink(outerGap)
 
-	area := Rect{
-		X:      int16(outerGap + left),
-		Y:      int16(outerGap + top),
-		Width:  wm.screen.WidthInPixels - 2*outerGap - left - right,
-		Height: wm.screen.HeightInPixels - 2*outerGap - top - bottom,
+	// The tabbed layout draws its tabs above the windows
+	if l, ok := ws.Layout.(*TabbedLayout); ok {
+		wm.updateTabBar(ws, clients, l.Bar(area))
 	}
 
 	// Get positions from layout
+	ws.focusLayout()
+	mag := magnifierOf(ws.Layout)
 	rects := ws.Layout.Arrange(clients, area)
 	isMonocle := ws.Layout.IsMonocle()
 
 	// Find focused client index for monocle layouts
 	focusedIdx := 0
 	for i, client := range clients {
-		if client == wm.focused {
+		if client == ws.Focused {
 			focusedIdx = i
 			break
 		}
 	}
 
 	// Apply positions
-	bw := wm.config.BorderWidth
+	hider, hides := baseLayout(ws.Layout).(WindowHider)
 	for i, client := range clients {
 		// In monocle mode, only show the focused window
-		if isMonocle && i != focusedIdx {
-			xproto.UnmapWindow(wm.conn, client.Window)
+		if isMonocle && i != focusedIdx || hides && hider.Hidden(client) {
+			wm.x.UnmapWindow(client.Window)
 			continue
 		}
 
 		// Make sure window is mapped (for monocle when switching focus)
-		if isMonocle {
-			xproto.MapWindow(wm.conn, client.Window)
+		if isMonocle || hides {
+			wm.x.MapWindow(client.Window)
 		}
 
 		r := rects[i]
@@ -508,15 +603,25 @@ func (wm *WindowManager) tile() {
 		client.Y = r.Y
 		client.Width = w
 		client.Height = h
+		client.BorderWidth = bw
 
-		xproto.ConfigureWindow(wm.conn, client.Window,
+		// The border is always set, so it comes back when smart borders
+		// stop applying
+		wm.x.ConfigureWindow(client.Window,
 			xproto.ConfigWindowX|
 				xproto.ConfigWindowY|
 				xproto.ConfigWindowWidth|
-				xproto.ConfigWindowHeight,
-			[]uint32{uint32(r.X), uint32(r.Y), uint32(w), uint32(h)},
+				xproto.ConfigWindowHeight|
+				xproto.ConfigWindowBorderWidth,
+			[]uint32{uint32(r.X), uint32(r.Y), uint32(w), uint32(h), uint32(bw)},
 		)
 	}
+
+	// The magnified window overlaps its neighbours, so it goes on top
+	if mag != nil && ws.Focused != nil && !ws.Focused.Floating {
+		wm.x.ConfigureWindow(ws.Focused.Window,
+			xproto.ConfigWindowStackMode, []uint32{xproto.StackModeAbove})
+	}
 }
 
 // switchToWorkspace switches to a different workspace
@@ -528,29 +633,26 @@ func (wm *WindowManager) switchToWorkspace(index int) {
 		return
 	}
 
-	// Hide windows on current workspace
-	for _, c := range wm.currentWorkspace().Clients {
-		xproto.UnmapWindow(wm.conn, c.Window)
+	mon := wm.currentMonitor()
+	if other := wm.monitorShowing(index); other != nil {
+		// Visible on another monitor: swap the two workspaces
+		other.Workspace = mon.Workspace
+		wm.showWorkspace(other)
+	} else {
+		// Hide windows on current workspace
+		for _, c := range wm.currentWorkspace().Clients {
+			wm.x.UnmapWindow(c.Window)
+		}
 	}
 
-	// Switch
+	// Switch and show windows on new workspace
+	mon.Workspace = index
 	wm.current = index
-
-	// Show windows on new workspace
-	for _, c := range wm.currentWorkspace().Clients {
-		xproto.MapWindow(wm.conn, c.Window)
-	}
+	wm.showWorkspace(mon)
 
 	// Tile and focus
 	wm.tile()
-	if ws := wm.currentWorkspace(); ws.Focused != nil {
-		wm.focus(ws.Focused)
-	} else if len(ws.Clients) > 0 {
-		wm.focus(ws.Clients[0])
-	} else {
-		wm.focused = nil
-		wm.updateActiveWindow()
-	}
+	wm.focusCurrent()
 
 	// Update EWMH
 	wm.updateCurrentDesktop()
@@ -578,19 +680,17 @@ func (wm *WindowManager) moveToWorkspace(c *Client, index int) {
 	// Update EWMH desktop
 	wm.setClientDesktop(c)
 
-	// Hide if moving to different workspace
-	if index != wm.current {
-		xproto.UnmapWindow(wm.conn, c.Window)
+	// Hide if the target workspace is not on any monitor
+	if m := wm.monitorShowing(index); m != nil {
+		wm.placeOnMonitor(c, m)
+	} else {
+		wm.x.UnmapWindow(c.Window)
 	}
 
 	// Focus next window in current workspace if we moved the focused one
 	if wm.focused == c {
 		wm.focused = nil
-		if currentWs.Focused != nil {
-			wm.focus(currentWs.Focused)
-		} else if len(currentWs.Clients) > 0 {
-			wm.focus(currentWs.Clients[0])
-		}
+		wm.focusCurrent()
 	}
 
 	// Retile
@@ -601,9 +701,9 @@ func (wm *WindowManager) moveToWorkspace(c *Client, index int) {
 
 // supportsProtocol checks if a window supports a WM protocol
 func (wm *WindowManager) supportsProtocol(win xproto.Window, protocol xproto.Atom) bool {
-	prop, err := xproto.GetProperty(wm.conn, false, win,
+	prop, err := wm.x.GetProperty(win,
 		wm.atoms.WM_PROTOCOLS, xproto.AtomAtom,
-		0, 64).Reply()
+		0, 64)
 
 	if err != nil || prop == nil || prop.ValueLen == 0 {
 		return false
@@ -635,56 +735,74 @@ func (wm *WindowManager) sendDeleteWindow(c *Client) {
 		}),
 	}
 
-	xproto.SendEvent(wm.conn, false, c.Window, xproto.EventMaskNoEvent, string(event.Bytes()))
+	wm.x.SendEvent(c.Window, xproto.EventMaskNoEvent, event.Bytes())
 }
 
 // destroyClient forcefully destroys a client
 func (wm *WindowManager) destroyClient(c *Client) {
-	xproto.KillClient(wm.conn, uint32(c.Window))
+	wm.x.KillClient(c.Window)
 }
 
-// updateStruts recalculates the reserved screen space from all dock windows
-func (wm *WindowManager) updateStruts() {
-	// Reset struts
-	wm.struts = [4]uint32{0, 0, 0, 0}
+// Strut is the space a dock reserves at the edges of the root window, as
+// in _NET_WM_STRUT_PARTIAL. Each edge only covers its start-end range, so
+// a bar along the top of one monitor leaves the others alone.
+type Strut struct {
+	Left, Right, Top, Bottom uint32
+	LeftStartY, LeftEndY     uint32
+	RightStartY, RightEndY   uint32
+	TopStartX, TopEndX       uint32
+	BottomStartX, BottomEndX uint32
+}
 
-	// Query all children of root for strut properties
-	tree, err := xproto.QueryTree(wm.conn, wm.root).Reply()
-	if err != nil {
-		return
+// updateStruts re-reads the struts of every dock and recalculates the
+// space reserved on each monitor
+func (wm *WindowManager) updateStruts() {
+	for _, d := range wm.docks {
+		d.Strut, _ = wm.windowStrut(d.Window)
 	}
 
-	for _, win := range tree.Children {
-		wm.checkWindowStruts(win)
+	for _, m := range wm.monitors {
+		m.Struts = wm.monitorStruts(m)
+		log.Printf("Struts on %s: left=%d right=%d top=%d bottom=%d", m.Name,
+			m.Struts[0], m.Struts[1], m.Struts[2], m.Struts[3])
 	}
-
-	log.Printf("Struts updated: left=%d right=%d top=%d bottom=%d",
-		wm.struts[0], wm.struts[1], wm.struts[2], wm.struts[3])
 }
 
-// checkWindowStruts checks a window for strut properties
-func (wm *WindowManager) checkWindowStruts(win xproto.Window) {
+// windowStrut reads the strut of a window. A plain _NET_WM_STRUT covers
+// the whole length of each edge.
+func (wm *WindowManager) windowStrut(win xproto.Window) (Strut, bool) {
 	// Try _NET_WM_STRUT_PARTIAL first (more precise)
-	prop, err := xproto.GetProperty(wm.conn, false, win,
+	prop, err := wm.x.GetProperty(win,
 		wm.atoms.NET_WM_STRUT_PARTIAL, xproto.AtomCardinal,
-		0, 12).Reply()
+		0, 12)
 
-	if err != nil || prop == nil || prop.ValueLen < 4 {
+	if err != nil || prop == nil || prop.ValueLen < 12 {
 		// Fall back to _NET_WM_STRUT
-		prop, err = xproto.GetProperty(wm.conn, false, win,
+		prop, err = wm.x.GetProperty(win,
 			wm.atoms.NET_WM_STRUT, xproto.AtomCardinal,
-			0, 4).Reply()
+			0, 4)
 
 		if err != nil || prop == nil || prop.ValueLen < 4 {
-			return
+			return Strut{}, false
 		}
 	}
 
-	// Struts are: left, right, top, bottom
-	for i := 0; i < 4 && i*4 < len(prop.Value); i++ {
-		val := binary.LittleEndian.Uint32(prop.Value[i*4:])
-		if val > wm.struts[i] {
-			wm.struts[i] = val
-		}
+	var v [12]uint32
+	for i := 0; i < len(v) && i*4+4 <= len(prop.Value); i++ {
+		v[i] = binary.LittleEndian.Uint32(prop.Value[i*4:])
+	}
+	if prop.ValueLen < 12 {
+		maxX := uint32(wm.screen.WidthInPixels) - 1
+		maxY := uint32(wm.screen.HeightInPixels) - 1
+		v[5], v[7], v[9], v[11] = maxY, maxY, maxX, maxX
+	}
+
+	strut := Strut{
+		Left: v[0], Right: v[1], Top: v[2], Bottom: v[3],
+		LeftStartY: v[4], LeftEndY: v[5],
+		RightStartY: v[6], RightEndY: v[7],
+		TopStartX: v[8], TopEndX: v[9],
+		BottomStartX: v[10], BottomEndX: v[11],
 	}
+	return strut, strut.Left|strut.Right|strut.Top|strut.Bottom != 0
 }
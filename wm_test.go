@@ -0,0 +1,364 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// newTestWM creates an initialized window manager on a 1920x1080 fake backend
+func newTestWM(t *testing.T) (*WindowManager, *fakeBackend) {
+	t.Helper()
+
+	return startTestWM(t, newFakeBackend(1920, 1080))
+}
+
+// startTestWM runs a window manager on an already set up fake backend
+func startTestWM(t *testing.T, f *fakeBackend) (*WindowManager, *fakeBackend) {
+	t.Helper()
+
+	root := f.window(f.root).geom
+	screen := &xproto.ScreenInfo{
+		Root:           f.root,
+		WidthInPixels:  root.Width,
+		HeightInPixels: root.Height,
+	}
+	wm := newWindowManager(f, screen, f.minKeycode, f.maxKeycode)
+	wm.gridSelect = &GridSelect{wm: wm}
+
+	if err := wm.becomeWM(); err != nil {
+		t.Fatalf("becomeWM: %v", err)
+	}
+	if err := wm.init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	return wm, f
+}
+
+// mapClient creates a window and sends the MapRequest a client would
+func mapClient(wm *WindowManager, f *fakeBackend) xproto.Window {
+	win := f.addWindow(Rect{X: 10, Y: 10, Width: 400, Height: 300})
+	wm.handleMapRequest(xproto.MapRequestEvent{Parent: f.root, Window: win})
+	return win
+}
+
+func TestManageWindowTilesAndFocuses(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	first := mapClient(wm, f)
+	if got := f.window(first).geom; got != (Rect{X: 8, Y: 8, Width: 1900, Height: 1060}) {
+		t.Errorf("single window geometry = %+v", got)
+	}
+
+	second := mapClient(wm, f)
+
+	// Tall: 1912px wide area split in half, inner gaps and borders removed
+	if got := f.window(first).geom; got != (Rect{X: 8, Y: 8, Width: 944, Height: 1060}) {
+		t.Errorf("master geometry = %+v", got)
+	}
+	if got := f.window(second).geom; got != (Rect{X: 964, Y: 8, Width: 944, Height: 1060}) {
+		t.Errorf("stack geometry = %+v", got)
+	}
+
+	for _, win := range []xproto.Window{first, second} {
+		w := f.window(win)
+		if !w.mapped {
+			t.Errorf("window %d not mapped", win)
+		}
+		if w.borderWidth != wm.config.BorderWidth {
+			t.Errorf("window %d border width = %d", win, w.borderWidth)
+		}
+		if w.eventMask&xproto.EventMaskStructureNotify == 0 {
+			t.Errorf("window %d not watched for structure changes", win)
+		}
+	}
+
+	if wm.focused == nil || wm.focused.Window != second {
+		t.Fatalf("focused = %v, want newest window", wm.focused)
+	}
+	if f.focus != second {
+		t.Errorf("input focus = %d, want %d", f.focus, second)
+	}
+	if f.top() != second {
+		t.Errorf("focused window not raised")
+	}
+	if got := f.window(second).borderPixel; got != wm.config.FocusedBorderColor {
+		t.Errorf("focused border = %#x", got)
+	}
+	if got := f.window(first).borderPixel; got != wm.config.UnfocusedBorderColor {
+		t.Errorf("unfocused border = %#x", got)
+	}
+
+	if got := f.cardinals(f.root, wm.atoms.NET_ACTIVE_WINDOW); len(got) != 1 || got[0] != uint32(second) {
+		t.Errorf("_NET_ACTIVE_WINDOW = %v", got)
+	}
+	if got := f.cardinals(f.root, wm.atoms.NET_CLIENT_LIST); len(got) != 2 {
+		t.Errorf("_NET_CLIENT_LIST = %v", got)
+	}
+	if got := f.cardinals(second, wm.atoms.NET_WM_DESKTOP); len(got) != 1 || got[0] != 0 {
+		t.Errorf("_NET_WM_DESKTOP = %v", got)
+	}
+}
+
+func TestManageWindowIgnoresOverrideRedirect(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	win := f.addWindow(Rect{Width: 100, Height: 100})
+	f.window(win).overrideRedirect = true
+	wm.handleMapRequest(xproto.MapRequestEvent{Parent: f.root, Window: win})
+
+	if _, managed := wm.clients[win]; managed {
+		t.Error("override-redirect window was managed")
+	}
+}
+
+func TestManageWindowFloatingRule(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	tiled := mapClient(wm, f)
+	win := f.addWindow(Rect{X: 100, Y: 100, Width: 500, Height: 400})
+	f.setClass(win, "pavucontrol", "Pavucontrol")
+	wm.handleMapRequest(xproto.MapRequestEvent{Parent: f.root, Window: win})
+
+	c := wm.clients[win]
+	if c == nil || !c.Floating {
+		t.Fatalf("pavucontrol should float, got %+v", c)
+	}
+	if got := f.window(win).geom; got != (Rect{X: 100, Y: 100, Width: 500, Height: 400}) {
+		t.Errorf("floating window was moved to %+v", got)
+	}
+	// The tiled window still owns the whole area
+	if got := f.window(tiled).geom.Width; got != 1900 {
+		t.Errorf("tiled width = %d, want 1900", got)
+	}
+}
+
+func TestManageWindowRestoresDesktop(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	win := f.addWindow(Rect{Width: 100, Height: 100})
+	f.setCardinals(win, wm.atoms.NET_WM_DESKTOP, xproto.AtomCardinal, 3)
+	wm.handleMapRequest(xproto.MapRequestEvent{Parent: f.root, Window: win})
+
+	c := wm.clients[win]
+	if c == nil || c.Workspace != 3 {
+		t.Fatalf("client workspace = %+v, want 3", c)
+	}
+	if f.window(win).mapped {
+		t.Error("window on hidden workspace is mapped")
+	}
+	if wm.focused == c {
+		t.Error("window on hidden workspace was focused")
+	}
+	if len(wm.workspaces[3].Clients) != 1 {
+		t.Error("workspace 4 does not contain the window")
+	}
+}
+
+func TestManageWindowFullscreen(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	win := f.addWindow(Rect{Width: 100, Height: 100})
+	f.setCardinals(win, wm.atoms.NET_WM_STATE, xproto.AtomAtom, uint32(wm.atoms.NET_WM_STATE_FULLSCREEN))
+	wm.handleMapRequest(xproto.MapRequestEvent{Parent: f.root, Window: win})
+
+	w := f.window(win)
+	if w.geom != (Rect{Width: 1920, Height: 1080}) || w.borderWidth != 0 {
+		t.Errorf("fullscreen geometry = %+v border %d", w.geom, w.borderWidth)
+	}
+	if !wm.clients[win].Floating {
+		t.Error("fullscreen window should not be tiled")
+	}
+}
+
+func TestUnmanageWindowRefocuses(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	first := mapClient(wm, f)
+	second := mapClient(wm, f)
+
+	f.destroyWindow(second)
+	wm.handleDestroyNotify(xproto.DestroyNotifyEvent{Event: f.root, Window: second})
+
+	if _, ok := wm.clients[second]; ok {
+		t.Fatal("destroyed window still managed")
+	}
+	if wm.focused == nil || wm.focused.Window != first {
+		t.Fatalf("focus = %v, want remaining window", wm.focused)
+	}
+	if got := f.window(first).geom.Width; got != 1900 {
+		t.Errorf("remaining window width = %d, want full area", got)
+	}
+	if got := f.cardinals(f.root, wm.atoms.NET_CLIENT_LIST); len(got) != 1 || got[0] != uint32(first) {
+		t.Errorf("_NET_CLIENT_LIST = %v", got)
+	}
+}
+
+func TestSwitchToWorkspace(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	first := mapClient(wm, f)
+	second := mapClient(wm, f)
+	wm.focus(wm.clients[first])
+
+	wm.switchToWorkspace(1)
+
+	if wm.current != 1 {
+		t.Fatalf("current = %d", wm.current)
+	}
+	for _, win := range []xproto.Window{first, second} {
+		if f.window(win).mapped {
+			t.Errorf("window %d still mapped on hidden workspace", win)
+		}
+	}
+	if wm.focused != nil {
+		t.Errorf("focused = %v on empty workspace", wm.focused)
+	}
+	if got := f.cardinals(f.root, wm.atoms.NET_CURRENT_DESKTOP); got[0] != 1 {
+		t.Errorf("_NET_CURRENT_DESKTOP = %v", got)
+	}
+	if got := f.cardinals(f.root, wm.atoms.NET_ACTIVE_WINDOW); got[0] != 0 {
+		t.Errorf("_NET_ACTIVE_WINDOW = %v", got)
+	}
+
+	wm.switchToWorkspace(0)
+
+	for _, win := range []xproto.Window{first, second} {
+		if !f.window(win).mapped {
+			t.Errorf("window %d not mapped after switching back", win)
+		}
+	}
+	if wm.focused == nil || wm.focused.Window != first {
+		t.Errorf("focus not restored: %v", wm.focused)
+	}
+
+	// Out of range and no-op switches are ignored
+	wm.switchToWorkspace(42)
+	wm.switchToWorkspace(0)
+	if wm.current != 0 {
+		t.Errorf("current = %d", wm.current)
+	}
+}
+
+func TestMoveToWorkspace(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	first := mapClient(wm, f)
+	second := mapClient(wm, f)
+
+	wm.moveToWorkspace(wm.clients[second], 2)
+
+	c := wm.clients[second]
+	if c.Workspace != 2 || len(wm.workspaces[2].Clients) != 1 || len(wm.workspaces[0].Clients) != 1 {
+		t.Fatalf("client not moved: workspace=%d", c.Workspace)
+	}
+	if f.window(second).mapped {
+		t.Error("moved window still visible")
+	}
+	if got := f.cardinals(second, wm.atoms.NET_WM_DESKTOP); got[0] != 2 {
+		t.Errorf("_NET_WM_DESKTOP = %v", got)
+	}
+	if wm.focused == nil || wm.focused.Window != first {
+		t.Errorf("focus = %v, want remaining window", wm.focused)
+	}
+	if got := f.window(first).geom.Width; got != 1900 {
+		t.Errorf("remaining window width = %d, want full area", got)
+	}
+
+	wm.switchToWorkspace(2)
+	if !f.window(second).mapped || wm.focused != c {
+		t.Error("moved window not shown and focused on its new workspace")
+	}
+}
+
+func TestKeyPressRunsBinding(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	first := mapClient(wm, f)
+	mapClient(wm, f)
+
+	wm.handleKeyPress(xproto.KeyPressEvent{
+		Detail: f.keycode(XK_j),
+		State:  xproto.ModMask4 | xproto.ModMask2, // NumLock is ignored
+	})
+
+	if wm.focused == nil || wm.focused.Window != first {
+		t.Errorf("Super+j did not focus next window: %v", wm.focused)
+	}
+}
+
+func TestGrabKeysCoversLockModifiers(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	want := fakeGrab{win: f.root, mod: xproto.ModMask4 | xproto.ModMaskLock, detail: byte(f.keycode(XK_Return))}
+	found := false
+	for _, g := range f.keyGrabs {
+		if g == want {
+			found = true
+		}
+		if g.detail == 0 {
+			t.Errorf("grabbed keycode 0: %+v", g)
+		}
+	}
+	if !found {
+		t.Errorf("Super+CapsLock+Return not grabbed (%d grabs)", len(f.keyGrabs))
+	}
+	if len(wm.config.Keybindings) == 0 {
+		t.Error("no keybindings resolved")
+	}
+}
+
+func TestMappingNotifyRegrabsKeys(t *testing.T) {
+	wm, f := newTestWM(t)
+
+	first := mapClient(wm, f)
+	mapClient(wm, f)
+
+	// Switch layouts: j and k trade places, and Return also appears on the
+	// Escape key
+	oldJ := f.keycode(XK_j)
+	for i, sym := range f.keysyms {
+		switch sym {
+		case XK_j:
+			f.keysyms[i] = XK_k
+		case XK_k:
+			f.keysyms[i] = XK_j
+		case XK_Escape:
+			f.keysyms[i] = XK_Return
+		}
+	}
+	wm.handleMappingNotify(xproto.MappingNotifyEvent{Request: xproto.MappingKeyboard})
+
+	if f.keycode(XK_j) == oldJ {
+		t.Fatal("test keymap did not change")
+	}
+	grabbed := func(mod uint16, keycode xproto.Keycode) bool {
+		for _, g := range f.keyGrabs {
+			if g.mod == mod && g.detail == byte(keycode) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, keycode := range wm.keysymToKeycodes(XK_Return) {
+		if !grabbed(xproto.ModMask4, keycode) {
+			t.Errorf("Super+Return not grabbed on keycode %d", keycode)
+		}
+	}
+	if n := len(wm.keysymToKeycodes(XK_Return)); n != 2 {
+		t.Errorf("Return is on %d keycodes, want 2", n)
+	}
+
+	// Super+j now comes from the new keycode
+	wm.handleKeyPress(xproto.KeyPressEvent{Detail: f.keycode(XK_j), State: xproto.ModMask4})
+	if wm.focused == nil || wm.focused.Window != first {
+		t.Errorf("Super+j on the new keycode did not focus next: %v", wm.focused)
+	}
+
+	// Pointer mapping changes don't touch the keymap
+	f.keyGrabs = nil
+	wm.handleMappingNotify(xproto.MappingNotifyEvent{Request: xproto.MappingPointer})
+	if len(f.keyGrabs) != 0 {
+		t.Error("pointer mapping change re-grabbed keys")
+	}
+}
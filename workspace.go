@@ -7,16 +7,28 @@ type Workspace struct {
 	Clients []*Client
 	Layout  Layout
 	Focused *Client
+
+	// Appearance overrides from the config
+	Settings WorkspaceSettings
+
+	// Factor new magnifiers start with
+	magnifyFactor float64
+
+	// Layout instances by name, so each keeps its state (ratios, the BSP
+	// tree) while the workspace cycles through layouts
+	layouts map[string]Layout
 }
 
 // NewWorkspace creates a new workspace with the given ID and name
 func NewWorkspace(id int, name string) *Workspace {
-	return &Workspace{
-		ID:      id,
-		Name:    name,
-		Clients: make([]*Client, 0),
-		Layout:  NewTallLayout(),
+	ws := &Workspace{
+		ID:            id,
+		Name:          name,
+		Clients:       make([]*Client, 0),
+		magnifyFactor: defaultMagnifyFactor,
 	}
+	ws.SetLayout(NewTallLayout())
+	return ws
 }
 
 // Add adds a client to this workspace
@@ -163,20 +175,112 @@ func (ws *Workspace) SwapMaster() {
 
 // SetLayout sets the workspace layout
 func (ws *Workspace) SetLayout(layout Layout) {
+	if ws.layouts == nil {
+		ws.layouts = make(map[string]Layout)
+	}
+	ws.layouts[layout.Name()] = layout
 	ws.Layout = layout
 }
 
-// NextLayout cycles to the next layout in the given list
-func (ws *Workspace) NextLayout(layouts []Layout) {
-	currentName := ws.Layout.Name()
-	for i, l := range layouts {
-		if l.Name() == currentName {
-			ws.Layout = layouts[(i+1)%len(layouts)]
-			return
-		}
+// ResetLayout goes back to a fresh tall layout, forgetting the state of
+// every layout used so far
+func (ws *Workspace) ResetLayout() {
+	ws.layouts = nil
+	ws.SetLayout(NewTallLayout())
+}
+
+// NextLayout cycles to the next of the named layouts. Each workspace has
+// its own instance of a layout, created the first time it is used.
+func (ws *Workspace) NextLayout(names []string) {
+	if len(names) == 0 {
+		return
 	}
 	// Default to first layout if current not found
-	if len(layouts) > 0 {
-		ws.Layout = layouts[0]
+	next := names[0]
+	for i, name := range names {
+		if name == ws.Layout.Name() {
+			next = names[(i+1)%len(names)]
+			break
+		}
+	}
+
+	ws.SelectLayout(next)
+}
+
+// SelectLayout switches to the named layout, reusing the workspace's
+// instance of it if there is one. It reports whether the name is known.
+func (ws *Workspace) SelectLayout(name string) bool {
+	if l, ok := ws.layouts[name]; ok {
+		ws.Layout = l
+		return true
+	}
+	l := newLayout(name)
+	if l == nil {
+		return false
+	}
+	for _, m := range magnifiers(l) {
+		m.Factor = ws.magnifyFactor
+	}
+	ws.SetLayout(l)
+	return true
+}
+
+// SetMagnifyFactor sets the factor of the workspace's magnifiers, and of
+// those it creates from now on
+func (ws *Workspace) SetMagnifyFactor(factor float64) {
+	ws.magnifyFactor = factor
+	for _, l := range ws.layouts {
+		for _, m := range magnifiers(l) {
+			m.Factor = factor
+		}
+	}
+}
+
+// ToggleModifier wraps the layout in the named modifier, or takes the
+// modifier off if the layout already has it. It reports false when the
+// layout can't be wrapped.
+func (ws *Workspace) ToggleModifier(mod string) bool {
+	var mods []string
+	found := false
+	factor := ws.magnifyFactor
+	if m := magnifierOf(ws.Layout); m != nil {
+		factor = m.Factor // Kept while other modifiers come and go
+	}
+	l := ws.Layout
+	for {
+		m, ok := l.(LayoutModifier)
+		if !ok {
+			break
+		}
+		if m.Modifier() == mod {
+			found = true
+		} else {
+			mods = append(mods, m.Modifier())
+		}
+		l = m.Unwrap()
+	}
+	if !found {
+		if !wrappable(l) {
+			return false
+		}
+		mods = append([]string{mod}, mods...)
+	}
+
+	// Wrap the base layout again, innermost modifier first
+	for i := len(mods) - 1; i >= 0; i-- {
+		if mods[i] == "magnify" {
+			l = NewMagnifierLayout(l, factor)
+		} else {
+			l = layoutModifiers[mods[i]](l)
+		}
+	}
+	ws.SetLayout(l)
+	return true
+}
+
+// focusLayout tells a layout that tracks focus which window has it
+func (ws *Workspace) focusLayout() {
+	if l, ok := ws.Layout.(FocusTracker); ok {
+		l.SetFocused(ws.Focused)
 	}
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// newTestWorkspace creates a workspace holding n clients with windows 1..n
+func newTestWorkspace(n int) (*Workspace, []*Client) {
+	ws := NewWorkspace(0, "1")
+	clients := make([]*Client, n)
+	for i := range clients {
+		clients[i] = &Client{Window: xproto.Window(i + 1)}
+		ws.Add(clients[i])
+	}
+	return ws, clients
+}
+
+func windowsOf(clients []*Client) []xproto.Window {
+	wins := make([]xproto.Window, len(clients))
+	for i, c := range clients {
+		wins[i] = c.Window
+	}
+	return wins
+}
+
+func equalWindows(a []xproto.Window, b ...xproto.Window) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWorkspaceAddSetsWorkspace(t *testing.T) {
+	ws := NewWorkspace(4, "5")
+	c := &Client{Window: 1}
+	ws.Add(c)
+	if c.Workspace != 4 {
+		t.Errorf("client workspace = %d, want 4", c.Workspace)
+	}
+}
+
+func TestWorkspaceRemoveMovesFocus(t *testing.T) {
+	ws, c := newTestWorkspace(3)
+
+	ws.Focused = c[1]
+	ws.Remove(c[1])
+	if ws.Focused != c[2] {
+		t.Errorf("focus after removing middle = %v, want next", ws.Focused)
+	}
+
+	ws.Remove(c[2])
+	if ws.Focused != c[0] {
+		t.Errorf("focus after removing last = %v, want previous", ws.Focused)
+	}
+
+	ws.Remove(c[0])
+	if ws.Focused != nil || len(ws.Clients) != 0 {
+		t.Errorf("empty workspace still has focus %v", ws.Focused)
+	}
+}
+
+func TestWorkspaceFocusCycles(t *testing.T) {
+	ws, c := newTestWorkspace(3)
+
+	if got := ws.FocusNext(); got != c[0] {
+		t.Errorf("FocusNext without focus = %v", got)
+	}
+	ws.FocusNext()
+	if got := ws.FocusNext(); got != c[2] {
+		t.Errorf("FocusNext = %v", got)
+	}
+	if got := ws.FocusNext(); got != c[0] {
+		t.Errorf("FocusNext did not wrap: %v", got)
+	}
+	if got := ws.FocusPrev(); got != c[2] {
+		t.Errorf("FocusPrev did not wrap: %v", got)
+	}
+	ws.Focused = c[2]
+	if got := ws.FocusMaster(); got != c[0] {
+		t.Errorf("FocusMaster = %v", got)
+	}
+
+	empty := NewWorkspace(1, "2")
+	if empty.FocusNext() != nil || empty.FocusPrev() != nil || empty.FocusMaster() != nil {
+		t.Error("focus on empty workspace should be nil")
+	}
+}
+
+func TestWorkspaceSwaps(t *testing.T) {
+	ws, c := newTestWorkspace(3)
+
+	ws.Focused = c[0]
+	ws.SwapNext()
+	if !equalWindows(windowsOf(ws.Clients), 2, 1, 3) {
+		t.Errorf("SwapNext = %v", windowsOf(ws.Clients))
+	}
+
+	ws.SwapPrev()
+	ws.SwapPrev()
+	if !equalWindows(windowsOf(ws.Clients), 3, 2, 1) {
+		t.Errorf("SwapPrev wrap = %v", windowsOf(ws.Clients))
+	}
+
+	ws.Focused = c[1]
+	ws.SwapMaster()
+	if !equalWindows(windowsOf(ws.Clients), 2, 3, 1) {
+		t.Errorf("SwapMaster = %v", windowsOf(ws.Clients))
+	}
+}
+
+func TestWorkspaceTiledClients(t *testing.T) {
+	ws, c := newTestWorkspace(3)
+	c[1].Floating = true
+
+	if got := windowsOf(ws.TiledClients()); !equalWindows(got, 1, 3) {
+		t.Errorf("TiledClients = %v", got)
+	}
+}
+
+func TestWorkspaceNextLayout(t *testing.T) {
+	layouts := []string{"tall", "full", "grid"}
+	ws := NewWorkspace(0, "1")
+
+	ws.NextLayout(layouts)
+	if ws.Layout.Name() != "full" {
+		t.Errorf("after tall = %s", ws.Layout.Name())
+	}
+	ws.NextLayout(layouts)
+	ws.NextLayout(layouts)
+	if ws.Layout.Name() != "tall" {
+		t.Errorf("did not wrap: %s", ws.Layout.Name())
+	}
+}
+
+func TestWorkspaceLayoutsKeepState(t *testing.T) {
+	layouts := []string{"tall", "bsp"}
+	a, b := NewWorkspace(0, "1"), NewWorkspace(1, "2")
+
+	a.NextLayout(layouts)
+	b.NextLayout(layouts)
+	if a.Layout == b.Layout {
+		t.Error("workspaces share a layout instance")
+	}
+
+	// Cycling back returns the same instance with its settings
+	a.NextLayout(layouts)
+	a.Layout.HandleMessage(LayoutMsgExpand)
+	a.NextLayout(layouts)
+	a.NextLayout(layouts)
+	if ratio := a.Layout.(*TallLayout).MasterRatio; ratio <= 0.5 {
+		t.Errorf("tall ratio after cycling = %f", ratio)
+	}
+
+	a.ResetLayout()
+	if ratio := a.Layout.(*TallLayout).MasterRatio; ratio != 0.5 {
+		t.Errorf("tall ratio after reset = %f", ratio)
+	}
+}
+
+func TestWorkspaceToggleModifier(t *testing.T) {
+	ws := NewWorkspace(0, "1")
+	tall := ws.Layout
+
+	for _, step := range []struct{ mod, want string }{
+		{"mirror", "mirror(tall)"},
+		{"reflectx", "reflectx(mirror(tall))"},
+		{"mirror", "reflectx(tall)"},
+		{"reflectx", "tall"},
+	} {
+		ws.ToggleModifier(step.mod)
+		if ws.Layout.Name() != step.want {
+			t.Errorf("toggle %s = %s, want %s", step.mod, ws.Layout.Name(), step.want)
+		}
+		if baseLayout(ws.Layout) != tall {
+			t.Errorf("toggle %s replaced the tall layout", step.mod)
+		}
+	}
+
+	// The tabbed layout's bar can't follow a modifier
+	ws.SelectLayout("tabbed")
+	if ws.ToggleModifier("mirror") || ws.Layout.Name() != "tabbed" {
+		t.Errorf("mirror on tabbed = %s, want tabbed", ws.Layout.Name())
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+/*
+#cgo pkg-config: xft x11
+#include <X11/Xlib.h>
+#include <X11/Xft/Xft.h>
+#include <stdlib.h>
+#include <string.h>
+
+// Helper functions to work with Xft from Go
+
+static XftFont* openFont(Display *dpy, int screen, const char *name) {
+    return XftFontOpenName(dpy, screen, name);
+}
+
+static void closeFont(Display *dpy, XftFont *font) {
+    if (font) XftFontClose(dpy, font);
+}
+
+static XftDraw* createDraw(Display *dpy, Drawable d, Visual *visual, Colormap cmap) {
+    return XftDrawCreate(dpy, d, visual, cmap);
+}
+
+static void destroyDraw(XftDraw *draw) {
+    if (draw) XftDrawDestroy(draw);
+}
+
+static void drawString(XftDraw *draw, XftColor *color, XftFont *font, int x, int y, const char *str, int len) {
+    XftDrawStringUtf8(draw, color, font, x, y, (const FcChar8*)str, len);
+}
+
+static void allocColor(Display *dpy, Visual *visual, Colormap cmap, XftColor *color, unsigned int rgb) {
+    XRenderColor xrc;
+    xrc.red = ((rgb >> 16) & 0xFF) * 257;
+    xrc.green = ((rgb >> 8) & 0xFF) * 257;
+    xrc.blue = (rgb & 0xFF) * 257;
+    xrc.alpha = 0xFFFF;
+    XftColorAllocValue(dpy, visual, cmap, &xrc, color);
+}
+
+static void freeColor(Display *dpy, Visual *visual, Colormap cmap, XftColor *color) {
+    XftColorFree(dpy, visual, cmap, color);
+}
+
+static int fontHeight(XftFont *font) {
+    return font->ascent + font->descent;
+}
+
+static int fontAscent(XftFont *font) {
+    return font->ascent;
+}
+
+static int textWidth(Display *dpy, XftFont *font, const char *str, int len) {
+    XGlyphInfo extents;
+    XftTextExtentsUtf8(dpy, font, (const FcChar8*)str, len, &extents);
+    return extents.xOff;
+}
+*/
+import "C"
+
+import (
+	"log"
+	"unsafe"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// xftFont is an Xft font for anti-aliased text. Xft cannot draw through
+// xgb, so each font has its own Xlib connection. GridSelect and the tab
+// bars draw their text with it.
+type xftFont struct {
+	display *C.Display
+	font    *C.XftFont
+}
+
+// xftDraw draws with an xftFont on a window
+type xftDraw struct {
+	font *xftFont
+	draw *C.XftDraw
+}
+
+// openXftFont opens the first of the named fonts that loads, or returns
+// nil. owner prefixes the log messages.
+func openXftFont(owner string, names []string) *xftFont {
+	displayName := C.CString("")
+	defer C.free(unsafe.Pointer(displayName))
+	display := C.XOpenDisplay(displayName)
+	if display == nil {
+		log.Printf("%s: Failed to open X display for Xft", owner)
+		return nil
+	}
+
+	// Use default screen (0) for font loading
+	screenNum := C.XDefaultScreen(display)
+	for i, name := range names {
+		fontName := C.CString(name)
+		font := C.openFont(display, screenNum, fontName)
+		C.free(unsafe.Pointer(fontName))
+		if font == nil {
+			continue
+		}
+
+		if i > 0 {
+			log.Printf("%s: Using fallback font: %s", owner, name)
+		}
+		log.Printf("%s: Loaded font, height=%d", owner, int(C.fontHeight(font)))
+		return &xftFont{display: display, font: font}
+	}
+
+	log.Printf("%s: Failed to load any font, text will use default", owner)
+	C.XCloseDisplay(display)
+	return nil
+}
+
+// Close frees the font and its connection
+func (f *xftFont) Close() {
+	if f == nil {
+		return
+	}
+	C.closeFont(f.display, f.font)
+	C.XCloseDisplay(f.display)
+}
+
+// Height returns the font height in pixels
+func (f *xftFont) Height() int {
+	return int(C.fontHeight(f.font))
+}
+
+// Ascent returns the height of the font above the baseline
+func (f *xftFont) Ascent() int {
+	return int(C.fontAscent(f.font))
+}
+
+// Width returns the width of text in pixels
+func (f *xftFont) Width(text string) int {
+	cstr := C.CString(text)
+	width := int(C.textWidth(f.display, f.font, cstr, C.int(len(text))))
+	C.free(unsafe.Pointer(cstr))
+	return width
+}
+
+// newDraw prepares drawing on win. The window must already exist on the
+// server, so sync the xgb connection first.
+func (f *xftFont) newDraw(win xproto.Window) *xftDraw {
+	screen := C.XDefaultScreenOfDisplay(f.display)
+	visual := C.XDefaultVisualOfScreen(screen)
+	colormap := C.XDefaultColormapOfScreen(screen)
+	return &xftDraw{
+		font: f,
+		draw: C.createDraw(f.display, C.Drawable(win), visual, colormap),
+	}
+}
+
+// Close frees the draw; the window itself is left alone
+func (d *xftDraw) Close() {
+	if d == nil {
+		return
+	}
+	C.destroyDraw(d.draw)
+}
+
+// withColor runs fn with an Xft color allocated for rgb
+func (d *xftDraw) withColor(rgb uint32, fn func(*C.XftColor)) {
+	screen := C.XDefaultScreenOfDisplay(d.font.display)
+	visual := C.XDefaultVisualOfScreen(screen)
+	colormap := C.XDefaultColormapOfScreen(screen)
+
+	var color C.XftColor
+	C.allocColor(d.font.display, visual, colormap, &color, C.uint(rgb))
+	fn(&color)
+	C.freeColor(d.font.display, visual, colormap, &color)
+
+	// Flush to ensure drawing is visible
+	C.XFlush(d.font.display)
+}
+
+// String draws text with its baseline at x, y
+func (d *xftDraw) String(text string, x, y int, rgb uint32) {
+	d.withColor(rgb, func(color *C.XftColor) {
+		cstr := C.CString(text)
+		C.drawString(d.draw, color, d.font.font, C.int(x), C.int(y), cstr, C.int(len(text)))
+		C.free(unsafe.Pointer(cstr))
+	})
+}
+
+// Rect fills a rectangle
+func (d *xftDraw) Rect(r Rect, rgb uint32) {
+	d.withColor(rgb, func(color *C.XftColor) {
+		C.XftDrawRect(d.draw, color, C.int(r.X), C.int(r.Y), C.uint(r.Width), C.uint(r.Height))
+	})
+}